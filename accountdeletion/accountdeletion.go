@@ -0,0 +1,428 @@
+// Package accountdeletion 玩家账号注销执行器：冷静期结束且已批准的注销申请，按步骤删除或匿名化
+// game、gameWeb、gamelog 三个库中的玩家数据。每个步骤的结果记录在 accountDeletionStep 表，
+// 执行失败后重试会跳过已成功的步骤，完成后的记录用于证明注销已执行。
+package accountdeletion
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gameWeb/config"
+	"gameWeb/db"
+	"gameWeb/log"
+	"gameWeb/loginprovider"
+	"gameWeb/middleware"
+	"gameWeb/wechat"
+	"strconv"
+	"time"
+)
+
+// 注销申请状态
+const (
+	StatusPending   int8 = 0 // 待审批
+	StatusApproved  int8 = 1 // 已批准，等待冷静期结束
+	StatusExecuting int8 = 2 // 执行中
+	StatusCompleted int8 = 3 // 已完成
+	StatusRejected  int8 = 4 // 已拒绝
+	StatusCancelled int8 = 5 // 玩家已撤销
+	StatusFailed    int8 = 6 // 执行失败，可以重试
+)
+
+// 步骤状态
+const (
+	stepSucceeded int8 = 1
+	stepFailed    int8 = 2
+)
+
+// 步骤处理方式
+const (
+	ActionDelete    = "delete"
+	ActionAnonymize = "anonymize"
+	ActionRevoke    = "revoke"
+)
+
+// AnonymizedNickname 匿名化后的昵称
+const AnonymizedNickname = "已注销用户"
+
+// staleHeartbeat 执行中的申请超过这个时间没有心跳，视为执行实例已退出，允许其他实例接手
+// 执行实例在每个步骤和每个批次后更新心跳，单个批次的耗时必须远小于这个时间
+const staleHeartbeat = 5 * time.Minute
+
+// ErrLostClaim 申请已被其他实例接手（本实例心跳超时），停止执行
+var ErrLostClaim = errors.New("account deletion claimed by another executor")
+
+// executorBatch 每次扫描处理的申请数量
+const executorBatch = 20
+
+// heartbeat 更新执行心跳，申请已被其他实例接手时返回 ErrLostClaim
+type heartbeat func() error
+
+// step 注销步骤
+type step struct {
+	name   string
+	action string
+	run    func(userid int64, beat heartbeat) (int64, error)
+}
+
+// steps 按顺序列出全部步骤：先吊销令牌和会话缓存，再处理账号表（玩家无法再登录这个userid），
+// 删除账号表后再吊销一次（覆盖执行期间或失败后等待重试期间重新登录签发的令牌），最后处理数据和日志
+func steps() []step {
+	list := []step{{
+		name:   "redis.clientTokens",
+		action: ActionRevoke,
+		run:    revokeClientTokens,
+	}, {
+		// 旧版 DES token 的会话（user:{userid}，由登录服写入）
+		name:   "redis.legacySession",
+		action: ActionRevoke,
+		run:    deleteLegacySession,
+	}, {
+		// 微信会话密钥以 openid 为键，需要在删除账号表之前查出 openid
+		name:   "redis.wechatSessionKeys",
+		action: ActionRevoke,
+		run:    deleteWechatSessionKeys,
+	}}
+
+	// 账号表：account 由登录服维护，其余为已注册的登录方式
+	tables := []string{"account"}
+	for _, p := range loginprovider.All() {
+		tables = append(tables, p.Table())
+	}
+	for _, table := range tables {
+		query := "DELETE FROM `" + table + "` WHERE userid = ?"
+		list = append(list, step{name: "game." + table, action: ActionDelete, run: execFunc(db.MySQLDB, query)})
+	}
+	list = append(list,
+		step{name: "redis.clientTokensAfterAccounts", action: ActionRevoke, run: revokeClientTokens},
+		step{name: "redis.legacySessionAfterAccounts", action: ActionRevoke, run: deleteLegacySession},
+	)
+
+	return append(list,
+		step{name: "game.userRiches", action: ActionDelete, run: execFunc(db.MySQLDB, "DELETE FROM userRiches WHERE userid = ?")},
+		step{name: "game.userStatus", action: ActionDelete, run: execFunc(db.MySQLDB, "DELETE FROM userStatus WHERE userid = ?")},
+		// userData 保留行，其他表和日志中的 userid 仍然可以关联，但不再包含个人信息
+		step{name: "game.userData", action: ActionAnonymize, run: func(userid int64, beat heartbeat) (int64, error) {
+			result, err := db.MySQLDB.Exec(
+				"UPDATE userData SET nickname = ?, headurl = '', sex = 0, province = '', city = '', ip = '', ext = NULL WHERE userid = ?",
+				AnonymizedNickname, userid)
+			if err != nil {
+				return 0, err
+			}
+			return result.RowsAffected()
+		}},
+		step{name: "gameWeb.mailUsers", action: ActionDelete, run: batchFunc(db.MySQLDBGameWeb,
+			"DELETE FROM mailUsers WHERE userid = ?")},
+		// 日志保留用于统计，只清除昵称、IP和扩展数据
+		step{name: "gamelog.logAuth", action: ActionAnonymize, run: batchFunc(db.MySQLDBGameLog,
+			"UPDATE logAuth SET nickname = '', ip = NULL, ext = NULL WHERE userid = ? AND (nickname <> '' OR ip IS NOT NULL OR ext IS NOT NULL)")},
+		// 对局记录涉及其他玩家，保留结果和分数，只清除扩展数据
+		step{name: "gamelog.logResult10001", action: ActionAnonymize, run: batchFunc(db.MySQLDBGameLog,
+			"UPDATE logResult10001 SET ext = NULL WHERE userid = ? AND ext IS NOT NULL")},
+	)
+}
+
+// revokeClientTokens 吊销玩家全部客户端令牌
+func revokeClientTokens(userid int64, beat heartbeat) (int64, error) {
+	n, err := middleware.RevokeAllClientTokens(userid)
+	return int64(n), err
+}
+
+// deleteLegacySession 删除旧版 token 的会话
+func deleteLegacySession(userid int64, beat heartbeat) (int64, error) {
+	key := "user:" + strconv.FormatInt(userid, 10)
+	exists, err := db.ExistsRedis(key)
+	if err != nil || !exists {
+		return 0, err
+	}
+	return 1, db.DelRedis(key)
+}
+
+// deleteWechatSessionKeys 删除玩家全部微信小游戏身份在各应用下缓存的会话密钥
+func deleteWechatSessionKeys(userid int64, beat heartbeat) (int64, error) {
+	rows, err := db.MySQLDB.Query("SELECT username FROM wechatMiniGame WHERE userid = ?", userid)
+	if err != nil {
+		return 0, err
+	}
+	var openids []string
+	for rows.Next() {
+		var openid string
+		if err := rows.Scan(&openid); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		openids = append(openids, openid)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, app := range loginprovider.Apps(loginprovider.WechatMiniGame) {
+		for _, openid := range openids {
+			if err := wechat.DeleteSessionKey(app.AppID, openid); err != nil {
+				return total, err
+			}
+			total++
+		}
+	}
+	return total, nil
+}
+
+// execFunc 执行一条语句
+func execFunc(conn *sql.DB, query string) func(userid int64, beat heartbeat) (int64, error) {
+	return func(userid int64, beat heartbeat) (int64, error) {
+		result, err := conn.Exec(query, userid)
+		if err != nil {
+			return 0, err
+		}
+		return result.RowsAffected()
+	}
+}
+
+// batchFunc 分批执行语句，避免大表长时间锁表，每批之后更新心跳；语句的条件必须排除已处理的行，否则不会结束
+func batchFunc(conn *sql.DB, query string) func(userid int64, beat heartbeat) (int64, error) {
+	return func(userid int64, beat heartbeat) (int64, error) {
+		batch := config.AppConfig.AccountDeletion.BatchSize
+		if batch <= 0 {
+			batch = 1000
+		}
+		var total int64
+		for {
+			result, err := conn.Exec(query+" LIMIT ?", userid, batch)
+			if err != nil {
+				return total, err
+			}
+			n, err := result.RowsAffected()
+			if err != nil {
+				return total, err
+			}
+			total += n
+			if n < int64(batch) {
+				return total, nil
+			}
+			if err := beat(); err != nil {
+				return total, err
+			}
+		}
+	}
+}
+
+// Execute 执行注销申请的全部步骤，已成功的步骤会跳过；每个步骤开始前调用 beat 更新心跳
+// 吊销步骤每次都重新执行：上次失败后到重试前账号表可能仍在，玩家可以重新登录拿到新的令牌
+func Execute(deletionID uint64, userid int64, beat heartbeat) error {
+	done, err := succeededSteps(deletionID)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range steps() {
+		if done[s.name] && s.action != ActionRevoke {
+			continue
+		}
+		if err := beat(); err != nil {
+			return err
+		}
+		affected, runErr := s.run(userid, beat)
+		if runErr == ErrLostClaim {
+			return runErr
+		}
+		if err := recordStep(deletionID, s, affected, runErr); err != nil {
+			return fmt.Errorf("record step %s: %v", s.name, err)
+		}
+		if runErr != nil {
+			return fmt.Errorf("%s: %v", s.name, runErr)
+		}
+		log.Infof("账号注销步骤完成: 申请ID=%d, 用户ID=%d, 步骤=%s, 影响行数=%d", deletionID, userid, s.name, affected)
+	}
+	return nil
+}
+
+// succeededSteps 查询已成功的步骤
+func succeededSteps(deletionID uint64) (map[string]bool, error) {
+	rows, err := db.MySQLDBGameWeb.Query(
+		"SELECT step FROM accountDeletionStep WHERE deletionId = ? AND status = ?", deletionID, stepSucceeded)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		done[name] = true
+	}
+	return done, rows.Err()
+}
+
+// recordStep 记录步骤结果，重试时覆盖上次失败的记录
+func recordStep(deletionID uint64, s step, affected int64, runErr error) error {
+	status := stepSucceeded
+	var errMsg interface{}
+	if runErr != nil {
+		status = stepFailed
+		errMsg = truncate(runErr.Error(), 512)
+	}
+	_, err := db.MySQLDBGameWeb.Exec(`
+		INSERT INTO accountDeletionStep (deletionId, step, action, affectedRows, status, errorMessage)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE action = VALUES(action), affectedRows = VALUES(affectedRows),
+			status = VALUES(status), errorMessage = VALUES(errorMessage), executedTime = NOW()`,
+		deletionID, s.name, s.action, affected, status, errMsg)
+	return err
+}
+
+// StartExecutor 启动后台执行器，按配置的间隔扫描到期的注销申请；间隔为0时不启动
+// 多个实例同时运行时通过数据库条件更新认领申请，同一申请只会被一个实例执行
+func StartExecutor() {
+	interval := config.AppConfig.AccountDeletion.ExecutorInterval
+	if interval <= 0 {
+		log.Info("账号注销执行器未启用")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := RunDue(); err != nil {
+				log.Errorf("账号注销执行器扫描失败: %v", err)
+			}
+		}
+	}()
+	log.Infof("账号注销执行器已启动，扫描间隔 %d 秒", interval)
+}
+
+// RunDue 执行到期的注销申请：已批准且冷静期已结束，或执行中但心跳已超时（执行实例已退出）
+func RunDue() error {
+	staleSeconds := int(staleHeartbeat / time.Second)
+	rows, err := db.MySQLDBGameWeb.Query(`
+		SELECT id, userid FROM accountDeletion
+		WHERE (status = ? AND executeAfter <= NOW())
+			OR (status = ? AND (heartbeatTime IS NULL OR heartbeatTime < NOW() - INTERVAL ? SECOND))
+		ORDER BY id LIMIT ?`,
+		StatusApproved, StatusExecuting, staleSeconds, executorBatch)
+	if err != nil {
+		return err
+	}
+
+	type due struct {
+		id     uint64
+		userid int64
+	}
+	var list []due
+	for rows.Next() {
+		var d due
+		if err := rows.Scan(&d.id, &d.userid); err != nil {
+			rows.Close()
+			return err
+		}
+		list = append(list, d)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, d := range list {
+		token, err := claim(d.id, staleSeconds)
+		if err != nil {
+			log.Errorf("认领账号注销申请失败: 申请ID=%d, err=%v", d.id, err)
+			continue
+		}
+		if token == "" {
+			continue
+		}
+		run(d.id, d.userid, token)
+	}
+	return nil
+}
+
+// claim 把申请标记为执行中并写入本实例的执行标识，返回执行标识，没有认领成功时返回空字符串
+func claim(deletionID uint64, staleSeconds int) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	result, err := db.MySQLDBGameWeb.Exec(`
+		UPDATE accountDeletion SET status = ?, executorToken = ?, startedTime = NOW(), heartbeatTime = NOW(), lastError = NULL
+		WHERE id = ? AND ((status = ? AND executeAfter <= NOW())
+			OR (status = ? AND (heartbeatTime IS NULL OR heartbeatTime < NOW() - INTERVAL ? SECOND)))`,
+		StatusExecuting, token, deletionID, StatusApproved, StatusExecuting, staleSeconds)
+	if err != nil {
+		return "", err
+	}
+	n, err := result.RowsAffected()
+	if err != nil || n != 1 {
+		return "", err
+	}
+	return token, nil
+}
+
+// beatFunc 返回更新心跳的函数：只更新仍由本实例执行的申请，已被其他实例接手时返回 ErrLostClaim
+func beatFunc(deletionID uint64, token string) heartbeat {
+	return func() error {
+		result, err := db.MySQLDBGameWeb.Exec(
+			"UPDATE accountDeletion SET heartbeatTime = NOW() WHERE id = ? AND status = ? AND executorToken = ?",
+			deletionID, StatusExecuting, token)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil || n == 1 {
+			return err
+		}
+		// 同一秒内再次更新时值没有变化，影响行数为0，需要确认执行标识是否仍是本实例
+		var current string
+		err = db.MySQLDBGameWeb.QueryRow(
+			"SELECT COALESCE(executorToken, '') FROM accountDeletion WHERE id = ? AND status = ?",
+			deletionID, StatusExecuting).Scan(&current)
+		if err == sql.ErrNoRows || (err == nil && current != token) {
+			return ErrLostClaim
+		}
+		return err
+	}
+}
+
+// run 执行一个已认领的申请并更新结果；结果只在申请仍由本实例执行时写入
+func run(deletionID uint64, userid int64, token string) {
+	log.Infof("开始执行账号注销: 申请ID=%d, 用户ID=%d", deletionID, userid)
+	if err := Execute(deletionID, userid, beatFunc(deletionID, token)); err != nil {
+		if err == ErrLostClaim {
+			log.Warnf("账号注销申请已被其他实例接手，停止执行: 申请ID=%d, 用户ID=%d", deletionID, userid)
+			return
+		}
+		log.Errorf("账号注销执行失败: 申请ID=%d, 用户ID=%d, err=%v", deletionID, userid, err)
+		if _, uerr := db.MySQLDBGameWeb.Exec(
+			"UPDATE accountDeletion SET status = ?, lastError = ? WHERE id = ? AND status = ? AND executorToken = ?",
+			StatusFailed, truncate(err.Error(), 512), deletionID, StatusExecuting, token); uerr != nil {
+			log.Errorf("更新账号注销状态失败: 申请ID=%d, err=%v", deletionID, uerr)
+		}
+		return
+	}
+
+	if _, err := db.MySQLDBGameWeb.Exec(
+		"UPDATE accountDeletion SET status = ?, completedTime = NOW() WHERE id = ? AND status = ? AND executorToken = ?",
+		StatusCompleted, deletionID, StatusExecuting, token); err != nil {
+		log.Errorf("更新账号注销状态失败: 申请ID=%d, err=%v", deletionID, err)
+		return
+	}
+	log.Infof("账号注销完成: 申请ID=%d, 用户ID=%d", deletionID, userid)
+}
+
+// truncate 按字符截断错误信息，保证不超过字段长度
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max])
+}
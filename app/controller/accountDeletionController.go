@@ -0,0 +1,420 @@
+package controller
+
+import (
+	"database/sql"
+	"errors"
+	"gameWeb/accountdeletion"
+	"gameWeb/config"
+	"gameWeb/db"
+	"gameWeb/log"
+	"gameWeb/middleware"
+	"gameWeb/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestAccountDeletion 玩家申请注销账号，管理员批准且冷静期结束后由执行器处理
+func RequestAccountDeletion(c *gin.Context) {
+	var req struct {
+		Reason string `json:"reason" binding:"max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request",
+		})
+		return
+	}
+
+	userid := c.GetInt64("userid")
+	days := config.AppConfig.AccountDeletion.CoolingOffDays
+	if days < 0 {
+		days = 0
+	}
+	executeAfter := time.Now().Add(time.Duration(days) * 24 * time.Hour)
+
+	id, err := createAccountDeletion(userid, req.Reason, c.ClientIP(), c.GetString("channelid"), executeAfter)
+	if err == errAccountDeletionExists {
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": "An account deletion request is already in progress",
+		})
+		return
+	}
+	if err != nil {
+		log.Errorf("Failed to create account deletion request: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to create account deletion request",
+		})
+		return
+	}
+	log.Infof("Account deletion requested: id=%d, userid=%d, executeAfter=%s", id, userid, executeAfter.Format(time.RFC3339))
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "Success",
+		"data":    gin.H{"id": id, "status": accountdeletion.StatusPending, "executeAfter": executeAfter},
+	})
+}
+
+// CancelAccountDeletion 玩家在执行前撤销注销申请
+func CancelAccountDeletion(c *gin.Context) {
+	userid := c.GetInt64("userid")
+	result, err := db.MySQLDBGameWeb.Exec(
+		"UPDATE accountDeletion SET status = ? WHERE userid = ? AND status IN (?, ?)",
+		accountdeletion.StatusCancelled, userid, accountdeletion.StatusPending, accountdeletion.StatusApproved)
+	if err != nil {
+		log.Errorf("Failed to cancel account deletion request: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to cancel account deletion request",
+		})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "No cancellable account deletion request",
+		})
+		return
+	}
+	log.Infof("Account deletion cancelled: userid=%d", userid)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "Success",
+	})
+}
+
+// GetAccountDeletionStatus 玩家查询最近一次注销申请
+func GetAccountDeletionStatus(c *gin.Context) {
+	deletion, err := getLatestAccountDeletion(c.GetInt64("userid"))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    200,
+			"message": "Success",
+			"data":    nil,
+		})
+		return
+	}
+	if err != nil {
+		log.Errorf("Failed to query account deletion request: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "Success",
+		"data": gin.H{
+			"id":           deletion.ID,
+			"status":       deletion.Status,
+			"executeAfter": deletion.ExecuteAfter,
+			"createdTime":  deletion.CreatedTime,
+		},
+	})
+}
+
+// GetAccountDeletions 管理后台分页查询注销申请
+func GetAccountDeletions(c *gin.Context) {
+	var req models.AccountDeletionQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	whereClause := "WHERE 1=1"
+	var args []interface{}
+	if req.Userid > 0 {
+		whereClause += " AND userid = ?"
+		args = append(args, req.Userid)
+	}
+	if req.Status != nil {
+		whereClause += " AND status = ?"
+		args = append(args, *req.Status)
+	}
+
+	var total int64
+	if err := db.MySQLDBGameWeb.QueryRow("SELECT COUNT(*) FROM accountDeletion "+whereClause, args...).Scan(&total); err != nil {
+		log.Errorf("统计注销申请失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	offset := (req.Page - 1) * req.PageSize
+	list, err := getAccountDeletionList(whereClause, args, req.PageSize, offset)
+	if err != nil {
+		log.Errorf("查询注销申请失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "获取成功",
+		Data: models.PaginationResponse{
+			Total:    total,
+			Page:     req.Page,
+			PageSize: req.PageSize,
+			Data:     list,
+		},
+	})
+}
+
+// GetAccountDeletionDetail 管理后台查看注销申请及执行步骤
+func GetAccountDeletionDetail(c *gin.Context) {
+	deletion, ok := loadAccountDeletionParam(c)
+	if !ok {
+		return
+	}
+
+	steps, err := getAccountDeletionSteps(deletion.ID)
+	if err != nil {
+		log.Errorf("查询注销步骤失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	deletion.Steps = steps
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "获取成功",
+		Data:    deletion,
+	})
+}
+
+// ApproveAccountDeletion 批准注销申请，冷静期结束后执行
+func ApproveAccountDeletion(c *gin.Context) {
+	reviewAccountDeletion(c, accountdeletion.StatusApproved, []int8{accountdeletion.StatusPending}, "已批准")
+}
+
+// RejectAccountDeletion 拒绝注销申请，执行前都可以拒绝
+func RejectAccountDeletion(c *gin.Context) {
+	reviewAccountDeletion(c, accountdeletion.StatusRejected,
+		[]int8{accountdeletion.StatusPending, accountdeletion.StatusApproved}, "已拒绝")
+}
+
+// RetryAccountDeletion 重新执行失败的注销申请，已成功的步骤会跳过
+func RetryAccountDeletion(c *gin.Context) {
+	reviewAccountDeletion(c, accountdeletion.StatusApproved, []int8{accountdeletion.StatusFailed}, "已重新排队执行")
+}
+
+// reviewAccountDeletion 把处于 from 状态的申请改为 to 状态并记录审批人
+func reviewAccountDeletion(c *gin.Context, to int8, from []int8, message string) {
+	var req models.AccountDeletionReviewRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Code:    400,
+				Message: "参数错误: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	deletion, ok := loadAccountDeletionParam(c)
+	if !ok {
+		return
+	}
+	middleware.SetAuditTarget(c, "accountDeletion", deletion.ID)
+	middleware.SetAuditBefore(c, gin.H{"userid": deletion.Userid, "status": deletion.Status})
+
+	allowed := false
+	for _, s := range from {
+		if deletion.Status == s {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Code:    409,
+			Message: "当前状态不能执行此操作",
+		})
+		return
+	}
+
+	adminID := c.GetUint64("adminId")
+	result, err := db.MySQLDBGameWeb.Exec(`
+		UPDATE accountDeletion SET status = ?, reviewerId = ?, reviewerName = ?, reviewComment = ?, reviewedTime = NOW()
+		WHERE id = ? AND status = ?`,
+		to, adminID, c.GetString("username"), req.Comment, deletion.ID, deletion.Status)
+	if err != nil {
+		log.Errorf("更新注销申请失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Code:    409,
+			Message: "申请状态已变化，请刷新后重试",
+		})
+		return
+	}
+	middleware.SetAuditAfter(c, gin.H{"userid": deletion.Userid, "status": to, "comment": req.Comment})
+	log.Infof("注销申请%s: 申请ID=%d, 用户ID=%d, 操作者=%d, IP=%s", message, deletion.ID, deletion.Userid, adminID, c.ClientIP())
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: message,
+	})
+}
+
+// loadAccountDeletionParam 按路由参数 id 查询注销申请，失败时已写入响应
+func loadAccountDeletionParam(c *gin.Context) (*models.AccountDeletion, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的申请ID",
+		})
+		return nil, false
+	}
+	deletion, err := getAccountDeletionByID(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Code:    404,
+			Message: "注销申请不存在",
+		})
+		return nil, false
+	}
+	if err != nil {
+		log.Errorf("查询注销申请失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return nil, false
+	}
+	return deletion, true
+}
+
+// 数据库操作函数
+
+var errAccountDeletionExists = errors.New("account deletion request already in progress")
+
+// accountDeletionColumns 查询注销申请的字段
+const accountDeletionColumns = `id, userid, status, COALESCE(reason, ''), requestIp, COALESCE(channel, ''), executeAfter,
+	reviewerId, COALESCE(reviewerName, ''), COALESCE(reviewComment, ''), reviewedTime, startedTime, completedTime,
+	COALESCE(lastError, ''), createdTime`
+
+// scanAccountDeletion 扫描一行注销申请
+func scanAccountDeletion(row interface{ Scan(...interface{}) error }) (*models.AccountDeletion, error) {
+	var d models.AccountDeletion
+	err := row.Scan(&d.ID, &d.Userid, &d.Status, &d.Reason, &d.RequestIP, &d.Channel, &d.ExecuteAfter,
+		&d.ReviewerID, &d.ReviewerName, &d.ReviewComment, &d.ReviewedTime, &d.StartedTime, &d.CompletedTime,
+		&d.LastError, &d.CreatedTime)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// createAccountDeletion 创建注销申请；同一用户只能有一个未结束的申请（待审批、已批准、执行中、执行失败）
+func createAccountDeletion(userid int64, reason, ip, channel string, executeAfter time.Time) (uint64, error) {
+	tx, err := db.MySQLDBGameWeb.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.QueryRow(
+		"SELECT COUNT(*) FROM accountDeletion WHERE userid = ? AND status IN (?, ?, ?, ?) FOR UPDATE",
+		userid, accountdeletion.StatusPending, accountdeletion.StatusApproved,
+		accountdeletion.StatusExecuting, accountdeletion.StatusFailed).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		return 0, errAccountDeletionExists
+	}
+
+	result, err := tx.Exec(
+		"INSERT INTO accountDeletion (userid, status, reason, requestIp, channel, executeAfter) VALUES (?, ?, ?, ?, ?, ?)",
+		userid, accountdeletion.StatusPending, reason, ip, channel, executeAfter)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(id), tx.Commit()
+}
+
+// getLatestAccountDeletion 查询用户最近一次注销申请
+func getLatestAccountDeletion(userid int64) (*models.AccountDeletion, error) {
+	row := db.MySQLDBGameWeb.QueryRow(
+		"SELECT "+accountDeletionColumns+" FROM accountDeletion WHERE userid = ? ORDER BY id DESC LIMIT 1", userid)
+	return scanAccountDeletion(row)
+}
+
+// getAccountDeletionByID 按ID查询注销申请
+func getAccountDeletionByID(id uint64) (*models.AccountDeletion, error) {
+	row := db.MySQLDBGameWeb.QueryRow("SELECT "+accountDeletionColumns+" FROM accountDeletion WHERE id = ?", id)
+	return scanAccountDeletion(row)
+}
+
+// getAccountDeletionList 分页查询注销申请
+func getAccountDeletionList(whereClause string, args []interface{}, limit, offset int) ([]models.AccountDeletion, error) {
+	query := "SELECT " + accountDeletionColumns + " FROM accountDeletion " + whereClause + " ORDER BY id DESC LIMIT ? OFFSET ?"
+	rows, err := db.MySQLDBGameWeb.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.AccountDeletion{}
+	for rows.Next() {
+		d, err := scanAccountDeletion(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *d)
+	}
+	return list, rows.Err()
+}
+
+// getAccountDeletionSteps 查询注销执行步骤
+func getAccountDeletionSteps(deletionID uint64) ([]models.AccountDeletionStep, error) {
+	rows, err := db.MySQLDBGameWeb.Query(`
+		SELECT step, action, affectedRows, status, COALESCE(errorMessage, ''), executedTime
+		FROM accountDeletionStep WHERE deletionId = ? ORDER BY id`, deletionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	steps := []models.AccountDeletionStep{}
+	for rows.Next() {
+		var s models.AccountDeletionStep
+		if err := rows.Scan(&s.Step, &s.Action, &s.AffectedRows, &s.Status, &s.ErrorMessage, &s.ExecutedTime); err != nil {
+			return nil, err
+		}
+		steps = append(steps, s)
+	}
+	return steps, rows.Err()
+}
@@ -32,12 +32,33 @@ func AdminLogin(c *gin.Context) {
 
 	// 记录登录IP
 	clientIP := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// 检查用户名和IP是否已被锁定
+	if remaining, err := checkAdminLoginLocked(req.Username, clientIP); err != nil {
+		log.Errorf("检查管理员登录锁定失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	} else if remaining > 0 {
+		log.Warnf("管理员登录被拒绝 - 已锁定: %s, IP: %s, 剩余: %v", req.Username, clientIP, remaining)
+		recordAdminLoginLog(nil, req.Username, clientIP, userAgent, 0, "已锁定")
+		respondAdminLoginLocked(c, remaining)
+		return
+	}
 
 	// 1. 查询管理员账户
 	admin, err := getAdminByUsername(req.Username)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Warnf("管理员登录失败 - 账户不存在: %s, IP: %s", req.Username, clientIP)
+			recordAdminLoginLog(nil, req.Username, clientIP, userAgent, 0, "账户不存在")
+			if remaining := recordAdminLoginFailure(req.Username, clientIP); remaining > 0 {
+				respondAdminLoginLocked(c, remaining)
+				return
+			}
 			c.JSON(http.StatusUnauthorized, models.APIResponse{
 				Code:    401,
 				Message: "用户名或密码错误",
@@ -55,6 +76,7 @@ func AdminLogin(c *gin.Context) {
 	// 2. 验证账户状态
 	if admin.Status != 1 {
 		log.Warnf("管理员账户已禁用: %s, IP: %s", req.Username, clientIP)
+		recordAdminLoginLog(&admin.ID, req.Username, clientIP, userAgent, 0, "账户已禁用")
 		c.JSON(http.StatusUnauthorized, models.APIResponse{
 			Code:    401,
 			Message: "账户已被禁用",
@@ -65,6 +87,11 @@ func AdminLogin(c *gin.Context) {
 	// 3. 验证密码
 	if err := bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(req.Password)); err != nil {
 		log.Warnf("管理员登录失败 - 密码错误: %s, IP: %s", req.Username, clientIP)
+		recordAdminLoginLog(&admin.ID, req.Username, clientIP, userAgent, 0, "密码错误")
+		if remaining := recordAdminLoginFailure(req.Username, clientIP); remaining > 0 {
+			respondAdminLoginLocked(c, remaining)
+			return
+		}
 		c.JSON(http.StatusUnauthorized, models.APIResponse{
 			Code:    401,
 			Message: "用户名或密码错误",
@@ -72,15 +99,92 @@ func AdminLogin(c *gin.Context) {
 		return
 	}
 
-	// 4. 更新最后登录信息
+	// 启用单点登录并禁止密码登录后，只有超级管理员可以用密码登录（身份提供方故障时应急）
+	if config.AppConfig.OIDC.Enabled && config.AppConfig.OIDC.DisablePasswordLogin && admin.IsSuperAdmin != 1 {
+		log.Warnf("管理员登录被拒绝 - 已禁止密码登录: %s, IP: %s", req.Username, clientIP)
+		recordAdminLoginLog(&admin.ID, req.Username, clientIP, userAgent, 0, "已禁止密码登录")
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Code:    403,
+			Message: "请使用单点登录",
+		})
+		return
+	}
+
+	// 4. 验证IP白名单
+	if allowed, err := middleware.IsAdminIPAllowed(admin.ID, clientIP); err != nil {
+		log.Errorf("查询IP白名单失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	} else if !allowed {
+		log.Warnf("管理员登录被拒绝 - IP不在白名单: %s, IP: %s", req.Username, clientIP)
+		recordAdminLoginLog(&admin.ID, req.Username, clientIP, userAgent, 0, "IP不在白名单")
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Code:    403,
+			Message: "当前IP不允许登录管理后台",
+		})
+		return
+	}
+
+	// 5. 两步验证：已启用或系统要求启用时，先返回预认证令牌
+	startAdminLoginSession(c, admin)
+}
+
+// startAdminLoginSession 主认证（密码或单点登录）通过后：需要两步验证时返回预认证令牌，否则直接完成登录
+func startAdminLoginSession(c *gin.Context, admin *models.AdminAccount) {
+	clientIP := c.ClientIP()
+	needTwoFactor, enrollRequired, err := adminNeedsTwoFactor(admin.ID)
+	if err != nil {
+		log.Errorf("查询管理员两步验证状态失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if needTwoFactor {
+		preAuthToken, err := createAdminPreAuth(admin.ID, clientIP)
+		if err != nil {
+			log.Errorf("生成管理员预认证令牌失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Code:    500,
+				Message: "系统错误",
+			})
+			return
+		}
+
+		log.Infof("管理员主认证通过，等待两步验证: %s (ID: %d), IP: %s", admin.Username, admin.ID, clientIP)
+		c.JSON(http.StatusOK, models.APIResponse{
+			Code:    200,
+			Message: "请完成两步验证",
+			Data: models.AdminPreAuthResponse{
+				NeedTwoFactor:  true,
+				EnrollRequired: enrollRequired,
+				PreAuthToken:   preAuthToken,
+				ExpiresIn:      int64(adminPreAuthTTL.Seconds()),
+			},
+		})
+		return
+	}
+
+	completeAdminLogin(c, admin, nil)
+}
+
+// completeAdminLogin 完成管理员登录：更新登录信息、签发JWT、缓存会话并返回响应
+func completeAdminLogin(c *gin.Context, admin *models.AdminAccount, recoveryCodes []string) {
+	clientIP := c.ClientIP()
+
+	// 1. 更新最后登录信息
 	admin.LastLoginIP = clientIP
 	admin.LastLoginTime = time.Now()
 	if err := updateAdminLoginInfo(admin); err != nil {
 		log.Errorf("更新管理员登录信息失败: %v", err)
 	}
 
-	// 5. 生成JWT Token
-	token, err := middleware.GenerateAdminJWT(admin)
+	// 2. 生成JWT Token
+	token, sessionID, err := middleware.GenerateAdminJWT(admin)
 	if err != nil {
 		log.Errorf("生成管理员JWT失败: %v", err)
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
@@ -90,17 +194,13 @@ func AdminLogin(c *gin.Context) {
 		return
 	}
 
-	// 6. 缓存会话到Redis
-	sessionKey := fmt.Sprintf("admin_session:%d", admin.ID)
-	sessionData := fmt.Sprintf("active:%d", time.Now().Unix())
-	expiration := time.Duration(config.AppConfig.Admin.SessionTimeout) * time.Hour
-
-	if err := db.SetRedisWithExpire(sessionKey, sessionData, expiration); err != nil {
+	// 3. 缓存会话到Redis（每次登录一个独立会话，不影响其他设备）
+	if err := middleware.CreateAdminSession(admin.ID, sessionID, clientIP, c.GetHeader("User-Agent")); err != nil {
 		log.Errorf("缓存管理员会话失败: %v", err)
 		// 不返回错误，登录仍然可以继续，但会话验证可能会失败
 	}
 
-	// 7. 构建响应
+	// 4. 构建响应
 	adminInfo := &models.AdminInfo{
 		ID:            admin.ID,
 		Username:      admin.Username,
@@ -119,14 +219,22 @@ func AdminLogin(c *gin.Context) {
 		CreatedTime:   admin.CreatedTime,
 		UpdatedTime:   admin.UpdatedTime,
 	}
+	if perms, err := middleware.GetAdminPermissions(admin.ID); err != nil {
+		log.Errorf("查询管理员权限失败: %v", err)
+	} else {
+		adminInfo.Permissions = perms
+	}
 
 	response := models.AdminLoginResponse{
-		Token:     token,
-		AdminInfo: adminInfo,
+		Token:         token,
+		AdminInfo:     adminInfo,
+		RecoveryCodes: recoveryCodes,
 	}
 
+	clearAdminLoginFailures(admin.Username)
+	recordAdminLoginLog(&admin.ID, admin.Username, clientIP, c.GetHeader("User-Agent"), 1, "")
 	log.Infof("管理员登录成功: %s (ID: %d), IP: %s", admin.Username, admin.ID, clientIP)
-	
+
 	c.JSON(http.StatusOK, models.APIResponse{
 		Code:    200,
 		Message: "登录成功",
@@ -146,9 +254,9 @@ func AdminLogout(c *gin.Context) {
 		return
 	}
 
-	// 删除Redis中的会话
-	sessionKey := fmt.Sprintf("admin_session:%d", adminId)
-	if err := db.DelRedis(sessionKey); err != nil {
+	// 只删除当前会话，其他设备的会话不受影响
+	sessionID := c.GetString("sessionId")
+	if _, err := middleware.RevokeAdminSession(adminId.(uint64), sessionID); err != nil {
 		log.Errorf("删除管理员会话失败: %v", err)
 	}
 
@@ -201,6 +309,11 @@ func GetAdminInfo(c *gin.Context) {
 		CreatedTime:   admin.CreatedTime,
 		UpdatedTime:   admin.UpdatedTime,
 	}
+	if perms, err := middleware.GetAdminPermissions(admin.ID); err != nil {
+		log.Errorf("查询管理员权限失败: %v", err)
+	} else {
+		adminInfo.Permissions = perms
+	}
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Code:    200,
@@ -209,17 +322,18 @@ func GetAdminInfo(c *gin.Context) {
 	})
 }
 
-// CreateAdmin 创建管理员账户（仅超级管理员可用）
+// CreateAdmin 创建管理员账户（需要admin.manage权限）
 func CreateAdmin(c *gin.Context) {
 	var req struct {
-		Username     string `json:"username" binding:"required,min=3,max=50"`
-		Password     string `json:"password" binding:"required,min=6,max=50"`
-		Email        string `json:"email" binding:"required,email"`
-		RealName     string `json:"realName" binding:"required,min=1,max=50"`
-		Mobile       string `json:"mobile"`
-		IsSuperAdmin bool   `json:"isSuperAdmin"`
-		DepartmentID *int   `json:"departmentId"`
-		Note         string `json:"note"`
+		Username     string   `json:"username" binding:"required,min=3,max=50"`
+		Password     string   `json:"password" binding:"required,min=6,max=50"`
+		Email        string   `json:"email" binding:"required,email"`
+		RealName     string   `json:"realName" binding:"required,min=1,max=50"`
+		Mobile       string   `json:"mobile"`
+		IsSuperAdmin bool     `json:"isSuperAdmin"`
+		DepartmentID *int     `json:"departmentId"`
+		Note         string   `json:"note"`
+		RoleIDs      []uint64 `json:"roleIds"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -231,6 +345,15 @@ func CreateAdmin(c *gin.Context) {
 		return
 	}
 
+	// 只有超级管理员才能创建超级管理员
+	if isSuperAdmin, _ := c.Get("isSuperAdmin"); req.IsSuperAdmin && isSuperAdmin != true {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Code:    403,
+			Message: "仅超级管理员可创建超级管理员账户",
+		})
+		return
+	}
+
 	// 检查用户名是否已存在
 	if exists, err := checkAdminUsernameExists(req.Username); err != nil {
 		log.Errorf("检查用户名失败: %v", err)
@@ -311,6 +434,16 @@ func CreateAdmin(c *gin.Context) {
 		return
 	}
 
+	// 分配初始角色
+	if len(req.RoleIDs) > 0 {
+		if err := setAdminRoles(admin.ID, req.RoleIDs, createdByID); err != nil {
+			log.Errorf("分配管理员角色失败: %v", err)
+		}
+	}
+
+	middleware.SetAuditTarget(c, "admin", admin.ID)
+	middleware.SetAuditAfter(c, gin.H{"admin": admin, "roleIds": req.RoleIDs})
+
 	log.Infof("管理员账户创建成功: %s, 创建者: %d", req.Username, createdByID)
 	
 	c.JSON(http.StatusOK, models.APIResponse{
@@ -331,6 +464,7 @@ func getAdminByUsername(username string) (*models.AdminAccount, error) {
 	query := `
 		SELECT id, username, passwordHash, email, mobile, status, isSuperAdmin, 
 		       realName, avatar, departmentId, note, lastLoginIp, lastLoginTime,
+		       passwordResetToken, tokenExpireTime,
 		       createdBy, updatedBy, createdTime, updatedTime 
 		FROM adminAccount 
 		WHERE username = ?
@@ -339,13 +473,14 @@ func getAdminByUsername(username string) (*models.AdminAccount, error) {
 	row := db.MySQLDBGameWeb.QueryRow(query, username)
 	
 	var departmentId, createdBy, updatedBy sql.NullInt64
-	var lastLoginTime, createdTime, updatedTime sql.NullTime
-	var avatar, mobile, note, lastLoginIp sql.NullString
+	var lastLoginTime, tokenExpireTime, createdTime, updatedTime sql.NullTime
+	var avatar, mobile, note, lastLoginIp, passwordResetToken sql.NullString
 
 	err := row.Scan(
 		&admin.ID, &admin.Username, &admin.PasswordHash, &admin.Email,
 		&mobile, &admin.Status, &admin.IsSuperAdmin, &admin.RealName,
 		&avatar, &departmentId, &note, &lastLoginIp, &lastLoginTime,
+		&passwordResetToken, &tokenExpireTime,
 		&createdBy, &updatedBy, &createdTime, &updatedTime,
 	)
 
@@ -373,6 +508,12 @@ func getAdminByUsername(username string) (*models.AdminAccount, error) {
 	if lastLoginTime.Valid {
 		admin.LastLoginTime = lastLoginTime.Time
 	}
+	if passwordResetToken.Valid {
+		admin.PasswordResetToken = passwordResetToken.String
+	}
+	if tokenExpireTime.Valid {
+		admin.TokenExpireTime = &tokenExpireTime.Time
+	}
 	if createdBy.Valid {
 		createdByID := uint64(createdBy.Int64)
 		admin.CreatedBy = &createdByID
@@ -397,6 +538,7 @@ func getAdminByID(id uint64) (*models.AdminAccount, error) {
 	query := `
 		SELECT id, username, passwordHash, email, mobile, status, isSuperAdmin, 
 		       realName, avatar, departmentId, note, lastLoginIp, lastLoginTime,
+		       passwordResetToken, tokenExpireTime,
 		       createdBy, updatedBy, createdTime, updatedTime 
 		FROM adminAccount 
 		WHERE id = ?
@@ -405,13 +547,14 @@ func getAdminByID(id uint64) (*models.AdminAccount, error) {
 	row := db.MySQLDBGameWeb.QueryRow(query, id)
 	
 	var departmentId, createdBy, updatedBy sql.NullInt64
-	var lastLoginTime, createdTime, updatedTime sql.NullTime
-	var avatar, mobile, note, lastLoginIp sql.NullString
+	var lastLoginTime, tokenExpireTime, createdTime, updatedTime sql.NullTime
+	var avatar, mobile, note, lastLoginIp, passwordResetToken sql.NullString
 
 	err := row.Scan(
 		&admin.ID, &admin.Username, &admin.PasswordHash, &admin.Email,
 		&mobile, &admin.Status, &admin.IsSuperAdmin, &admin.RealName,
 		&avatar, &departmentId, &note, &lastLoginIp, &lastLoginTime,
+		&passwordResetToken, &tokenExpireTime,
 		&createdBy, &updatedBy, &createdTime, &updatedTime,
 	)
 
@@ -439,6 +582,12 @@ func getAdminByID(id uint64) (*models.AdminAccount, error) {
 	if lastLoginTime.Valid {
 		admin.LastLoginTime = lastLoginTime.Time
 	}
+	if passwordResetToken.Valid {
+		admin.PasswordResetToken = passwordResetToken.String
+	}
+	if tokenExpireTime.Valid {
+		admin.TokenExpireTime = &tokenExpireTime.Time
+	}
 	if createdBy.Valid {
 		createdByID := uint64(createdBy.Int64)
 		admin.CreatedBy = &createdByID
@@ -606,19 +755,8 @@ func UpdateAdmin(c *gin.Context) {
 		return
 	}
 
-	// 获取当前管理员信息以检查权限
-	currentAdmin, err := getAdminByID(currentAdminID.(uint64))
-	if err != nil {
-		log.Errorf("查询当前管理员信息失败: %v", err)
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Code:    500,
-			Message: "系统错误",
-		})
-		return
-	}
-
-	// 权限检查：只能修改自己的信息，或者超级管理员可以修改任何人的信息
-	if currentAdminID.(uint64) != targetAdminID && currentAdmin.IsSuperAdmin != 1 {
+	// 权限检查：只能修改自己的信息，或者拥有管理员管理权限可以修改其他人的信息
+	if currentAdminID.(uint64) != targetAdminID && !middleware.HasPermission(c, middleware.PermAdminManage) {
 		c.JSON(http.StatusForbidden, models.APIResponse{
 			Code:    403,
 			Message: "没有权限修改该管理员信息",
@@ -663,6 +801,15 @@ func UpdateAdmin(c *gin.Context) {
 		return
 	}
 
+	// 只有超级管理员才能修改其他超级管理员（以上下文为准，API密钥从不具备超级管理员身份）
+	if currentAdminID.(uint64) != targetAdminID && targetAdmin.IsSuperAdmin == 1 && !c.GetBool("isSuperAdmin") {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Code:    403,
+			Message: "没有权限修改超级管理员信息",
+		})
+		return
+	}
+
 	// 检查邮箱唯一性（如果要更新邮箱）
 	if req.Email != nil && *req.Email != targetAdmin.Email {
 		if exists, err := checkAdminEmailExistsExcludeID(*req.Email, targetAdminID); err != nil {
@@ -681,6 +828,9 @@ func UpdateAdmin(c *gin.Context) {
 		}
 	}
 
+	middleware.SetAuditTarget(c, "admin", targetAdminID)
+	middleware.SetAuditBefore(c, targetAdmin)
+
 	// 执行更新操作
 	if err := updateAdminInfo(targetAdminID, req.Email, req.Mobile, req.RealName, req.Avatar, req.DepartmentID, req.Note, currentAdminID.(uint64)); err != nil {
 		log.Errorf("更新管理员信息失败: %v", err)
@@ -691,6 +841,10 @@ func UpdateAdmin(c *gin.Context) {
 		return
 	}
 
+	if after, err := getAdminByID(targetAdminID); err == nil {
+		middleware.SetAuditAfter(c, after)
+	}
+
 	log.Infof("管理员信息更新成功: ID: %d, 操作者: %d", targetAdminID, currentAdminID.(uint64))
 	
 	c.JSON(http.StatusOK, models.APIResponse{
@@ -710,7 +864,7 @@ func generateSecureToken(length int) (string, error) {
 	return fmt.Sprintf("%x", bytes), nil
 }
 
-// DeleteAdmin 删除管理员账户（仅超级管理员可用）
+// DeleteAdmin 删除管理员账户（需要admin.manage权限）
 func DeleteAdmin(c *gin.Context) {
 	// 获取路径参数中的管理员ID
 	adminID := c.Param("id")
@@ -753,11 +907,11 @@ func DeleteAdmin(c *gin.Context) {
 		return
 	}
 
-	// 权限检查：只有超级管理员才能删除其他管理员
-	if currentAdmin.IsSuperAdmin != 1 {
+	// 权限检查：需要管理员管理权限
+	if !middleware.HasPermission(c, middleware.PermAdminManage) {
 		c.JSON(http.StatusForbidden, models.APIResponse{
 			Code:    403,
-			Message: "仅超级管理员可执行此操作",
+			Message: "没有权限执行此操作",
 		})
 		return
 	}
@@ -789,6 +943,15 @@ func DeleteAdmin(c *gin.Context) {
 		return
 	}
 
+	// 只有超级管理员才能删除超级管理员（以上下文为准，API密钥从不具备超级管理员身份）
+	if targetAdmin.IsSuperAdmin == 1 && !c.GetBool("isSuperAdmin") {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Code:    403,
+			Message: "仅超级管理员可删除超级管理员账户",
+		})
+		return
+	}
+
 	// 检查是否为最后一个超级管理员
 	if targetAdmin.IsSuperAdmin == 1 {
 		superAdminCount, err := countSuperAdmins()
@@ -810,6 +973,9 @@ func DeleteAdmin(c *gin.Context) {
 		}
 	}
 
+	middleware.SetAuditTarget(c, "admin", targetAdminID)
+	middleware.SetAuditBefore(c, targetAdmin)
+
 	// 执行删除操作
 	if err := deleteAdminByID(targetAdminID); err != nil {
 		log.Errorf("删除管理员失败: %v", err)
@@ -820,9 +986,14 @@ func DeleteAdmin(c *gin.Context) {
 		return
 	}
 
+	// 清除角色关联和权限缓存
+	if err := setAdminRoles(targetAdminID, nil, currentAdminID.(uint64)); err != nil {
+		log.Errorf("清除管理员角色失败: %v", err)
+	}
+	middleware.ClearAdminPermissionCache(targetAdminID)
+
 	// 清除相关的Redis会话
-	sessionKey := fmt.Sprintf("admin_session:%d", targetAdminID)
-	if err := db.DelRedis(sessionKey); err != nil {
+	if _, err := middleware.RevokeAllAdminSessions(targetAdminID); err != nil {
 		log.Errorf("清除管理员会话失败: %v", err)
 		// 不影响主流程，只记录日志
 	}
@@ -847,11 +1018,15 @@ func countSuperAdmins() (int, error) {
 // deleteAdminByID 根据ID删除管理员
 func deleteAdminByID(adminID uint64) error {
 	query := "DELETE FROM adminAccount WHERE id = ?"
-	_, err := db.MySQLDBGameWeb.Exec(query, adminID)
+	if _, err := db.MySQLDBGameWeb.Exec(query, adminID); err != nil {
+		return err
+	}
+	// 解除单点登录身份关联，该身份再次登录时按配置重新关联或创建
+	_, err := db.MySQLDBGameWeb.Exec("DELETE FROM adminIdentity WHERE adminId = ?", adminID)
 	return err
 }
 
-// GetAdminList 获取管理员列表（仅超级管理员可用）
+// GetAdminList 获取管理员列表（需要admin.manage权限）
 func GetAdminList(c *gin.Context) {
 	// 解析查询参数
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -878,21 +1053,11 @@ func GetAdminList(c *gin.Context) {
 		return
 	}
 
-	currentAdmin, err := getAdminByID(currentAdminID.(uint64))
-	if err != nil {
-		log.Errorf("查询当前管理员信息失败: %v", err)
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Code:    500,
-			Message: "系统错误",
-		})
-		return
-	}
-
-	// 权限检查：只有超级管理员可以查看所有管理员列表
-	if currentAdmin.IsSuperAdmin != 1 {
+	// 权限检查：需要管理员管理权限才能查看所有管理员列表
+	if !middleware.HasPermission(c, middleware.PermAdminManage) {
 		c.JSON(http.StatusForbidden, models.APIResponse{
 			Code:    403,
-			Message: "仅超级管理员可查看管理员列表",
+			Message: "没有权限查看管理员列表",
 		})
 		return
 	}
@@ -0,0 +1,538 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"gameWeb/config"
+	"gameWeb/db"
+	"gameWeb/log"
+	"gameWeb/middleware"
+	"gameWeb/models"
+	"gameWeb/oidc"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// adminOIDCStateTTL 单点登录请求（state）有效期
+const adminOIDCStateTTL = 10 * time.Minute
+
+// oidcPasswordHash 自动创建的单点登录账户的密码哈希，不是有效的bcrypt哈希，无法用密码登录
+const oidcPasswordHash = "!oidc"
+
+// consumeOIDCStateScript 原子地读取并删除state，保证每个登录请求只能回调一次
+const consumeOIDCStateScript = `
+local value = redis.call('GET', KEYS[1])
+if value then
+	redis.call('DEL', KEYS[1])
+end
+return value
+`
+
+// errOIDCAccountNotLinked 身份没有关联管理员账户，且未开启自动创建
+var errOIDCAccountNotLinked = errors.New("oidc identity not linked")
+
+// errOIDCAccountConflict 自动创建账户时用户名或邮箱已被未关联的账户占用
+var errOIDCAccountConflict = errors.New("oidc account conflict")
+
+// adminOIDCState 发起登录时保存的state数据
+type adminOIDCState struct {
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	IP       string `json:"ip"`
+}
+
+// adminOIDCStateKey state在Redis中的键
+func adminOIDCStateKey(state string) string {
+	return "admin_oidc_state:" + state
+}
+
+// AdminOIDCLogin 发起单点登录，返回身份提供方的授权地址
+func AdminOIDCLogin(c *gin.Context) {
+	if oidc.Admin == nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Code:    404,
+			Message: "未启用单点登录",
+		})
+		return
+	}
+
+	state, err := oidc.RandomString(24)
+	if err != nil {
+		log.Errorf("生成单点登录state失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	nonce, err := oidc.RandomString(24)
+	if err != nil {
+		log.Errorf("生成单点登录nonce失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	verifier, challenge, err := oidc.GeneratePKCE()
+	if err != nil {
+		log.Errorf("生成PKCE失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	authURL, err := oidc.Admin.AuthCodeURL(c.Request.Context(), state, nonce, challenge)
+	if err != nil {
+		log.Errorf("获取身份提供方配置失败: %v", err)
+		c.JSON(http.StatusBadGateway, models.APIResponse{
+			Code:    502,
+			Message: "身份提供方暂时不可用",
+		})
+		return
+	}
+
+	data, _ := json.Marshal(adminOIDCState{Nonce: nonce, Verifier: verifier, IP: c.ClientIP()})
+	if err := db.SetRedisWithExpire(adminOIDCStateKey(state), string(data), adminOIDCStateTTL); err != nil {
+		log.Errorf("保存单点登录state失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "获取成功",
+		Data: models.AdminOIDCLoginResponse{
+			AuthorizationURL: authURL,
+			State:            state,
+			ExpiresIn:        int64(adminOIDCStateTTL.Seconds()),
+		},
+	})
+}
+
+// AdminOIDCCallback 单点登录回调：用授权码换取ID Token，关联或创建管理员账户后按普通登录流程签发JWT
+func AdminOIDCCallback(c *gin.Context) {
+	if oidc.Admin == nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Code:    404,
+			Message: "未启用单点登录",
+		})
+		return
+	}
+
+	var req models.AdminOIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	clientIP := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// 1. 校验state（一次性，且必须与发起登录的IP一致）
+	state, ok := consumeAdminOIDCState(req.State)
+	if !ok || state.IP != clientIP {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "登录请求无效或已过期，请重新登录",
+		})
+		return
+	}
+
+	// 2. 换取并验证ID Token
+	ctx := c.Request.Context()
+	token, err := oidc.Admin.Exchange(ctx, req.Code, state.Verifier)
+	if err != nil {
+		log.Errorf("单点登录换取令牌失败: %v, IP: %s", err, clientIP)
+		recordAdminLoginLog(nil, "", clientIP, userAgent, 0, "单点登录换取令牌失败")
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Code:    401,
+			Message: "单点登录失败，请重新登录",
+		})
+		return
+	}
+	claims, err := oidc.Admin.VerifyIDToken(ctx, token.IDToken, state.Nonce)
+	if err != nil {
+		log.Errorf("单点登录ID Token验证失败: %v, IP: %s", err, clientIP)
+		recordAdminLoginLog(nil, "", clientIP, userAgent, 0, "单点登录ID Token无效")
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Code:    401,
+			Message: "单点登录失败，请重新登录",
+		})
+		return
+	}
+	subject := oidc.ClaimString(claims, "sub")
+	ssoName := oidcUsername(claims)
+
+	// 3. 查找关联的管理员账户，按配置关联已有账户或自动创建
+	admin, err := resolveOIDCAdmin(claims)
+	if err != nil {
+		switch err {
+		case errOIDCAccountNotLinked:
+			log.Warnf("单点登录被拒绝 - 未关联管理员账户: sub=%s, 用户=%s, IP: %s", subject, ssoName, clientIP)
+			recordAdminLoginLog(nil, ssoName, clientIP, userAgent, 0, "单点登录身份未关联账户")
+			c.JSON(http.StatusForbidden, models.APIResponse{
+				Code:    403,
+				Message: "该身份未关联管理员账户，请联系管理员",
+			})
+		case errOIDCAccountConflict:
+			log.Warnf("单点登录被拒绝 - 用户名或邮箱已被占用: sub=%s, 用户=%s, IP: %s", subject, ssoName, clientIP)
+			recordAdminLoginLog(nil, ssoName, clientIP, userAgent, 0, "单点登录账户冲突")
+			c.JSON(http.StatusConflict, models.APIResponse{
+				Code:    409,
+				Message: "用户名或邮箱已被其他管理员账户占用，请联系管理员",
+			})
+		default:
+			log.Errorf("单点登录查询管理员账户失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Code:    500,
+				Message: "系统错误",
+			})
+		}
+		return
+	}
+
+	// 4. 验证账户状态
+	if admin.Status != 1 {
+		log.Warnf("管理员账户已禁用: %s, IP: %s", admin.Username, clientIP)
+		recordAdminLoginLog(&admin.ID, admin.Username, clientIP, userAgent, 0, "账户已禁用")
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Code:    401,
+			Message: "账户已被禁用",
+		})
+		return
+	}
+
+	// 5. 验证IP白名单
+	if allowed, err := middleware.IsAdminIPAllowed(admin.ID, clientIP); err != nil {
+		log.Errorf("查询IP白名单失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	} else if !allowed {
+		log.Warnf("管理员登录被拒绝 - IP不在白名单: %s, IP: %s", admin.Username, clientIP)
+		recordAdminLoginLog(&admin.ID, admin.Username, clientIP, userAgent, 0, "IP不在白名单")
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Code:    403,
+			Message: "当前IP不允许登录管理后台",
+		})
+		return
+	}
+
+	// 6. 按角色声明同步角色和超级管理员标记
+	if err := syncOIDCAdminRoles(admin, claims); err != nil {
+		log.Errorf("同步单点登录角色失败: %v, 管理员: %s", err, admin.Username)
+	}
+	if err := touchAdminIdentity(oidc.Admin.Issuer, subject, oidc.ClaimString(claims, "email")); err != nil {
+		log.Errorf("更新单点登录身份失败: %v", err)
+	}
+
+	log.Infof("管理员单点登录认证通过: %s (ID: %d), sub=%s, IP: %s", admin.Username, admin.ID, subject, clientIP)
+
+	// 7. 两步验证和签发JWT与密码登录相同
+	startAdminLoginSession(c, admin)
+}
+
+// consumeAdminOIDCState 读取并删除state
+func consumeAdminOIDCState(state string) (*adminOIDCState, bool) {
+	result, err := db.EvalRedis(consumeOIDCStateScript, []string{adminOIDCStateKey(state)})
+	if err != nil {
+		// 键不存在时脚本返回nil，go-redis会返回redis.Nil
+		return nil, false
+	}
+	data, ok := result.(string)
+	if !ok {
+		return nil, false
+	}
+	var s adminOIDCState
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return nil, false
+	}
+	return &s, true
+}
+
+// resolveOIDCAdmin 查找身份关联的管理员账户；首次登录时按配置用已验证邮箱关联已有账户，或自动创建账户
+func resolveOIDCAdmin(claims jwt.MapClaims) (*models.AdminAccount, error) {
+	issuer := oidc.Admin.Issuer
+	subject := oidc.ClaimString(claims, "sub")
+
+	adminID, err := getAdminIDByIdentity(issuer, subject)
+	if err == nil {
+		return getAdminByID(adminID)
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	cfg := config.AppConfig.OIDC
+	email := oidc.ClaimString(claims, "email")
+	emailVerified := email != "" && oidc.ClaimBool(claims, "email_verified")
+
+	// 按已验证邮箱关联已有账户（不按用户名关联，避免IdP中同名用户接管本地账户）
+	if cfg.LinkByEmail && emailVerified {
+		var id uint64
+		err := db.MySQLDBGameWeb.QueryRow("SELECT id FROM adminAccount WHERE email = ?", email).Scan(&id)
+		if err == nil {
+			if err := createAdminIdentity(id, issuer, subject, email); err != nil {
+				return nil, err
+			}
+			log.Infof("单点登录身份已关联管理员账户: 管理员ID=%d, sub=%s, email=%s", id, subject, email)
+			return getAdminByID(id)
+		}
+		if err != sql.ErrNoRows {
+			return nil, err
+		}
+	}
+
+	if !cfg.AutoProvision {
+		return nil, errOIDCAccountNotLinked
+	}
+	// 自动创建需要邮箱（adminAccount.email 唯一），未验证的邮箱不能占用
+	if !emailVerified {
+		return nil, errOIDCAccountNotLinked
+	}
+	return provisionOIDCAdmin(claims, issuer, subject, email)
+}
+
+// provisionOIDCAdmin 自动创建单点登录管理员账户，创建后没有任何角色，由角色映射或管理员分配
+func provisionOIDCAdmin(claims jwt.MapClaims, issuer, subject, email string) (*models.AdminAccount, error) {
+	if exists, err := checkAdminEmailExists(email); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, errOIDCAccountConflict
+	}
+
+	username := oidcUsername(claims)
+	if exists, err := checkAdminUsernameExists(username); err != nil {
+		return nil, err
+	} else if exists {
+		// 用户名被本地账户占用时追加身份标识的哈希
+		sum := sha256.Sum256([]byte(issuer + "|" + subject))
+		username = truncateRunes(username, 43) + "_" + hex.EncodeToString(sum[:])[:6]
+		if exists, err := checkAdminUsernameExists(username); err != nil {
+			return nil, err
+		} else if exists {
+			return nil, errOIDCAccountConflict
+		}
+	}
+
+	realName := oidc.ClaimString(claims, "name")
+	if realName == "" {
+		realName = username
+	}
+
+	tx, err := db.MySQLDBGameWeb.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO adminAccount (username, passwordHash, email, status, isSuperAdmin, realName, note)
+		VALUES (?, ?, ?, 1, 0, ?, ?)
+	`, username, oidcPasswordHash, email, truncateRunes(realName, 50), "单点登录自动创建")
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO adminIdentity (adminId, issuer, subject, email) VALUES (?, ?, ?, ?)",
+		id, issuer, subject, email,
+	); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Infof("单点登录自动创建管理员账户: %s (ID: %d), sub=%s, email=%s", username, id, subject, email)
+	return getAdminByID(uint64(id))
+}
+
+// syncOIDCAdminRoles 按角色声明同步管理员角色和超级管理员标记，未配置映射时不修改
+func syncOIDCAdminRoles(admin *models.AdminAccount, claims jwt.MapClaims) error {
+	cfg := config.AppConfig.OIDC
+	values := map[string]bool{}
+	for _, v := range oidc.ClaimStrings(claims, cfg.RolesClaim) {
+		values[v] = true
+	}
+	changed := false
+
+	if len(cfg.RoleMappings) > 0 {
+		names := []string{}
+		for _, m := range cfg.RoleMappings {
+			if values[m.Value] {
+				names = append(names, m.Roles...)
+			}
+		}
+		roleIDs, err := getRoleIDsByNames(names)
+		if err != nil {
+			return err
+		}
+		current, err := getRolesByAdminID(admin.ID)
+		if err != nil {
+			return err
+		}
+		currentIDs := make([]uint64, 0, len(current))
+		for _, r := range current {
+			currentIDs = append(currentIDs, r.ID)
+		}
+		if !sameRoleIDs(currentIDs, roleIDs) {
+			if err := setAdminRoles(admin.ID, roleIDs, admin.ID); err != nil {
+				return err
+			}
+			log.Infof("单点登录同步管理员角色: %s (ID: %d), 角色: %v -> %v", admin.Username, admin.ID, currentIDs, roleIDs)
+			changed = true
+		}
+	}
+
+	if len(cfg.SuperAdminValues) > 0 {
+		var isSuperAdmin int8
+		for _, v := range cfg.SuperAdminValues {
+			if values[v] {
+				isSuperAdmin = 1
+			}
+		}
+		if isSuperAdmin != admin.IsSuperAdmin {
+			if isSuperAdmin == 0 {
+				// 不撤销最后一个超级管理员，避免系统无人可管
+				count, err := countSuperAdmins()
+				if err != nil {
+					return err
+				}
+				if count <= 1 {
+					log.Warnf("单点登录未撤销超级管理员 - 最后一个超级管理员: %s (ID: %d)", admin.Username, admin.ID)
+					isSuperAdmin = admin.IsSuperAdmin
+				}
+			}
+			if isSuperAdmin != admin.IsSuperAdmin {
+				if _, err := db.MySQLDBGameWeb.Exec("UPDATE adminAccount SET isSuperAdmin = ? WHERE id = ?", isSuperAdmin, admin.ID); err != nil {
+					return err
+				}
+				log.Infof("单点登录同步超级管理员标记: %s (ID: %d), %d -> %d", admin.Username, admin.ID, admin.IsSuperAdmin, isSuperAdmin)
+				admin.IsSuperAdmin = isSuperAdmin
+				changed = true
+			}
+		}
+	}
+
+	if changed {
+		middleware.ClearAdminPermissionCache(admin.ID)
+	}
+	return nil
+}
+
+// oidcUsername 从声明中取用户名：配置的用户名声明，其次邮箱前缀，最后sub
+func oidcUsername(claims jwt.MapClaims) string {
+	username := oidc.ClaimString(claims, config.AppConfig.OIDC.UsernameClaim)
+	if username == "" {
+		if email := oidc.ClaimString(claims, "email"); email != "" {
+			username = strings.SplitN(email, "@", 2)[0]
+		}
+	}
+	if username == "" {
+		username = oidc.ClaimString(claims, "sub")
+	}
+	return truncateRunes(username, 50)
+}
+
+// truncateRunes 按字符截断
+func truncateRunes(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max])
+}
+
+// sameRoleIDs 判断两组角色ID是否相同（忽略顺序）
+func sameRoleIDs(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	x := append([]uint64{}, a...)
+	y := append([]uint64{}, b...)
+	sort.Slice(x, func(i, j int) bool { return x[i] < x[j] })
+	sort.Slice(y, func(i, j int) bool { return y[i] < y[j] })
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// getAdminIDByIdentity 根据外部身份查询关联的管理员ID
+func getAdminIDByIdentity(issuer, subject string) (uint64, error) {
+	var adminID uint64
+	query := "SELECT adminId FROM adminIdentity WHERE issuer = ? AND subject = ?"
+	err := db.MySQLDBGameWeb.QueryRow(query, issuer, subject).Scan(&adminID)
+	return adminID, err
+}
+
+// createAdminIdentity 关联外部身份和管理员账户
+func createAdminIdentity(adminID uint64, issuer, subject, email string) error {
+	_, err := db.MySQLDBGameWeb.Exec(
+		"INSERT INTO adminIdentity (adminId, issuer, subject, email) VALUES (?, ?, ?, ?)",
+		adminID, issuer, subject, email,
+	)
+	return err
+}
+
+// touchAdminIdentity 更新外部身份的最近登录时间和邮箱
+func touchAdminIdentity(issuer, subject, email string) error {
+	var emailValue interface{}
+	if email != "" {
+		emailValue = email
+	}
+	_, err := db.MySQLDBGameWeb.Exec(
+		"UPDATE adminIdentity SET lastLoginTime = NOW(), email = COALESCE(?, email) WHERE issuer = ? AND subject = ?",
+		emailValue, issuer, subject,
+	)
+	return err
+}
+
+// getRoleIDsByNames 根据角色名称查询角色ID，不存在的角色记录警告后忽略
+func getRoleIDsByNames(names []string) ([]uint64, error) {
+	ids := []uint64{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		var id uint64
+		err := db.MySQLDBGameWeb.QueryRow("SELECT id FROM adminRole WHERE name = ?", name).Scan(&id)
+		if err == sql.ErrNoRows {
+			log.Warnf("单点登录角色映射中的角色不存在: %s", name)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,321 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"gameWeb/config"
+	"gameWeb/db"
+	"gameWeb/log"
+	"gameWeb/middleware"
+	"gameWeb/models"
+	"gameWeb/notifier"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// adminPasswordForgotPrefix 找回密码请求频率限制键前缀（按IP）
+	adminPasswordForgotPrefix = "admin_pwd_forgot:"
+	// adminPasswordForgotLimit 同一IP每小时最多发起的找回密码请求次数
+	adminPasswordForgotLimit = 5
+)
+
+// ForgotAdminPassword 管理员自助找回密码：向账户绑定的邮箱发送重置链接
+// 无论账户是否存在都返回相同结果，避免被用来探测账户
+func ForgotAdminPassword(c *gin.Context) {
+	var req struct {
+		Account string `json:"account" binding:"required,max=100"` // 用户名或邮箱
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("找回密码参数绑定失败: %v", err)
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	clientIP := c.ClientIP()
+	limitKey := adminPasswordForgotPrefix + clientIP
+	count, err := db.IncrRedis(limitKey)
+	if err != nil {
+		log.Errorf("记录找回密码请求次数失败: %v", err)
+	} else {
+		if count == 1 {
+			if err := db.ExpireRedis(limitKey, time.Hour); err != nil {
+				log.Errorf("设置找回密码请求次数过期时间失败: %v", err)
+			}
+		}
+		if count > adminPasswordForgotLimit {
+			c.JSON(http.StatusTooManyRequests, models.APIResponse{
+				Code:    429,
+				Message: "请求过于频繁，请稍后再试",
+			})
+			return
+		}
+	}
+
+	admin, err := getAdminByAccount(strings.TrimSpace(req.Account))
+	switch {
+	case err == sql.ErrNoRows:
+		log.Warnf("找回密码账户不存在: %s, IP: %s", req.Account, clientIP)
+	case err != nil:
+		log.Errorf("查询管理员失败: %v", err)
+	case admin.Status != 1:
+		log.Warnf("找回密码账户已禁用: %s, IP: %s", admin.Username, clientIP)
+	case admin.Email == "":
+		log.Warnf("找回密码账户未绑定邮箱: %s, IP: %s", admin.Username, clientIP)
+	default:
+		if _, err := issueAdminPasswordReset(admin); err != nil {
+			log.Errorf("发送密码重置邮件失败: %v", err)
+		} else {
+			log.Infof("管理员申请找回密码: %s (ID: %d), IP: %s", admin.Username, admin.ID, clientIP)
+		}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "如果账户存在且已绑定邮箱，重置邮件已发送",
+	})
+}
+
+// InitiateAdminPasswordReset 超级管理员为指定管理员发起密码重置
+func InitiateAdminPasswordReset(c *gin.Context) {
+	targetAdminID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的管理员ID格式",
+		})
+		return
+	}
+
+	admin, err := getAdminByID(targetAdminID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Code:    404,
+				Message: "管理员不存在",
+			})
+			return
+		}
+		log.Errorf("查询管理员失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if admin.Email == "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "该管理员未绑定邮箱，无法发送重置邮件",
+		})
+		return
+	}
+
+	expireTime, err := issueAdminPasswordReset(admin)
+	if err != nil {
+		log.Errorf("发送密码重置邮件失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "发送重置邮件失败",
+		})
+		return
+	}
+
+	operatorID, _ := c.Get("adminId")
+	log.Infof("超级管理员发起密码重置: 目标=%s (ID: %d), 操作者=%v, IP=%s", admin.Username, admin.ID, operatorID, c.ClientIP())
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "重置邮件已发送",
+		Data: gin.H{
+			"email":      admin.Email,
+			"expireTime": expireTime,
+		},
+	})
+}
+
+// ResetAdminPassword 使用重置令牌设置新密码，成功后使该管理员的现有会话失效
+func ResetAdminPassword(c *gin.Context) {
+	var req struct {
+		Token       string `json:"token" binding:"required"`
+		NewPassword string `json:"newPassword" binding:"required,min=6,max=50"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("重置密码参数绑定失败: %v", err)
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	tokenHash := hashPasswordResetToken(req.Token)
+	adminID, err := getAdminIDByResetToken(tokenHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Code:    400,
+				Message: "重置令牌无效或已过期",
+			})
+			return
+		}
+		log.Errorf("查询密码重置令牌失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		log.Errorf("密码加密失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	updated, err := resetAdminPasswordByToken(adminID, tokenHash, string(passwordHash))
+	if err != nil {
+		log.Errorf("重置管理员密码失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if !updated {
+		// 令牌已被并发请求使用
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "重置令牌无效或已过期",
+		})
+		return
+	}
+
+	if _, err := middleware.RevokeAllAdminSessions(adminID); err != nil {
+		log.Errorf("清除管理员会话失败: %v", err)
+	}
+	if admin, err := getAdminByID(adminID); err == nil {
+		clearAdminLoginFailures(admin.Username)
+	}
+
+	log.Infof("管理员密码已通过重置令牌修改: ID=%d, IP=%s", adminID, c.ClientIP())
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "密码已重置，请重新登录",
+	})
+}
+
+// ==================== 辅助函数 ====================
+
+// issueAdminPasswordReset 生成重置令牌（只保存哈希）并通过通知通道发送，返回过期时间
+func issueAdminPasswordReset(admin *models.AdminAccount) (time.Time, error) {
+	token, err := generateSecureToken(32)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	expireMinutes := config.AppConfig.Admin.PasswordResetExpireMinutes
+	if expireMinutes <= 0 {
+		expireMinutes = 30
+	}
+	expireTime := time.Now().Add(time.Duration(expireMinutes) * time.Minute)
+
+	if err := saveAdminPasswordResetToken(admin.ID, hashPasswordResetToken(token), expireTime); err != nil {
+		return time.Time{}, err
+	}
+
+	body := fmt.Sprintf("%s，您好：\n\n我们收到了重置管理后台密码的请求。", admin.Username)
+	if resetURL := config.AppConfig.Admin.PasswordResetURL; resetURL != "" {
+		sep := "?"
+		if strings.Contains(resetURL, "?") {
+			sep = "&"
+		}
+		body += fmt.Sprintf("请在 %d 分钟内打开以下链接设置新密码：\n%s%stoken=%s\n", expireMinutes, resetURL, sep, token)
+	} else {
+		body += fmt.Sprintf("请在 %d 分钟内使用以下重置令牌设置新密码：\n%s\n", expireMinutes, token)
+	}
+	body += "\n如果这不是您本人的操作，请忽略此邮件并联系超级管理员。"
+
+	err = notifier.Send(notifier.Message{
+		To:      admin.Email,
+		Subject: "管理后台密码重置",
+		Body:    body,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return expireTime, nil
+}
+
+// hashPasswordResetToken 计算重置令牌哈希，数据库中不保存明文
+func hashPasswordResetToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
+}
+
+// ==================== 数据库操作函数 ====================
+
+// getAdminByAccount 根据用户名或邮箱查询管理员
+func getAdminByAccount(account string) (*models.AdminAccount, error) {
+	admin, err := getAdminByUsername(account)
+	if err != sql.ErrNoRows || !strings.Contains(account, "@") {
+		return admin, err
+	}
+
+	var id uint64
+	if err := db.MySQLDBGameWeb.QueryRow("SELECT id FROM adminAccount WHERE email = ?", account).Scan(&id); err != nil {
+		return nil, err
+	}
+	return getAdminByID(id)
+}
+
+// saveAdminPasswordResetToken 保存重置令牌哈希和过期时间（覆盖之前未使用的令牌）
+func saveAdminPasswordResetToken(adminID uint64, tokenHash string, expireTime time.Time) error {
+	query := "UPDATE adminAccount SET passwordResetToken = ?, tokenExpireTime = ? WHERE id = ?"
+	_, err := db.MySQLDBGameWeb.Exec(query, tokenHash, expireTime, adminID)
+	return err
+}
+
+// getAdminIDByResetToken 根据未过期的重置令牌哈希查询管理员ID
+func getAdminIDByResetToken(tokenHash string) (uint64, error) {
+	var id uint64
+	query := `
+		SELECT id FROM adminAccount
+		WHERE passwordResetToken = ? AND tokenExpireTime > ? AND status = 1
+	`
+	err := db.MySQLDBGameWeb.QueryRow(query, tokenHash, time.Now()).Scan(&id)
+	return id, err
+}
+
+// resetAdminPasswordByToken 修改密码并清除重置令牌，令牌已被使用时返回false
+func resetAdminPasswordByToken(adminID uint64, tokenHash, passwordHash string) (bool, error) {
+	query := `
+		UPDATE adminAccount
+		SET passwordHash = ?, passwordResetToken = NULL, tokenExpireTime = NULL
+		WHERE id = ? AND passwordResetToken = ?
+	`
+	result, err := db.MySQLDBGameWeb.Exec(query, passwordHash, adminID, tokenHash)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
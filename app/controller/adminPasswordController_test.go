@@ -0,0 +1,176 @@
+package controller
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"gameWeb/config"
+	"gameWeb/db"
+	"gameWeb/log"
+	"gameWeb/middleware"
+	"gameWeb/notifier"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 密码重置流程需要真实的 gameWeb 库（已执行 sql/adminAccount.sql）和 Redis，
+// 通过环境变量指定，未设置时跳过：
+//
+//	GAMEWEB_TEST_MYSQL_DSN=root:password@tcp(localhost:3306)/gameWeb?parseTime=true
+//	GAMEWEB_TEST_REDIS_ADDR=localhost:6379
+func setupPasswordResetTest(t *testing.T) {
+	t.Helper()
+	dsn := os.Getenv("GAMEWEB_TEST_MYSQL_DSN")
+	redisAddr := os.Getenv("GAMEWEB_TEST_REDIS_ADDR")
+	if dsn == "" || redisAddr == "" {
+		t.Skip("未设置 GAMEWEB_TEST_MYSQL_DSN 或 GAMEWEB_TEST_REDIS_ADDR，跳过密码重置流程测试")
+	}
+
+	log.SugaredLogger = zap.NewNop().Sugar()
+	gin.SetMode(gin.TestMode)
+
+	conn, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("打开MySQL连接失败: %v", err)
+	}
+	if err := conn.Ping(); err != nil {
+		t.Fatalf("连接MySQL失败: %v", err)
+	}
+	db.MySQLDBGameWeb = conn
+
+	db.RedisClient = redis.NewClient(&redis.Options{Addr: redisAddr})
+	if err := db.RedisClient.Ping(db.RedisClient.Context()).Err(); err != nil {
+		t.Fatalf("连接Redis失败: %v", err)
+	}
+
+	config.AppConfig.Admin.SessionTimeout = 1
+	config.AppConfig.Admin.PasswordResetExpireMinutes = 30
+	config.AppConfig.Admin.PasswordResetURL = "https://admin.example.com/reset-password"
+
+	t.Cleanup(func() {
+		conn.Close()
+		db.RedisClient.Close()
+	})
+}
+
+// callAdminHandler 以 JSON 请求体调用处理函数，返回状态码和响应
+func callAdminHandler(t *testing.T, handler gin.HandlerFunc, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	payload, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(payload))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.RemoteAddr = "192.0.2.10:40000"
+	handler(c)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v, body=%s", err, w.Body.String())
+	}
+	return w.Code, resp
+}
+
+func TestAdminPasswordResetFlow(t *testing.T) {
+	setupPasswordResetTest(t)
+
+	suffix := time.Now().UnixNano()
+	username := fmt.Sprintf("reset_test_%d", suffix)
+	email := username + "@example.com"
+	result, err := db.MySQLDBGameWeb.Exec(
+		"INSERT INTO adminAccount (username, passwordHash, email, realName, status) VALUES (?, ?, ?, ?, 1)",
+		username, "unused", email, "重置测试")
+	if err != nil {
+		t.Fatalf("创建测试管理员失败: %v", err)
+	}
+	id, _ := result.LastInsertId()
+	adminID := uint64(id)
+	t.Cleanup(func() {
+		db.MySQLDBGameWeb.Exec("DELETE FROM adminAccount WHERE id = ?", adminID)
+		db.DelRedis(adminPasswordForgotPrefix + "192.0.2.10")
+		db.DelRedis(loginLockKey(adminLoginFailPrefix, loginLockTypeUsername, username))
+	})
+
+	sessionID := "reset-test-session"
+	if err := middleware.CreateAdminSession(adminID, sessionID, "192.0.2.10", "go-test"); err != nil {
+		t.Fatalf("创建管理员会话失败: %v", err)
+	}
+	sessionKey := fmt.Sprintf("admin_session:%d:%s", adminID, sessionID)
+
+	mem := &notifier.MemoryNotifier{}
+	notifier.SetNotifier(mem)
+	t.Cleanup(func() { notifier.SetNotifier(&notifier.LogNotifier{}) })
+
+	// 1. 申请重置，令牌通过通知通道发出
+	if code, resp := callAdminHandler(t, ForgotAdminPassword, gin.H{"account": email}); code != http.StatusOK {
+		t.Fatalf("申请重置: 状态码 %d, 响应 %v", code, resp)
+	}
+	messages := mem.Messages()
+	if len(messages) != 1 || messages[0].To != email {
+		t.Fatalf("应向 %s 发送一封邮件, 实际: %+v", email, messages)
+	}
+	token := extractResetToken(t, messages[0].Body)
+
+	var storedHash string
+	if err := db.MySQLDBGameWeb.QueryRow("SELECT passwordResetToken FROM adminAccount WHERE id = ?", adminID).Scan(&storedHash); err != nil {
+		t.Fatalf("查询重置令牌失败: %v", err)
+	}
+	if storedHash == token || storedHash != hashPasswordResetToken(token) {
+		t.Fatalf("数据库中应只保存令牌哈希")
+	}
+
+	// 2. 使用令牌设置新密码，现有会话失效
+	if code, resp := callAdminHandler(t, ResetAdminPassword, gin.H{"token": token, "newPassword": "newPassword123"}); code != http.StatusOK {
+		t.Fatalf("重置密码: 状态码 %d, 响应 %v", code, resp)
+	}
+	exists, err := db.ExistsRedis(sessionKey)
+	if err != nil {
+		t.Fatalf("查询会话失败: %v", err)
+	}
+	if exists {
+		t.Fatalf("重置密码后会话 %s 应已失效", sessionKey)
+	}
+
+	admin, err := getAdminByID(adminID)
+	if err != nil {
+		t.Fatalf("查询管理员失败: %v", err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte("newPassword123")) != nil {
+		t.Fatalf("新密码未生效")
+	}
+
+	// 3. 令牌只能使用一次
+	if code, _ := callAdminHandler(t, ResetAdminPassword, gin.H{"token": token, "newPassword": "anotherPassword"}); code != http.StatusBadRequest {
+		t.Fatalf("重复使用令牌: 状态码 %d, 期望 400", code)
+	}
+}
+
+// extractResetToken 从重置邮件的链接中取出令牌
+func extractResetToken(t *testing.T, body string) string {
+	t.Helper()
+	for _, line := range strings.Split(body, "\n") {
+		if !strings.HasPrefix(line, config.AppConfig.Admin.PasswordResetURL) {
+			continue
+		}
+		u, err := url.Parse(strings.TrimSpace(line))
+		if err != nil {
+			t.Fatalf("解析重置链接失败: %v", err)
+		}
+		if token := u.Query().Get("token"); token != "" {
+			return token
+		}
+	}
+	t.Fatalf("邮件中没有重置链接: %s", body)
+	return ""
+}
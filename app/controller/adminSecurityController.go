@@ -0,0 +1,352 @@
+package controller
+
+import (
+	"fmt"
+	"gameWeb/config"
+	"gameWeb/db"
+	"gameWeb/log"
+	"gameWeb/middleware"
+	"gameWeb/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 登录锁定相关的Redis键前缀
+const (
+	adminLoginFailPrefix = "admin_login_fail:" // 失败计数: admin_login_fail:{type}:{target}
+	adminLoginLockPrefix = "admin_login_lock:" // 锁定标记: admin_login_lock:{type}:{target}
+
+	loginLockTypeUsername = "username"
+	loginLockTypeIP       = "ip"
+)
+
+// ==================== 登录锁定 ====================
+
+// adminLockoutDuration 获取配置的锁定时长
+func adminLockoutDuration() time.Duration {
+	minutes := config.AppConfig.Admin.LockoutDuration
+	if minutes <= 0 {
+		minutes = 30
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// loginLockThreshold 获取指定类型的最大失败次数
+func loginLockThreshold(lockType string) int64 {
+	if lockType == loginLockTypeIP {
+		if config.AppConfig.Admin.MaxIPLoginAttempts > 0 {
+			return int64(config.AppConfig.Admin.MaxIPLoginAttempts)
+		}
+		return 20
+	}
+	if config.AppConfig.Admin.MaxLoginAttempts > 0 {
+		return int64(config.AppConfig.Admin.MaxLoginAttempts)
+	}
+	return 5
+}
+
+// loginLockKey 生成失败计数或锁定标记的Redis键
+// 用户名忽略大小写和首尾空格，避免换一种写法绕过锁定
+func loginLockKey(prefix, lockType, target string) string {
+	if lockType == loginLockTypeUsername {
+		target = strings.ToLower(strings.TrimSpace(target))
+	}
+	return prefix + lockType + ":" + target
+}
+
+// getLoginLockRemaining 查询用户名或IP的剩余锁定时间，未锁定时返回0
+func getLoginLockRemaining(lockType, target string) (time.Duration, error) {
+	ttl, err := db.TTLRedis(loginLockKey(adminLoginLockPrefix, lockType, target))
+	if err != nil {
+		return 0, err
+	}
+	// -2表示键不存在，-1表示没有过期时间（不应出现）
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// checkAdminLoginLocked 检查用户名和IP是否处于锁定状态，返回最长的剩余锁定时间
+func checkAdminLoginLocked(username, ip string) (time.Duration, error) {
+	userRemaining, err := getLoginLockRemaining(loginLockTypeUsername, username)
+	if err != nil {
+		return 0, err
+	}
+	ipRemaining, err := getLoginLockRemaining(loginLockTypeIP, ip)
+	if err != nil {
+		return 0, err
+	}
+	if ipRemaining > userRemaining {
+		return ipRemaining, nil
+	}
+	return userRemaining, nil
+}
+
+// incrLoginFailure 增加失败计数，达到阈值时加锁，返回是否触发锁定
+func incrLoginFailure(lockType, target string) (bool, error) {
+	failKey := loginLockKey(adminLoginFailPrefix, lockType, target)
+	count, err := db.IncrRedis(failKey)
+	if err != nil {
+		return false, err
+	}
+	// 第一次失败时设置计数窗口，窗口长度与锁定时长一致
+	if count == 1 {
+		if err := db.ExpireRedis(failKey, adminLockoutDuration()); err != nil {
+			return false, err
+		}
+	}
+
+	if count < loginLockThreshold(lockType) {
+		return false, nil
+	}
+
+	lockKey := loginLockKey(adminLoginLockPrefix, lockType, target)
+	if err := db.SetRedisWithExpire(lockKey, time.Now().Unix(), adminLockoutDuration()); err != nil {
+		return false, err
+	}
+	if err := db.DelRedis(failKey); err != nil {
+		log.Errorf("清除登录失败计数失败: %v", err)
+	}
+	log.Warnf("管理员登录已锁定: 类型=%s, 目标=%s, 时长=%v", lockType, target, adminLockoutDuration())
+	return true, nil
+}
+
+// recordAdminLoginFailure 记录一次登录失败，返回触发锁定后的剩余锁定时间（未锁定为0）
+func recordAdminLoginFailure(username, ip string) time.Duration {
+	userLocked, err := incrLoginFailure(loginLockTypeUsername, username)
+	if err != nil {
+		log.Errorf("记录用户名登录失败次数失败: %v", err)
+	}
+	ipLocked, err := incrLoginFailure(loginLockTypeIP, ip)
+	if err != nil {
+		log.Errorf("记录IP登录失败次数失败: %v", err)
+	}
+	if userLocked || ipLocked {
+		return adminLockoutDuration()
+	}
+	return 0
+}
+
+// clearAdminLoginFailures 登录成功后清除用户名的失败计数
+// IP计数不清除，避免攻击者用一个有效账户重置对其他账户的爆破计数
+func clearAdminLoginFailures(username string) {
+	if err := db.DelRedis(loginLockKey(adminLoginFailPrefix, loginLockTypeUsername, username)); err != nil {
+		log.Errorf("清除登录失败计数失败: %v", err)
+	}
+}
+
+// respondAdminLoginLocked 返回账户锁定响应
+func respondAdminLoginLocked(c *gin.Context, remaining time.Duration) {
+	seconds := int64(remaining.Seconds())
+	minutes := (seconds + 59) / 60
+	c.JSON(http.StatusTooManyRequests, models.APIResponse{
+		Code:    429,
+		Message: fmt.Sprintf("登录失败次数过多，请在%d分钟后重试", minutes),
+		Data: gin.H{
+			"remainingSeconds": seconds,
+			"lockedUntil":      time.Now().Add(remaining),
+		},
+	})
+}
+
+// GetAdminLoginLocks 获取当前被锁定的用户名和IP列表（仅超级管理员可用）
+func GetAdminLoginLocks(c *gin.Context) {
+	keys, err := db.ScanRedisKeys(adminLoginLockPrefix + "*")
+	if err != nil {
+		log.Errorf("查询登录锁定列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	locks := make([]models.AdminLoginLock, 0, len(keys))
+	for _, key := range keys {
+		parts := strings.SplitN(strings.TrimPrefix(key, adminLoginLockPrefix), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		remaining, err := getLoginLockRemaining(parts[0], parts[1])
+		if err != nil || remaining <= 0 {
+			continue
+		}
+		locks = append(locks, models.AdminLoginLock{
+			Type:             parts[0],
+			Target:           parts[1],
+			RemainingSeconds: int64(remaining.Seconds()),
+			LockedUntil:      time.Now().Add(remaining),
+		})
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "查询成功",
+		Data:    locks,
+	})
+}
+
+// ClearAdminLoginLock 解除用户名或IP的登录锁定（仅超级管理员可用）
+func ClearAdminLoginLock(c *gin.Context) {
+	var req struct {
+		Type   string `json:"type" binding:"required,oneof=username ip"`
+		Target string `json:"target" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("解除登录锁定参数绑定失败: %v", err)
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := db.DelRedis(loginLockKey(adminLoginLockPrefix, req.Type, req.Target)); err != nil {
+		log.Errorf("解除登录锁定失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if err := db.DelRedis(loginLockKey(adminLoginFailPrefix, req.Type, req.Target)); err != nil {
+		log.Errorf("清除登录失败计数失败: %v", err)
+	}
+
+	adminId, _ := c.Get("adminId")
+	log.Infof("管理员解除登录锁定: 类型=%s, 目标=%s, 操作者=%v, IP=%s", req.Type, req.Target, adminId, c.ClientIP())
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "解除成功",
+	})
+}
+
+// ==================== 登录历史 ====================
+
+// recordAdminLoginLog 记录一次管理员登录尝试，写入失败只记录日志不影响登录流程
+func recordAdminLoginLog(adminID *uint64, username, ip, userAgent string, status int8, reason string) {
+	middleware.RecordAdminLoginLog(adminID, username, ip, userAgent, status, reason)
+}
+
+// GetAdminLoginLogs 查询管理员登录历史（仅超级管理员可用）
+func GetAdminLoginLogs(c *gin.Context) {
+	var req models.AdminLoginLogQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Errorf("登录历史参数绑定失败: %v", err)
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 设置默认值
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PageSize < 1 {
+		req.PageSize = 20
+	}
+	if req.PageSize > 100 {
+		req.PageSize = 100
+	}
+
+	// 构建查询条件
+	whereConditions := []string{}
+	args := []interface{}{}
+
+	if req.AdminID > 0 {
+		whereConditions = append(whereConditions, "adminId = ?")
+		args = append(args, req.AdminID)
+	}
+	if req.Username != "" {
+		whereConditions = append(whereConditions, "username = ?")
+		args = append(args, req.Username)
+	}
+	if req.IP != "" {
+		whereConditions = append(whereConditions, "ip = ?")
+		args = append(args, req.IP)
+	}
+	if req.Status != nil {
+		whereConditions = append(whereConditions, "status = ?")
+		args = append(args, *req.Status)
+	}
+	if !req.StartTime.IsZero() {
+		whereConditions = append(whereConditions, "createdTime >= ?")
+		args = append(args, req.StartTime)
+	}
+	if !req.EndTime.IsZero() {
+		whereConditions = append(whereConditions, "createdTime <= ?")
+		args = append(args, req.EndTime)
+	}
+
+	whereClause := ""
+	if len(whereConditions) > 0 {
+		whereClause = "WHERE " + strings.Join(whereConditions, " AND ")
+	}
+
+	total, logs, err := getAdminLoginLogList(whereClause, args, req.Page, req.PageSize)
+	if err != nil {
+		log.Errorf("查询管理员登录历史失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "获取成功",
+		Data: models.PaginationResponse{
+			Total:    total,
+			Page:     req.Page,
+			PageSize: req.PageSize,
+			Data:     logs,
+		},
+	})
+}
+
+// getAdminLoginLogList 分页查询管理员登录历史
+func getAdminLoginLogList(whereClause string, args []interface{}, page, pageSize int) (int64, []models.AdminLoginLog, error) {
+	var total int64
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM adminLoginLog %s", whereClause)
+	if err := db.MySQLDBGameWeb.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return 0, nil, err
+	}
+
+	offset := (page - 1) * pageSize
+	listQuery := fmt.Sprintf(`
+		SELECT id, adminId, username, ip, COALESCE(userAgent, ''), status, COALESCE(reason, ''), createdTime
+		FROM adminLoginLog
+		%s
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?
+	`, whereClause)
+
+	finalArgs := append(args, pageSize, offset)
+	rows, err := db.MySQLDBGameWeb.Query(listQuery, finalArgs...)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	logs := []models.AdminLoginLog{}
+	for rows.Next() {
+		var item models.AdminLoginLog
+		var adminID *uint64
+		if err := rows.Scan(&item.ID, &adminID, &item.Username, &item.IP, &item.UserAgent,
+			&item.Status, &item.Reason, &item.CreatedTime); err != nil {
+			return 0, nil, err
+		}
+		item.AdminID = adminID
+		logs = append(logs, item)
+	}
+
+	return total, logs, nil
+}
@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"gameWeb/log"
+	"gameWeb/middleware"
+	"gameWeb/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMySessions 获取当前管理员的全部登录会话
+func GetMySessions(c *gin.Context) {
+	adminId, _ := c.Get("adminId")
+	respondAdminSessions(c, adminId.(uint64), c.GetString("sessionId"))
+}
+
+// RevokeMySession 注销当前管理员的某个会话（例如在其他设备上的登录）
+func RevokeMySession(c *gin.Context) {
+	adminId, _ := c.Get("adminId")
+	sessionID := c.Param("sessionId")
+
+	revoked, err := middleware.RevokeAdminSession(adminId.(uint64), sessionID)
+	if err != nil {
+		log.Errorf("注销管理员会话失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if !revoked {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Code:    404,
+			Message: "会话不存在或已过期",
+		})
+		return
+	}
+
+	log.Infof("管理员注销会话: ID=%v, 会话=%s, IP=%s", adminId, sessionID, c.ClientIP())
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "会话已注销",
+	})
+}
+
+// GetAdminSessions 获取指定管理员的全部登录会话（仅超级管理员）
+func GetAdminSessions(c *gin.Context) {
+	targetAdminID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的管理员ID格式",
+		})
+		return
+	}
+
+	currentSessionID := ""
+	if adminId, _ := c.Get("adminId"); adminId.(uint64) == targetAdminID {
+		currentSessionID = c.GetString("sessionId")
+	}
+	respondAdminSessions(c, targetAdminID, currentSessionID)
+}
+
+// ForceLogoutAdmin 强制指定管理员下线，注销其全部会话（仅超级管理员）
+func ForceLogoutAdmin(c *gin.Context) {
+	targetAdminID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的管理员ID格式",
+		})
+		return
+	}
+
+	count, err := middleware.RevokeAllAdminSessions(targetAdminID)
+	if err != nil {
+		log.Errorf("强制管理员下线失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	adminId, _ := c.Get("adminId")
+	log.Infof("强制管理员下线: 目标ID=%d, 会话数=%d, 操作者=%v, IP=%s", targetAdminID, count, adminId, c.ClientIP())
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "已强制下线",
+		Data:    gin.H{"revokedSessions": count},
+	})
+}
+
+// ForceRevokeAdminSession 注销指定管理员的某个会话（仅超级管理员）
+func ForceRevokeAdminSession(c *gin.Context) {
+	targetAdminID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的管理员ID格式",
+		})
+		return
+	}
+	sessionID := c.Param("sessionId")
+
+	revoked, err := middleware.RevokeAdminSession(targetAdminID, sessionID)
+	if err != nil {
+		log.Errorf("注销管理员会话失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if !revoked {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Code:    404,
+			Message: "会话不存在或已过期",
+		})
+		return
+	}
+
+	adminId, _ := c.Get("adminId")
+	log.Infof("注销管理员会话: 目标ID=%d, 会话=%s, 操作者=%v, IP=%s", targetAdminID, sessionID, adminId, c.ClientIP())
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "会话已注销",
+	})
+}
+
+// respondAdminSessions 返回管理员会话列表，并标记当前请求所在的会话
+func respondAdminSessions(c *gin.Context, adminID uint64, currentSessionID string) {
+	sessions, err := middleware.ListAdminSessions(adminID)
+	if err != nil {
+		log.Errorf("查询管理员会话失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].SessionID == currentSessionID
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "获取成功",
+		Data:    sessions,
+	})
+}
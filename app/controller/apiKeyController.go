@@ -0,0 +1,356 @@
+package controller
+
+import (
+	"database/sql"
+	"encoding/json"
+	"gameWeb/db"
+	"gameWeb/log"
+	"gameWeb/middleware"
+	"gameWeb/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAPIKeyList 获取API密钥列表：超级管理员可查看全部，其他管理员只能查看自己创建的
+func GetAPIKeyList(c *gin.Context) {
+	var ownerID uint64
+	if isSuperAdmin, _ := c.Get("isSuperAdmin"); isSuperAdmin != true {
+		adminId, _ := c.Get("adminId")
+		ownerID = adminId.(uint64)
+	}
+
+	keys, err := getAPIKeyList(ownerID)
+	if err != nil {
+		log.Errorf("查询API密钥列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "获取成功",
+		Data:    keys,
+	})
+}
+
+// CreateAPIKey 创建API密钥，密钥权限不能超出创建者自身的权限，明文密钥只在响应中返回一次
+func CreateAPIKey(c *gin.Context) {
+	var req struct {
+		Name        string   `json:"name" binding:"required,min=1,max=50"`
+		Permissions []string `json:"permissions" binding:"required,min=1"`
+		ExpireDays  int      `json:"expireDays" binding:"min=0,max=3650"` // 0表示永不过期
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("创建API密钥参数绑定失败: %v", err)
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if invalid, err := findInvalidPermissions(req.Permissions); err != nil {
+		log.Errorf("校验权限编码失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	} else if len(invalid) > 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的权限编码: " + strings.Join(invalid, ", "),
+		})
+		return
+	}
+
+	// 不能授予自己没有的权限
+	for _, perm := range req.Permissions {
+		if !middleware.HasPermission(c, perm) {
+			c.JSON(http.StatusForbidden, models.APIResponse{
+				Code:    403,
+				Message: "不能授予自己没有的权限: " + perm,
+			})
+			return
+		}
+	}
+
+	key, prefix, hash, err := middleware.GenerateAPIKey()
+	if err != nil {
+		log.Errorf("生成API密钥失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	var expireTime *time.Time
+	if req.ExpireDays > 0 {
+		t := time.Now().AddDate(0, 0, req.ExpireDays)
+		expireTime = &t
+	}
+
+	adminId, _ := c.Get("adminId")
+	keyID, err := createAPIKey(req.Name, prefix, hash, req.Permissions, expireTime, adminId.(uint64))
+	if err != nil {
+		log.Errorf("创建API密钥失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "创建失败",
+		})
+		return
+	}
+
+	apiKey, err := getAPIKeyByID(keyID)
+	if err != nil {
+		log.Errorf("查询API密钥失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	middleware.SetAuditTarget(c, "apikey", keyID)
+	middleware.SetAuditAfter(c, apiKey)
+	log.Infof("API密钥创建成功: ID=%d, 名称=%s, 权限=%v, 创建者=%v", keyID, req.Name, req.Permissions, adminId)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "创建成功，请妥善保存密钥，之后将无法再次查看",
+		Data: models.AdminAPIKeyCreateResponse{
+			Key:    key,
+			APIKey: apiKey,
+		},
+	})
+}
+
+// RotateAPIKey 轮换API密钥：生成新密钥，旧密钥立即失效，权限和过期时间保持不变
+func RotateAPIKey(c *gin.Context) {
+	apiKey, ok := loadManageableAPIKey(c)
+	if !ok {
+		return
+	}
+	if apiKey.Status != 1 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "API密钥已吊销，无法轮换",
+		})
+		return
+	}
+
+	key, prefix, hash, err := middleware.GenerateAPIKey()
+	if err != nil {
+		log.Errorf("生成API密钥失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if err := rotateAPIKey(apiKey.ID, prefix, hash); err != nil {
+		log.Errorf("轮换API密钥失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "轮换失败",
+		})
+		return
+	}
+
+	rotated, err := getAPIKeyByID(apiKey.ID)
+	if err != nil {
+		log.Errorf("查询API密钥失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	middleware.SetAuditTarget(c, "apikey", apiKey.ID)
+	middleware.SetAuditBefore(c, apiKey)
+	middleware.SetAuditAfter(c, rotated)
+	adminId, _ := c.Get("adminId")
+	log.Infof("API密钥轮换成功: ID=%d, 名称=%s, 操作者=%v", apiKey.ID, apiKey.Name, adminId)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "轮换成功，旧密钥已失效，请妥善保存新密钥",
+		Data: models.AdminAPIKeyCreateResponse{
+			Key:    key,
+			APIKey: rotated,
+		},
+	})
+}
+
+// RevokeAPIKey 吊销API密钥
+func RevokeAPIKey(c *gin.Context) {
+	apiKey, ok := loadManageableAPIKey(c)
+	if !ok {
+		return
+	}
+
+	if err := revokeAPIKey(apiKey.ID); err != nil {
+		log.Errorf("吊销API密钥失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "吊销失败",
+		})
+		return
+	}
+
+	middleware.SetAuditTarget(c, "apikey", apiKey.ID)
+	middleware.SetAuditBefore(c, apiKey)
+	adminId, _ := c.Get("adminId")
+	log.Infof("API密钥已吊销: ID=%d, 名称=%s, 操作者=%v", apiKey.ID, apiKey.Name, adminId)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "吊销成功",
+	})
+}
+
+// loadManageableAPIKey 读取路径中的API密钥，并校验当前管理员是否可以管理它（创建者或超级管理员）
+func loadManageableAPIKey(c *gin.Context) (*models.AdminAPIKey, bool) {
+	keyID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的密钥ID",
+		})
+		return nil, false
+	}
+
+	apiKey, err := getAPIKeyByID(keyID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Code:    404,
+				Message: "API密钥不存在",
+			})
+			return nil, false
+		}
+		log.Errorf("查询API密钥失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return nil, false
+	}
+
+	adminId, _ := c.Get("adminId")
+	if isSuperAdmin, _ := c.Get("isSuperAdmin"); isSuperAdmin != true && apiKey.CreatedBy != adminId.(uint64) {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Code:    403,
+			Message: "只能管理自己创建的API密钥",
+		})
+		return nil, false
+	}
+	return apiKey, true
+}
+
+// ==================== 数据库操作函数 ====================
+
+const apiKeyColumns = `
+	id, name, keyPrefix, permissions, status, expireTime, lastUsedTime, COALESCE(lastUsedIp, ''),
+	rotatedTime, createdBy, createdTime, updatedTime
+`
+
+// scanAPIKey 扫描一行API密钥数据
+func scanAPIKey(scanner interface{ Scan(...interface{}) error }) (*models.AdminAPIKey, error) {
+	key := &models.AdminAPIKey{}
+	var permsJSON string
+	var expireTime, lastUsedTime, rotatedTime sql.NullTime
+	if err := scanner.Scan(&key.ID, &key.Name, &key.KeyPrefix, &permsJSON, &key.Status,
+		&expireTime, &lastUsedTime, &key.LastUsedIP, &rotatedTime,
+		&key.CreatedBy, &key.CreatedTime, &key.UpdatedTime); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(permsJSON), &key.Permissions); err != nil {
+		return nil, err
+	}
+	if expireTime.Valid {
+		key.ExpireTime = &expireTime.Time
+	}
+	if lastUsedTime.Valid {
+		key.LastUsedTime = &lastUsedTime.Time
+	}
+	if rotatedTime.Valid {
+		key.RotatedTime = &rotatedTime.Time
+	}
+	return key, nil
+}
+
+// getAPIKeyByID 根据ID查询API密钥
+func getAPIKeyByID(keyID uint64) (*models.AdminAPIKey, error) {
+	row := db.MySQLDBGameWeb.QueryRow("SELECT "+apiKeyColumns+" FROM adminApiKey WHERE id = ?", keyID)
+	return scanAPIKey(row)
+}
+
+// getAPIKeyList 查询API密钥列表，ownerID为0时查询全部
+func getAPIKeyList(ownerID uint64) ([]*models.AdminAPIKey, error) {
+	query := "SELECT " + apiKeyColumns + " FROM adminApiKey"
+	args := []interface{}{}
+	if ownerID > 0 {
+		query += " WHERE createdBy = ?"
+		args = append(args, ownerID)
+	}
+	query += " ORDER BY id DESC"
+
+	rows, err := db.MySQLDBGameWeb.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*models.AdminAPIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// createAPIKey 创建API密钥
+func createAPIKey(name, prefix, hash string, permissions []string, expireTime *time.Time, createdBy uint64) (uint64, error) {
+	permsJSON, err := json.Marshal(permissions)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		INSERT INTO adminApiKey (name, keyPrefix, keyHash, permissions, status, expireTime, createdBy)
+		VALUES (?, ?, ?, ?, 1, ?, ?)
+	`
+	result, err := db.MySQLDBGameWeb.Exec(query, name, prefix, hash, string(permsJSON), expireTime, createdBy)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return uint64(id), err
+}
+
+// rotateAPIKey 替换密钥前缀和哈希
+func rotateAPIKey(keyID uint64, prefix, hash string) error {
+	query := "UPDATE adminApiKey SET keyPrefix = ?, keyHash = ?, rotatedTime = ? WHERE id = ?"
+	_, err := db.MySQLDBGameWeb.Exec(query, prefix, hash, time.Now(), keyID)
+	return err
+}
+
+// revokeAPIKey 吊销API密钥
+func revokeAPIKey(keyID uint64) error {
+	_, err := db.MySQLDBGameWeb.Exec("UPDATE adminApiKey SET status = 0 WHERE id = ?", keyID)
+	return err
+}
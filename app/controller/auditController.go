@@ -0,0 +1,269 @@
+package controller
+
+import (
+	"encoding/csv"
+	"fmt"
+	"gameWeb/db"
+	"gameWeb/log"
+	"gameWeb/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// auditExportMaxRows CSV导出的最大行数，超出时需要缩小查询范围
+const auditExportMaxRows = 50000
+
+// GetAdminAuditLogs 分页查询管理员操作审计日志
+func GetAdminAuditLogs(c *gin.Context) {
+	var req models.AdminAuditQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Errorf("审计日志参数绑定失败: %v", err)
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 设置默认值
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PageSize < 1 {
+		req.PageSize = 20
+	}
+	if req.PageSize > 100 {
+		req.PageSize = 100
+	}
+
+	whereClause, args := buildAuditWhereClause(&req)
+
+	var total int64
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM adminAuditLog %s", whereClause)
+	if err := db.MySQLDBGameWeb.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		log.Errorf("统计审计日志失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	offset := (req.Page - 1) * req.PageSize
+	logs, err := getAdminAuditLogList(whereClause, args, req.PageSize, offset)
+	if err != nil {
+		log.Errorf("查询审计日志失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "获取成功",
+		Data: models.PaginationResponse{
+			Total:    total,
+			Page:     req.Page,
+			PageSize: req.PageSize,
+			Data:     logs,
+		},
+	})
+}
+
+// ExportAdminAuditLogs 按查询条件导出审计日志为CSV（忽略分页参数）
+func ExportAdminAuditLogs(c *gin.Context) {
+	var req models.AdminAuditQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Errorf("审计日志导出参数绑定失败: %v", err)
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	whereClause, args := buildAuditWhereClause(&req)
+
+	var total int64
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM adminAuditLog %s", whereClause)
+	if err := db.MySQLDBGameWeb.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		log.Errorf("统计审计日志失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if total > auditExportMaxRows {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: fmt.Sprintf("导出数据超过%d条，请缩小查询范围", auditExportMaxRows),
+		})
+		return
+	}
+
+	logs, err := getAdminAuditLogList(whereClause, args, auditExportMaxRows, 0)
+	if err != nil {
+		log.Errorf("查询审计日志失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("admin_audit_%s.csv", time.Now().Format("20060102150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Status(http.StatusOK)
+
+	// 写入UTF-8 BOM，方便Excel正确识别中文
+	c.Writer.Write([]byte("\xEF\xBB\xBF"))
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{
+		"id", "adminId", "username", "apiKeyId", "method", "route", "path", "targetType", "targetId",
+		"result", "statusCode", "errorMessage", "ip", "userAgent", "durationMs",
+		"beforeData", "afterData", "createdTime",
+	})
+	for _, item := range logs {
+		w.Write([]string{
+			strconv.FormatUint(item.ID, 10),
+			strconv.FormatUint(item.AdminID, 10),
+			csvSafe(item.Username),
+			formatOptionalUint(item.APIKeyID),
+			item.Method,
+			item.Route,
+			csvSafe(item.Path),
+			csvSafe(item.TargetType),
+			csvSafe(item.TargetID),
+			strconv.Itoa(int(item.Result)),
+			strconv.Itoa(item.StatusCode),
+			csvSafe(item.ErrorMessage),
+			item.IP,
+			csvSafe(item.UserAgent),
+			strconv.FormatInt(item.DurationMs, 10),
+			csvSafe(item.BeforeData),
+			csvSafe(item.AfterData),
+			item.CreatedTime.Format("2006-01-02 15:04:05"),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Errorf("导出审计日志失败: %v", err)
+		return
+	}
+
+	adminId, _ := c.Get("adminId")
+	log.Infof("管理员导出审计日志: 管理员ID=%v, 条数=%d, IP=%s", adminId, len(logs), c.ClientIP())
+}
+
+// buildAuditWhereClause 根据查询条件构建WHERE子句
+func buildAuditWhereClause(req *models.AdminAuditQueryRequest) (string, []interface{}) {
+	whereConditions := []string{}
+	args := []interface{}{}
+
+	if req.AdminID > 0 {
+		whereConditions = append(whereConditions, "adminId = ?")
+		args = append(args, req.AdminID)
+	}
+	if req.APIKeyID > 0 {
+		whereConditions = append(whereConditions, "apiKeyId = ?")
+		args = append(args, req.APIKeyID)
+	}
+	if req.Username != "" {
+		whereConditions = append(whereConditions, "username = ?")
+		args = append(args, req.Username)
+	}
+	if req.Method != "" {
+		whereConditions = append(whereConditions, "method = ?")
+		args = append(args, strings.ToUpper(req.Method))
+	}
+	if req.Route != "" {
+		whereConditions = append(whereConditions, "route LIKE ?")
+		args = append(args, "%"+req.Route+"%")
+	}
+	if req.TargetType != "" {
+		whereConditions = append(whereConditions, "targetType = ?")
+		args = append(args, req.TargetType)
+	}
+	if req.TargetID != "" {
+		whereConditions = append(whereConditions, "targetId = ?")
+		args = append(args, req.TargetID)
+	}
+	if req.Result != nil {
+		whereConditions = append(whereConditions, "result = ?")
+		args = append(args, *req.Result)
+	}
+	if req.IP != "" {
+		whereConditions = append(whereConditions, "ip = ?")
+		args = append(args, req.IP)
+	}
+	if !req.StartTime.IsZero() {
+		whereConditions = append(whereConditions, "createdTime >= ?")
+		args = append(args, req.StartTime)
+	}
+	if !req.EndTime.IsZero() {
+		whereConditions = append(whereConditions, "createdTime <= ?")
+		args = append(args, req.EndTime)
+	}
+
+	whereClause := ""
+	if len(whereConditions) > 0 {
+		whereClause = "WHERE " + strings.Join(whereConditions, " AND ")
+	}
+	return whereClause, args
+}
+
+// formatOptionalUint 格式化可为空的ID
+func formatOptionalUint(v *uint64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatUint(*v, 10)
+}
+
+// csvSafe 防止CSV公式注入：以 = + - @ 开头的内容前加单引号
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// getAdminAuditLogList 查询审计日志
+func getAdminAuditLogList(whereClause string, args []interface{}, limit, offset int) ([]models.AdminAuditLog, error) {
+	query := fmt.Sprintf(`
+		SELECT id, adminId, username, apiKeyId, method, route, path, COALESCE(targetType, ''), COALESCE(targetId, ''),
+		       COALESCE(beforeData, ''), COALESCE(afterData, ''), ip, COALESCE(userAgent, ''),
+		       statusCode, result, COALESCE(errorMessage, ''), durationMs, createdTime
+		FROM adminAuditLog
+		%s
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?
+	`, whereClause)
+
+	finalArgs := append(args, limit, offset)
+	rows, err := db.MySQLDBGameWeb.Query(query, finalArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logs := []models.AdminAuditLog{}
+	for rows.Next() {
+		var item models.AdminAuditLog
+		if err := rows.Scan(&item.ID, &item.AdminID, &item.Username, &item.APIKeyID, &item.Method, &item.Route, &item.Path,
+			&item.TargetType, &item.TargetID, &item.BeforeData, &item.AfterData, &item.IP, &item.UserAgent,
+			&item.StatusCode, &item.Result, &item.ErrorMessage, &item.DurationMs, &item.CreatedTime); err != nil {
+			return nil, err
+		}
+		logs = append(logs, item)
+	}
+	return logs, rows.Err()
+}
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"gameWeb/keyring"
+	"gameWeb/log"
+	"gameWeb/middleware"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RefreshClientToken 使用刷新令牌换取新的访问令牌，刷新令牌同时轮换
+func RefreshClientToken(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refreshToken" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request",
+		})
+		return
+	}
+
+	tokens, err := middleware.RefreshClientTokens(req.RefreshToken)
+	if err != nil {
+		switch err {
+		case middleware.ErrRefreshTokenInvalid:
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "Invalid or expired refresh token",
+			})
+		case middleware.ErrRefreshTokenReused:
+			log.Warnf("Refresh token reused, IP: %s", c.ClientIP())
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "Refresh token has been revoked, please login again",
+			})
+		default:
+			log.Errorf("Failed to refresh client tokens: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "Failed to refresh token",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "Success",
+		"data":    tokens,
+	})
+}
+
+// ClientLogout 客户端登出，吊销当前访问令牌并作废其所属的刷新令牌族
+func ClientLogout(c *gin.Context) {
+	userid := c.GetInt64("userid")
+	familyID := c.GetString("fid")
+
+	if expireTime, ok := c.Get("tokenExpireTime"); ok {
+		if err := middleware.RevokeClientToken(c.GetString("jti"), expireTime.(time.Time)); err != nil {
+			log.Errorf("Failed to revoke access token: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "Failed to logout",
+			})
+			return
+		}
+	}
+
+	if familyID != "" {
+		if err := middleware.RevokeClientTokenFamily(userid, familyID); err != nil {
+			log.Errorf("Failed to revoke refresh token family: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "Failed to logout",
+			})
+			return
+		}
+	}
+
+	log.Infof("Client logout: userid=%d, fid=%s, IP: %s", userid, familyID, c.ClientIP())
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "Success",
+	})
+}
+
+// GetJWKS 发布客户端令牌的公钥（JWKS），游戏服据此验证RS256/EdDSA签名的令牌
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, keyring.Client.JWKS())
+}
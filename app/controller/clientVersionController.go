@@ -0,0 +1,328 @@
+package controller
+
+import (
+	"database/sql"
+	"gameWeb/clientversion"
+	"gameWeb/db"
+	"gameWeb/log"
+	"gameWeb/loginprovider"
+	"gameWeb/middleware"
+	"gameWeb/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetClientVersionPolicies 查询全部客户端版本策略（直接查询数据库，不经过缓存）
+func GetClientVersionPolicies(c *gin.Context) {
+	policies, err := clientversion.LoadPolicies()
+	if err != nil {
+		log.Errorf("查询客户端版本策略失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "获取成功",
+		Data:    policies,
+	})
+}
+
+// CreateClientVersionPolicy 创建客户端版本策略，每个登录方式和应用只能有一条
+func CreateClientVersionPolicy(c *gin.Context) {
+	var req models.ClientVersionPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if msg := validateClientVersionPolicy(&req); msg != "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: msg,
+		})
+		return
+	}
+
+	if existing, err := findClientVersionPolicyID(req.Channel, req.Appid); err != nil {
+		log.Errorf("查询客户端版本策略失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	} else if existing != 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "该登录方式和应用的版本策略已存在",
+		})
+		return
+	}
+
+	adminID := c.GetUint64("adminId")
+	id, err := createClientVersionPolicy(&req, adminID, c.GetString("username"))
+	if err != nil {
+		log.Errorf("创建客户端版本策略失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "创建失败",
+		})
+		return
+	}
+	reloadClientVersion()
+	middleware.SetAuditTarget(c, "clientVersionPolicy", id)
+	middleware.SetAuditAfter(c, req)
+	log.Infof("创建客户端版本策略: ID=%d, 登录方式=%s, 应用=%d, 最低版本=%s, 推荐版本=%s, 操作者=%d",
+		id, req.Channel, req.Appid, req.MinVersion, req.RecommendedVersion, adminID)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "创建成功",
+		Data:    gin.H{"id": id},
+	})
+}
+
+// UpdateClientVersionPolicy 修改客户端版本策略
+func UpdateClientVersionPolicy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的策略ID",
+		})
+		return
+	}
+	var req models.ClientVersionPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if msg := validateClientVersionPolicy(&req); msg != "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: msg,
+		})
+		return
+	}
+
+	before, err := getClientVersionPolicyByID(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Code:    404,
+			Message: "版本策略不存在",
+		})
+		return
+	}
+	if err != nil {
+		log.Errorf("查询客户端版本策略失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if existing, err := findClientVersionPolicyID(req.Channel, req.Appid); err != nil {
+		log.Errorf("查询客户端版本策略失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	} else if existing != 0 && existing != id {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "该登录方式和应用的版本策略已存在",
+		})
+		return
+	}
+	middleware.SetAuditTarget(c, "clientVersionPolicy", id)
+	middleware.SetAuditBefore(c, before)
+
+	adminID := c.GetUint64("adminId")
+	if err := updateClientVersionPolicy(id, &req, adminID, c.GetString("username")); err != nil {
+		log.Errorf("修改客户端版本策略失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "修改失败",
+		})
+		return
+	}
+	reloadClientVersion()
+	middleware.SetAuditAfter(c, req)
+	log.Infof("修改客户端版本策略: ID=%d, 最低版本 %s -> %s, 推荐版本 %s -> %s, 操作者=%d",
+		id, before.MinVersion, req.MinVersion, before.RecommendedVersion, req.RecommendedVersion, adminID)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "修改成功",
+	})
+}
+
+// DeleteClientVersionPolicy 删除客户端版本策略，之后该登录方式和应用不再检查版本（appid 为0的通用策略除外）
+func DeleteClientVersionPolicy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的策略ID",
+		})
+		return
+	}
+
+	before, err := getClientVersionPolicyByID(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Code:    404,
+			Message: "版本策略不存在",
+		})
+		return
+	}
+	if err != nil {
+		log.Errorf("查询客户端版本策略失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	middleware.SetAuditTarget(c, "clientVersionPolicy", id)
+	middleware.SetAuditBefore(c, before)
+
+	if _, err := db.MySQLDBGameWeb.Exec("DELETE FROM clientVersionPolicy WHERE id = ?", id); err != nil {
+		log.Errorf("删除客户端版本策略失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "删除失败",
+		})
+		return
+	}
+	reloadClientVersion()
+	log.Infof("删除客户端版本策略: ID=%d, 登录方式=%s, 应用=%d, 操作者=%d", id, before.Channel, before.Appid, c.GetUint64("adminId"))
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "删除成功",
+	})
+}
+
+// validateClientVersionPolicy 校验登录方式和版本号，返回错误信息，为空表示通过
+func validateClientVersionPolicy(req *models.ClientVersionPolicyRequest) string {
+	if _, ok := loginprovider.Get(req.Channel); !ok {
+		return "未知的登录方式: " + req.Channel
+	}
+	if req.MinVersion == "" && req.RecommendedVersion == "" {
+		return "最低支持版本和推荐版本至少填写一个"
+	}
+	for _, v := range []string{req.MinVersion, req.RecommendedVersion} {
+		if _, err := clientversion.Parse(v); v != "" && err != nil {
+			return "版本号格式错误: " + v
+		}
+	}
+	if req.MinVersion != "" && req.RecommendedVersion != "" &&
+		clientversion.Compare(req.RecommendedVersion, req.MinVersion) < 0 {
+		return "推荐版本不能低于最低支持版本"
+	}
+	return ""
+}
+
+// reloadClientVersion 通知所有实例重新加载版本策略，本实例立即重新加载
+func reloadClientVersion() {
+	if err := clientversion.NotifyChanged(); err != nil {
+		log.Warnf("发送客户端版本策略变更通知失败: %v", err)
+	}
+	if err := clientversion.Reload(); err != nil {
+		log.Warnf("重新加载客户端版本策略失败: %v", err)
+	}
+}
+
+// checkClientVersion 按请求头中的版本号检查客户端版本
+// 必须更新时返回 426 并返回 false；提示更新时返回检查结果，由调用方放入响应
+func checkClientVersion(c *gin.Context, channel string, appid int) (*clientversion.Result, bool) {
+	version := c.GetHeader(clientversion.HeaderVersion)
+	result := clientversion.Check(channel, appid, version)
+	if result == nil || !result.Force {
+		return result, true
+	}
+
+	log.Warnf("Client version too old: channel=%s, appid=%d, version=%q, minVersion=%s",
+		channel, appid, version, result.Policy.MinVersion)
+	c.JSON(http.StatusUpgradeRequired, gin.H{
+		"code":    426,
+		"message": "Client update required",
+		"data":    gin.H{"update": clientUpdateInfo(result)},
+	})
+	return result, false
+}
+
+// clientUpdateInfo 返回给客户端的更新信息
+func clientUpdateInfo(result *clientversion.Result) gin.H {
+	return gin.H{
+		"force":              result.Force,
+		"minVersion":         result.Policy.MinVersion,
+		"recommendedVersion": result.Policy.RecommendedVersion,
+		"updateUrl":          result.Policy.UpdateURL,
+		"releaseNotes":       result.Policy.ReleaseNotes,
+	}
+}
+
+// clientAppid 读取请求头中的应用ID，没有或格式错误时为0（只匹配通用策略）
+func clientAppid(c *gin.Context) int {
+	appid, _ := strconv.Atoi(c.GetHeader(clientversion.HeaderAppid))
+	return appid
+}
+
+// 数据库操作函数
+
+// getClientVersionPolicyByID 查询客户端版本策略
+func getClientVersionPolicyByID(id uint64) (models.ClientVersionPolicy, error) {
+	var p models.ClientVersionPolicy
+	err := db.MySQLDBGameWeb.QueryRow(`
+		SELECT id, channel, appid, COALESCE(minVersion, ''), COALESCE(recommendedVersion, ''),
+			COALESCE(updateUrl, ''), COALESCE(releaseNotes, ''), updatedBy, updatedByName, createdTime, updatedTime
+		FROM clientVersionPolicy WHERE id = ?`, id).Scan(&p.ID, &p.Channel, &p.Appid, &p.MinVersion, &p.RecommendedVersion,
+		&p.UpdateURL, &p.ReleaseNotes, &p.UpdatedBy, &p.UpdatedByName, &p.CreatedTime, &p.UpdatedTime)
+	return p, err
+}
+
+// findClientVersionPolicyID 查询登录方式和应用对应的策略ID，不存在时为0
+func findClientVersionPolicyID(channel string, appid int) (uint64, error) {
+	var id uint64
+	err := db.MySQLDBGameWeb.QueryRow("SELECT id FROM clientVersionPolicy WHERE channel = ? AND appid = ?", channel, appid).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// createClientVersionPolicy 创建客户端版本策略
+func createClientVersionPolicy(req *models.ClientVersionPolicyRequest, adminID uint64, adminName string) (int64, error) {
+	result, err := db.MySQLDBGameWeb.Exec(`
+		INSERT INTO clientVersionPolicy (channel, appid, minVersion, recommendedVersion, updateUrl, releaseNotes, updatedBy, updatedByName)
+		VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), ?, ?)`,
+		req.Channel, req.Appid, req.MinVersion, req.RecommendedVersion, req.UpdateURL, req.ReleaseNotes, adminID, adminName)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// updateClientVersionPolicy 修改客户端版本策略
+func updateClientVersionPolicy(id uint64, req *models.ClientVersionPolicyRequest, adminID uint64, adminName string) error {
+	_, err := db.MySQLDBGameWeb.Exec(`
+		UPDATE clientVersionPolicy
+		SET channel = ?, appid = ?, minVersion = NULLIF(?, ''), recommendedVersion = NULLIF(?, ''),
+			updateUrl = NULLIF(?, ''), releaseNotes = NULLIF(?, ''), updatedBy = ?, updatedByName = ?
+		WHERE id = ?`,
+		req.Channel, req.Appid, req.MinVersion, req.RecommendedVersion, req.UpdateURL, req.ReleaseNotes, adminID, adminName, id)
+	return err
+}
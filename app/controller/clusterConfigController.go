@@ -0,0 +1,453 @@
+package controller
+
+import (
+	"database/sql"
+	"encoding/json"
+	"gameWeb/cluster"
+	"gameWeb/db"
+	"gameWeb/log"
+	"gameWeb/middleware"
+	"gameWeb/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 集群配置历史来源
+const (
+	clusterHistoryImport   = "import"
+	clusterHistoryPublish  = "publish"
+	clusterHistoryRollback = "rollback"
+)
+
+// GetClusterConfig 查看 Redis 中当前的集群配置（不经过缓存），配置格式错误时同时返回问题列表
+func GetClusterConfig(c *gin.Context) {
+	raw, err := cluster.ReadRaw()
+	if err == cluster.ErrNotFound {
+		c.JSON(http.StatusOK, models.APIResponse{
+			Code:    200,
+			Message: "集群配置不存在",
+			Data:    gin.H{"ver": 0, "config": nil, "valid": false, "errors": []string{"集群配置不存在"}},
+		})
+		return
+	}
+	if err != nil {
+		log.Errorf("读取集群配置失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	problems := checkClusterConfig([]byte(raw))
+	var config interface{} = raw
+	if json.Valid([]byte(raw)) {
+		config = json.RawMessage(raw)
+	}
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "获取成功",
+		Data: gin.H{
+			"ver":    cluster.RawVersion(raw),
+			"config": config,
+			"valid":  len(problems) == 0,
+			"errors": problems,
+		},
+	})
+}
+
+// ValidateClusterConfig 按集群配置结构校验提交的配置
+func ValidateClusterConfig(c *gin.Context) {
+	var req models.ClusterConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	problems := checkClusterConfig(req.Config)
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "校验完成",
+		Data:    gin.H{"valid": len(problems) == 0, "errors": problems},
+	})
+}
+
+// DiffClusterConfig 对比提交的配置与当前配置
+func DiffClusterConfig(c *gin.Context) {
+	var req models.ClusterConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+	next, err := cluster.Parse(req.Config)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "集群配置格式错误: " + err.Error(),
+		})
+		return
+	}
+
+	current, ver, ok := currentClusterConfig(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "获取成功",
+		Data:    gin.H{"baseVer": ver, "changes": cluster.Diff(current, next)},
+	})
+}
+
+// PublishClusterConfig 校验并发布集群配置，版本号自动加一并写入发布历史
+func PublishClusterConfig(c *gin.Context) {
+	var req models.ClusterConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if req.BaseVer == nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "缺少编辑时的版本号 baseVer",
+		})
+		return
+	}
+	if problems := checkClusterConfig(req.Config); len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "集群配置校验失败",
+			Data:    gin.H{"errors": problems},
+		})
+		return
+	}
+	next, _ := cluster.Parse(req.Config)
+
+	publishClusterConfig(c, next, *req.BaseVer, clusterHistoryPublish, nil, req.Comment)
+}
+
+// RollbackClusterConfig 把历史版本的内容作为新版本发布
+func RollbackClusterConfig(c *gin.Context) {
+	ver, err := strconv.Atoi(c.Param("ver"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的版本号",
+		})
+		return
+	}
+	var req models.ClusterConfigRollbackRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Code:    400,
+				Message: "参数错误: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	content, err := getClusterConfigHistoryContent(ver)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Code:    404,
+			Message: "历史版本不存在",
+		})
+		return
+	}
+	if err != nil {
+		log.Errorf("查询集群配置历史失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	// 导入的手工配置可能本身就有问题，回滚前同样校验
+	if problems := checkClusterConfig([]byte(content)); len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "该历史版本校验失败，不能回滚",
+			Data:    gin.H{"errors": problems},
+		})
+		return
+	}
+	target, _ := cluster.Parse([]byte(content))
+
+	baseVer := 0
+	if raw, err := cluster.ReadRaw(); err == nil {
+		baseVer = cluster.RawVersion(raw)
+	} else if err != cluster.ErrNotFound {
+		log.Errorf("读取集群配置失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	publishClusterConfig(c, target, baseVer, clusterHistoryRollback, &ver, req.Comment)
+}
+
+// GetClusterConfigHistory 分页查询集群配置发布历史
+func GetClusterConfigHistory(c *gin.Context) {
+	var req models.ClusterConfigHistoryQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	var total int64
+	if err := db.MySQLDBGameWeb.QueryRow("SELECT COUNT(*) FROM clusterConfigHistory").Scan(&total); err != nil {
+		log.Errorf("统计集群配置历史失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	list, err := getClusterConfigHistoryList(req.PageSize, (req.Page-1)*req.PageSize)
+	if err != nil {
+		log.Errorf("查询集群配置历史失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "获取成功",
+		Data: models.PaginationResponse{
+			Total:    total,
+			Page:     req.Page,
+			PageSize: req.PageSize,
+			Data:     list,
+		},
+	})
+}
+
+// GetClusterConfigHistoryDetail 查看历史版本内容及其与当前配置的差异
+func GetClusterConfigHistoryDetail(c *gin.Context) {
+	ver, err := strconv.Atoi(c.Param("ver"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的版本号",
+		})
+		return
+	}
+
+	content, err := getClusterConfigHistoryContent(ver)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Code:    404,
+			Message: "历史版本不存在",
+		})
+		return
+	}
+	if err != nil {
+		log.Errorf("查询集群配置历史失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	data := gin.H{"ver": ver, "config": content}
+	if json.Valid([]byte(content)) {
+		data["config"] = json.RawMessage(content)
+	}
+	// 差异表示回滚到该版本会带来的变化
+	if target, err := cluster.Parse([]byte(content)); err == nil {
+		current, _, ok := currentClusterConfig(c)
+		if !ok {
+			return
+		}
+		data["changes"] = cluster.Diff(current, target)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "获取成功",
+		Data:    data,
+	})
+}
+
+// publishClusterConfig 写入历史并发布：历史记录在事务中先写入，发布成功后才提交，失败时回滚
+func publishClusterConfig(c *gin.Context, cfg *cluster.Config, baseVer int, action string, rollbackFrom *int, comment string) {
+	newVer := baseVer + 1
+	raw, err := cluster.Encode(cfg, newVer)
+	if err != nil {
+		log.Errorf("序列化集群配置失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	middleware.SetAuditTarget(c, "clusterConfig", newVer)
+	current, err := cluster.ReadRaw()
+	if err != nil && err != cluster.ErrNotFound {
+		log.Errorf("读取集群配置失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	adminID := c.GetUint64("adminId")
+	adminName := c.GetString("username")
+	tx, err := db.MySQLDBGameWeb.Begin()
+	if err != nil {
+		log.Errorf("开启事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	// 手工写入、没有历史记录的当前配置先记录下来，之后可以回滚到它
+	if current != "" {
+		if _, err := tx.Exec(`
+			INSERT IGNORE INTO clusterConfigHistory (ver, content, action, comment)
+			VALUES (?, ?, ?, '发布前的手工配置')`,
+			cluster.RawVersion(current), current, clusterHistoryImport); err != nil {
+			log.Errorf("记录当前集群配置失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Code:    500,
+				Message: "系统错误",
+			})
+			return
+		}
+	}
+	_, err = tx.Exec(`
+		INSERT INTO clusterConfigHistory (ver, content, action, rollbackFrom, comment, adminId, adminName)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		newVer, raw, action, rollbackFrom, comment, adminID, adminName)
+	if err != nil {
+		// 版本号已存在说明 Redis 中的配置被手工改回了旧版本号，需要先处理冲突
+		log.Errorf("写入集群配置历史失败: %v", err)
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Code:    409,
+			Message: "版本号 " + strconv.Itoa(newVer) + " 已存在于发布历史，请检查当前配置的 ver",
+		})
+		return
+	}
+
+	if err := cluster.Publish(raw, baseVer); err != nil {
+		if err == cluster.ErrVersionConflict {
+			c.JSON(http.StatusConflict, models.APIResponse{
+				Code:    409,
+				Message: "集群配置已被修改，请刷新后重新编辑",
+			})
+			return
+		}
+		log.Errorf("发布集群配置失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		// 配置已经生效，只是历史没有保存
+		log.Errorf("集群配置已发布但历史保存失败: ver=%d, err=%v", newVer, err)
+	}
+
+	var before *cluster.Config
+	if current != "" {
+		before, _ = cluster.Parse([]byte(current))
+	}
+	changes := cluster.Diff(before, cfg)
+	middleware.SetAuditAfter(c, gin.H{"ver": newVer, "action": action, "rollbackFrom": rollbackFrom, "changes": changes})
+	log.Infof("发布集群配置: ver %d -> %d, 方式=%s, 变更数=%d, 操作者=%d, IP=%s",
+		baseVer, newVer, action, len(changes), adminID, c.ClientIP())
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "发布成功",
+		Data:    gin.H{"ver": newVer, "changes": changes},
+	})
+}
+
+// checkClusterConfig 解析并校验配置，返回全部问题
+func checkClusterConfig(raw []byte) []string {
+	cfg, err := cluster.Parse(raw)
+	if err != nil {
+		return []string{"JSON 格式错误: " + err.Error()}
+	}
+	if problems := cluster.Validate(cfg); len(problems) > 0 {
+		return problems
+	}
+	return []string{}
+}
+
+// currentClusterConfig 读取当前配置用于对比，不存在或无法解析时视为空配置；失败时已写入响应
+func currentClusterConfig(c *gin.Context) (*cluster.Config, int, bool) {
+	raw, err := cluster.ReadRaw()
+	if err == cluster.ErrNotFound {
+		return nil, 0, true
+	}
+	if err != nil {
+		log.Errorf("读取集群配置失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return nil, 0, false
+	}
+	cfg, _ := cluster.Parse([]byte(raw))
+	return cfg, cluster.RawVersion(raw), true
+}
+
+// 数据库操作函数
+
+// getClusterConfigHistoryContent 查询历史版本内容
+func getClusterConfigHistoryContent(ver int) (string, error) {
+	var content string
+	err := db.MySQLDBGameWeb.QueryRow("SELECT content FROM clusterConfigHistory WHERE ver = ?", ver).Scan(&content)
+	return content, err
+}
+
+// getClusterConfigHistoryList 分页查询发布历史（不含内容）
+func getClusterConfigHistoryList(limit, offset int) ([]models.ClusterConfigHistory, error) {
+	rows, err := db.MySQLDBGameWeb.Query(`
+		SELECT id, ver, action, rollbackFrom, COALESCE(comment, ''), adminId, adminName, createdTime
+		FROM clusterConfigHistory ORDER BY ver DESC LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.ClusterConfigHistory{}
+	for rows.Next() {
+		var h models.ClusterConfigHistory
+		if err := rows.Scan(&h.ID, &h.Ver, &h.Action, &h.RollbackFrom, &h.Comment, &h.AdminID, &h.AdminName, &h.CreatedTime); err != nil {
+			return nil, err
+		}
+		list = append(list, h)
+	}
+	return list, rows.Err()
+}
@@ -3,13 +3,18 @@ package controller
 // 在导入部分添加net/url包
 import (
 	"crypto/md5"
-	"encoding/json"
-	"errors"
+	"database/sql"
 	"fmt"
+	"gameWeb/clientversion"
+	"gameWeb/cluster"
 	"gameWeb/config"
 	"gameWeb/db"
+	"gameWeb/grayrelease"
 	"gameWeb/log"
-	"io/ioutil"
+	"gameWeb/loginprovider"
+	"gameWeb/maintenance"
+	"gameWeb/middleware"
+	"gameWeb/wechat"
 	"net/http"
 	"net/url"
 	"strings"
@@ -17,65 +22,67 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// 定义服务节点结构体
-type ServiceNode struct {
-	Addr       string `json:"addr"`
-	Name       string `json:"name"`
-	Cnt        int    `json:"cnt"`
-	ClientAddr string `json:"clientAddr,omitempty"`
-	Hide       bool   `json:"hide,omitempty"`
-}
-
-// 定义ClusterConfig结构体
-type ClusterConfig struct {
-	List struct {
-		Match    []ServiceNode `json:"match"`
-		Robot    []ServiceNode `json:"robot"`
-		Game     []ServiceNode `json:"game"`
-		Login    []ServiceNode `json:"login"`
-		User     []ServiceNode `json:"user"`
-		Gate     []ServiceNode `json:"gate"`
-		Activity []ServiceNode `json:"activity"`
-		Auth     []ServiceNode `json:"auth"`
-	} `json:"list"`
-	Ver int `json:"ver"`
-}
-
 // GetAuthGameList 获取游戏列表
+// 数据来自内存中的集群配置快照，客户端带 If-None-Match 且配置未变化时返回 304
 func GetAuthGameList(c *gin.Context) {
-	// 从Redis获取clusterConfig
-	clusterConfigStr, err := db.GetRedis("clusterConfig")
-	if err != nil {
-		log.Errorf("Failed to get clusterConfig from Redis: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    500,
-			"message": "Failed to get cluster configuration",
-			"error":   err.Error(),
-		})
+	// 版本过低和维护期间不返回服务器地址；需要在 ETag 判断之前，避免客户端用缓存的列表进入
+	// 先检查版本，维护期间发布的新版本可以提前通知玩家更新
+	channel := c.GetString("channelid")
+	update, ok := checkClientVersion(c, channel, clientAppid(c))
+	if !ok {
+		return
+	}
+	// 维护期间放行名单中的用户和IP仍然可以获取服务器列表
+	if w := maintenance.Current(channel); w != nil && !maintenance.Allowed(c.GetInt64("userid"), c.ClientIP()) {
+		respondMaintenance(c, w, channel)
 		return
 	}
 
-	// 如果获取到空值
-	if clusterConfigStr == "" {
+	snap, err := cluster.Current()
+	if err == cluster.ErrNotFound {
 		c.JSON(http.StatusNotFound, gin.H{
 			"code":    404,
 			"message": "Cluster configuration not found",
 		})
 		return
 	}
-
-	// 解析JSON
-	var clusterConfig ClusterConfig
-	if err := json.Unmarshal([]byte(clusterConfigStr), &clusterConfig); err != nil {
-		log.Errorf("Failed to parse clusterConfig: %v", err)
+	if err != nil {
+		log.Errorf("Failed to get clusterConfig: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
-			"message": "Failed to parse cluster configuration",
+			"message": "Failed to get cluster configuration",
 			"error":   err.Error(),
 		})
 		return
 	}
 
+	// 灰度规则决定玩家可见的网关和游戏节点
+	userid := c.GetInt64("userid")
+	routing := grayrelease.Route(snap.Config, userid, channel, c.GetHeader(clientversion.HeaderVersion))
+
+	// 推荐网关由快照和 userid 决定，同一用户的响应只随快照变化，ETag 仍然有效；响应因用户而异，不允许共享缓存
+	// 提示更新时响应中包含更新信息，ETag 加上策略版本，策略修改后客户端缓存失效
+	// 有灰度规则时 ETag 加上规则版本和命中的规则，规则修改或玩家改为命中其他规则后客户端缓存失效
+	etag := strings.TrimSuffix(snap.ETag, `"`)
+	if update != nil {
+		etag += fmt.Sprintf("-u%d.%d", update.Policy.ID, update.Policy.UpdatedTime.Unix())
+	}
+	if ver := grayrelease.Version(); ver != "" {
+		ruleID := uint64(0)
+		if routing.Rule != nil {
+			ruleID = routing.Rule.ID
+		}
+		etag += fmt.Sprintf("-g%s.%d", ver, ruleID)
+	}
+	etag += `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if cluster.MatchETag(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	clusterConfig := snap.Config
+
 	// 将gate和game根据类型分开存储
 	result := make(map[string]map[string]string)
 	result["gate"] = make(map[string]string)
@@ -84,7 +91,7 @@ func GetAuthGameList(c *gin.Context) {
 
 	// 在处理gate和game数据时添加urlencode处理
 	// 处理gate数据
-	for _, gate := range clusterConfig.List.Gate {
+	for _, gate := range routing.Gates {
 		if gate.Hide {
 			continue
 		}
@@ -93,7 +100,7 @@ func GetAuthGameList(c *gin.Context) {
 	}
 
 	// 处理game数据
-	for _, game := range clusterConfig.List.Game {
+	for _, game := range routing.Games {
 		encodedAddr := url.QueryEscape(game.ClientAddr)
 		result["game"][game.Name] = encodedAddr
 	}
@@ -106,22 +113,29 @@ func GetAuthGameList(c *gin.Context) {
 		result["login"][login.Name] = encodedAddr
 	}
 
+	// 推荐网关：按负载和 userid 选择，gateOrder 为可用网关的建议连接顺序，推荐网关连接失败时按顺序尝试
+	data := gin.H{"gate": result["gate"], "game": result["game"], "login": result["login"]}
+	gates := cluster.RecommendGates(routing.Gates, userid, config.AppConfig.Cluster.GateLoadSlack)
+	gateOrder := make([]string, 0, len(gates))
+	for _, gate := range gates {
+		gateOrder = append(gateOrder, gate.Name)
+	}
+	data["gateOrder"] = gateOrder
+	if len(gates) > 0 {
+		data["recommend"] = gin.H{"gate": gates[0].Name, "addr": url.QueryEscape(gates[0].ClientAddr)}
+	}
+	if update != nil {
+		data["update"] = clientUpdateInfo(update)
+	}
+
 	// 返回整理后的数据
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
-		"data": result,
+		"data": data,
 	})
 }
 
-func getWechatInfo(appid int) (config.WechatInfo, error) {
-	for _, info := range config.AppConfig.WechatInfos {
-		if info.ID == appid {
-			return info, nil
-		}
-	}
-	return config.WechatInfo{}, errors.New("未找到对应的微信配置信息")
-}
-
+// ThirdLogin 客户端第三方登录：按 loginType 选择登录方式，换取平台用户标识后写入该登录方式的账号表
 func ThirdLogin(c *gin.Context) {
 	var req struct {
 		Appid     int    `json:"appid" binding:"required"`
@@ -138,101 +152,138 @@ func ThirdLogin(c *gin.Context) {
 		return
 	}
 
-	if req.LoginType == "wechatMiniGame" {
-		code := req.LoginData
-		info, err := getWechatInfo(req.Appid)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"code":    500,
-				"message": "Failed to login",
-			})
-			return
-		}
+	// 登录方式必须已注册，且该应用开启了这种登录方式
+	provider, ok := loginprovider.Get(req.LoginType)
+	if !ok {
+		log.Warnf("Unsupported login type: %q, appid=%d", req.LoginType, req.Appid)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Unsupported login type",
+		})
+		return
+	}
+	app, ok := loginprovider.FindApp(req.Appid, provider.Name())
+	if !ok {
+		log.Warnf("Login type %s not enabled for appid %d", provider.Name(), req.Appid)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Login type not enabled for this app",
+		})
+		return
+	}
 
-		values := url.Values{}
-		values.Add("appid", info.AppID)
-		values.Add("secret", info.Secret)
-		values.Add("js_code", code)
-		values.Add("grant_type", "authorization_code")
-		baseURL := "https://api.weixin.qq.com/sns/jscode2session"
-		fullURL := baseURL + "?" + values.Encode()
-		log.Infof("WeChat API request URL: %s", fullURL)
-		resp, err := http.Get(fullURL)
-		if err != nil {
-			log.Errorf("Failed to make HTTP request: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"code":    500,
-				"message": "Failed to make HTTP request",
+	// 版本过低时不登录；请求头中没有应用ID时使用请求体中的 appid
+	appid := clientAppid(c)
+	if appid == 0 {
+		appid = req.Appid
+	}
+	update, ok := checkClientVersion(c, provider.Name(), appid)
+	if !ok {
+		return
+	}
+
+	identity, err := provider.Login(c.Request.Context(), app, req.LoginData)
+	if err != nil {
+		if err == loginprovider.ErrInvalidLoginData {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Invalid login data",
 			})
 			return
 		}
-
-		defer resp.Body.Close()
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			log.Errorf("Failed to read response body: %v", err)
+		if perr, ok := err.(*loginprovider.PlatformError); ok {
+			log.Errorf("%v", perr)
 			c.JSON(http.StatusInternalServerError, gin.H{
-				"code":    500,
-				"message": "Failed to read response body",
+				"code":    perr.Code,
+				"message": perr.Provider + " API error: " + perr.Message,
 			})
 			return
 		}
+		log.Errorf("Failed to login with %s: %v", provider.Name(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to login",
+		})
+		return
+	}
 
-		var wxresp struct {
-			SessionKey string `json:"session_key"`
-			Unionid    string `json:"unionid"`
-			Errmsg     string `json:"errmsg"`
-			Errcode    int    `json:"errcode"`
-			Openid     string `json:"openid"`
+	// 维护期间只允许放行名单中的用户和IP登录，在写入账号表之前判断，避免更新登录服使用的密码
+	if w := maintenance.Current(provider.Name()); w != nil && !maintenance.IPAllowed(c.ClientIP()) {
+		var userid int64
+		err := db.MySQLDB.QueryRow("SELECT userid FROM `"+provider.Table()+"` WHERE username = ?", identity.OpenID).Scan(&userid)
+		if err != nil && err != sql.ErrNoRows {
+			log.Errorf("Failed to query account userid: %v", err)
 		}
-
-		err = json.Unmarshal(body, &wxresp)
-		if err != nil {
-			log.Errorf("Failed to unmarshal response body: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"code":    500,
-				"message": "Failed to unmarshal response body",
-			})
+		if !maintenance.Allowed(userid, c.ClientIP()) {
+			respondMaintenance(c, w, provider.Name())
 			return
 		}
+	}
 
-		// 检查微信返回的错误码
-		if wxresp.Errcode != 0 {
-			log.Errorf("WeChat API error, code: %d, message: %s", wxresp.Errcode, wxresp.Errmsg)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"code":    wxresp.Errcode,
-				"message": "WeChat API error: " + wxresp.Errmsg,
-			})
-			return
-		}
+	tokenStr, password := thirdAccountPassword(identity.SessionKey)
+
+	// 将数据写入登录方式对应的账号表（表名来自注册的登录方式，不使用客户端输入）
+	// 使用UPSERT操作：如果username存在则更新，否则插入新记录
+	table := provider.Table()
+	_, err = db.MySQLDB.Exec(
+		"INSERT INTO `"+table+"` (username, password, type) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE password = ?",
+		identity.OpenID, password, provider.Name(), password)
+	if err != nil {
+		log.Errorf("Failed to insert/update account data: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to save account data",
+		})
+		return
+	}
 
-		log.Infof("WeChat response parsed: openid=%s, session_key=%s, unionid=%s",
-			wxresp.Openid, wxresp.SessionKey, wxresp.Unionid)
+	// 缓存微信会话密钥，客户端之后提交加密的用户信息时用来解密
+	if provider.Name() == loginprovider.WechatMiniGame && identity.SessionKey != "" {
+		if err := wechat.SaveSessionKey(app.AppID, identity.OpenID, identity.SessionKey); err != nil {
+			log.Errorf("Failed to save wechat session key: %v", err)
+		}
+	}
 
-		token := md5.Sum([]byte(wxresp.SessionKey))
-		// 将 [16]byte 转换为十六进制字符串
-		tokenStr := fmt.Sprintf("%x", token)
-		token2 := md5.Sum([]byte(tokenStr))
-		tokenStr2 := fmt.Sprintf("%x", token2)
+	data := map[string]interface{}{"openid": identity.OpenID, "token": tokenStr}
+	if update != nil {
+		data["update"] = clientUpdateInfo(update)
+	}
 
-		// 将数据写入account表
-		// 使用UPSERT操作：如果username存在则更新，否则插入新记录
-		_, err = db.MySQLDB.Exec(
-			"INSERT INTO "+req.LoginType+" (username, password, type) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE password = ?",
-			wxresp.Openid, strings.ToUpper(tokenStr2), req.LoginType, strings.ToUpper(tokenStr2))
+	// 账户已分配userid时签发访问令牌和刷新令牌；新账户要等登录服分配userid后再次登录才会下发
+	var userid int64
+	err = db.MySQLDB.QueryRow("SELECT userid FROM `"+table+"` WHERE username = ?", identity.OpenID).Scan(&userid)
+	if err != nil {
+		log.Errorf("Failed to query account userid: %v", err)
+	} else if userid > 0 {
+		tokens, err := middleware.IssueClientTokens(userid, provider.Name())
 		if err != nil {
-			log.Errorf("Failed to insert/update account data: %v", err)
+			log.Errorf("Failed to issue client tokens: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"code":    500,
-				"message": "Failed to save account data",
+				"message": "Failed to issue tokens",
 			})
 			return
 		}
-
-		c.JSON(http.StatusOK, gin.H{
-			"code":    200,
-			"message": "Success",
-			"data":    map[string]interface{}{"openid": wxresp.Openid, "token": tokenStr},
-		})
+		data["userid"] = userid
+		data["accessToken"] = tokens.AccessToken
+		data["refreshToken"] = tokens.RefreshToken
+		data["expiresIn"] = tokens.ExpiresIn
+		data["refreshExpiresIn"] = tokens.RefreshExpiresIn
 	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "Success",
+		"data":    data,
+	})
+}
+
+// thirdAccountPassword 由平台会话密钥生成客户端 token 和账号表中保存的密码
+// token 为会话密钥的MD5，密码为 token 再做一次MD5后转大写，登录服用 token 校验
+func thirdAccountPassword(sessionKey string) (string, string) {
+	token := md5.Sum([]byte(sessionKey))
+	// 将 [16]byte 转换为十六进制字符串
+	tokenStr := fmt.Sprintf("%x", token)
+	token2 := md5.Sum([]byte(tokenStr))
+	return tokenStr, strings.ToUpper(fmt.Sprintf("%x", token2))
 }
@@ -0,0 +1,340 @@
+package controller
+
+import (
+	"database/sql"
+	"encoding/json"
+	"gameWeb/clientversion"
+	"gameWeb/cluster"
+	"gameWeb/db"
+	"gameWeb/grayrelease"
+	"gameWeb/log"
+	"gameWeb/loginprovider"
+	"gameWeb/middleware"
+	"gameWeb/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetGrayReleaseRules 查询全部灰度规则（含停用的），按匹配顺序排列
+func GetGrayReleaseRules(c *gin.Context) {
+	rules, err := grayrelease.LoadRules(false)
+	if err != nil {
+		log.Errorf("查询灰度发布规则失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "获取成功",
+		Data:    rules,
+	})
+}
+
+// CreateGrayReleaseRule 创建灰度规则
+func CreateGrayReleaseRule(c *gin.Context) {
+	var req models.GrayReleaseRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if msg := validateGrayReleaseRule(&req); msg != "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: msg,
+		})
+		return
+	}
+
+	adminID := c.GetUint64("adminId")
+	id, err := createGrayReleaseRule(&req, adminID, c.GetString("username"))
+	if err != nil {
+		log.Errorf("创建灰度发布规则失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "创建失败",
+		})
+		return
+	}
+	reloadGrayRelease()
+	middleware.SetAuditTarget(c, "grayReleaseRule", id)
+	middleware.SetAuditAfter(c, req)
+	log.Infof("创建灰度发布规则: ID=%d, 名称=%s, 启用=%v, 操作者=%d", id, req.Name, req.Enabled, adminID)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "创建成功",
+		Data:    gin.H{"id": id},
+	})
+}
+
+// UpdateGrayReleaseRule 修改灰度规则（包括启用和停用）
+// 分流比例以规则ID为种子，修改比例不会打乱已命中的玩家；需要重新分流时删除后重新创建
+func UpdateGrayReleaseRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的规则ID",
+		})
+		return
+	}
+	var req models.GrayReleaseRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if msg := validateGrayReleaseRule(&req); msg != "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: msg,
+		})
+		return
+	}
+
+	before, ok := loadGrayReleaseRule(c, id)
+	if !ok {
+		return
+	}
+	middleware.SetAuditTarget(c, "grayReleaseRule", id)
+	middleware.SetAuditBefore(c, before)
+
+	adminID := c.GetUint64("adminId")
+	if err := updateGrayReleaseRule(id, &req, adminID, c.GetString("username")); err != nil {
+		log.Errorf("修改灰度发布规则失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "修改失败",
+		})
+		return
+	}
+	reloadGrayRelease()
+	middleware.SetAuditAfter(c, req)
+	log.Infof("修改灰度发布规则: ID=%d, 名称=%s, 启用 %v -> %v, 操作者=%d", id, req.Name, before.Enabled, req.Enabled, adminID)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "修改成功",
+	})
+}
+
+// DeleteGrayReleaseRule 删除灰度规则，规则引用的节点不再是灰度节点，所有玩家都可以看到
+func DeleteGrayReleaseRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的规则ID",
+		})
+		return
+	}
+
+	before, ok := loadGrayReleaseRule(c, id)
+	if !ok {
+		return
+	}
+	middleware.SetAuditTarget(c, "grayReleaseRule", id)
+	middleware.SetAuditBefore(c, before)
+
+	if _, err := db.MySQLDBGameWeb.Exec("DELETE FROM grayReleaseRule WHERE id = ?", id); err != nil {
+		log.Errorf("删除灰度发布规则失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "删除失败",
+		})
+		return
+	}
+	reloadGrayRelease()
+	log.Infof("删除灰度发布规则: ID=%d, 名称=%s, 操作者=%d", id, before.Name, c.GetUint64("adminId"))
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "删除成功",
+	})
+}
+
+// PreviewGrayRelease 按当前生效的规则和集群配置，预览玩家会命中的规则和看到的节点
+func PreviewGrayRelease(c *gin.Context) {
+	var req models.GrayReleasePreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	snap, err := cluster.Current()
+	if err == cluster.ErrNotFound {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Code:    404,
+			Message: "集群配置不存在",
+		})
+		return
+	}
+	if err != nil {
+		log.Errorf("读取集群配置失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	routing := grayrelease.Route(snap.Config, req.Userid, req.Channel, req.Version)
+	data := gin.H{
+		"rule":  routing.Rule,
+		"gates": nodeNames(routing.Gates),
+		"games": nodeNames(routing.Games),
+	}
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "获取成功",
+		Data:    data,
+	})
+}
+
+// validateGrayReleaseRule 校验匹配条件和分配的节点，返回错误信息，为空表示通过
+func validateGrayReleaseRule(req *models.GrayReleaseRuleRequest) string {
+	if len(req.Userids) == 0 && len(req.Channels) == 0 && req.MinVersion == "" && req.MaxVersion == "" && req.Percentage == nil {
+		return "至少需要一个匹配条件：指定用户、登录方式、版本范围或分流比例"
+	}
+	if len(req.GateNodes) == 0 && len(req.GameNodes) == 0 {
+		return "至少需要分配网关节点或游戏节点"
+	}
+	for _, channel := range req.Channels {
+		if _, ok := loginprovider.Get(channel); !ok {
+			return "未知的登录方式: " + channel
+		}
+	}
+	for _, v := range []string{req.MinVersion, req.MaxVersion} {
+		if _, err := clientversion.Parse(v); v != "" && err != nil {
+			return "版本号格式错误: " + v
+		}
+	}
+	if req.MinVersion != "" && req.MaxVersion != "" && clientversion.Compare(req.MaxVersion, req.MinVersion) < 0 {
+		return "最高版本不能低于最低版本"
+	}
+
+	// 节点必须存在于当前集群配置中，避免名称写错导致规则不生效
+	snap, err := cluster.Current()
+	if err != nil {
+		return "无法读取集群配置，不能校验节点: " + err.Error()
+	}
+	if name := missingNode(snap.Config.List.Gate, req.GateNodes); name != "" {
+		return "集群配置中没有网关节点: " + name
+	}
+	if name := missingNode(snap.Config.List.Game, req.GameNodes); name != "" {
+		return "集群配置中没有游戏节点: " + name
+	}
+	return ""
+}
+
+// missingNode 返回第一个不在节点列表中的名称，都存在时为空
+func missingNode(nodes []cluster.ServiceNode, names []string) string {
+	exists := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		exists[node.Name] = true
+	}
+	for _, name := range names {
+		if !exists[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+// nodeNames 节点名称列表
+func nodeNames(nodes []cluster.ServiceNode) []string {
+	names := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+	return names
+}
+
+// loadGrayReleaseRule 查询灰度规则，不存在或查询失败时写入响应并返回 false
+func loadGrayReleaseRule(c *gin.Context, id uint64) (models.GrayReleaseRule, bool) {
+	row := db.MySQLDBGameWeb.QueryRow("SELECT "+grayrelease.RuleColumns+" FROM grayReleaseRule WHERE id = ?", id)
+	rule, err := grayrelease.ScanRule(row)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Code:    404,
+			Message: "灰度规则不存在",
+		})
+		return rule, false
+	}
+	if err != nil {
+		log.Errorf("查询灰度发布规则失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return rule, false
+	}
+	return rule, true
+}
+
+// reloadGrayRelease 通知所有实例重新加载灰度规则，本实例立即重新加载
+func reloadGrayRelease() {
+	if err := grayrelease.NotifyChanged(); err != nil {
+		log.Warnf("发送灰度发布规则变更通知失败: %v", err)
+	}
+	if err := grayrelease.Reload(); err != nil {
+		log.Warnf("重新加载灰度发布规则失败: %v", err)
+	}
+}
+
+// 数据库操作函数
+
+// encodeJSONList 列表序列化为JSON，为空时保存 NULL
+func encodeJSONList(list interface{}, n int) interface{} {
+	if n == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(list)
+	return string(data)
+}
+
+// createGrayReleaseRule 创建灰度规则
+func createGrayReleaseRule(req *models.GrayReleaseRuleRequest, adminID uint64, adminName string) (int64, error) {
+	result, err := db.MySQLDBGameWeb.Exec(`
+		INSERT INTO grayReleaseRule (name, priority, enabled, userids, channels, minVersion, maxVersion, percentage,
+			gateNodes, gameNodes, comment, updatedBy, updatedByName)
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, NULLIF(?, ''), ?, ?)`,
+		req.Name, req.Priority, req.Enabled,
+		encodeJSONList(req.Userids, len(req.Userids)), encodeJSONList(req.Channels, len(req.Channels)),
+		req.MinVersion, req.MaxVersion, req.Percentage,
+		encodeJSONList(req.GateNodes, len(req.GateNodes)), encodeJSONList(req.GameNodes, len(req.GameNodes)),
+		req.Comment, adminID, adminName)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// updateGrayReleaseRule 修改灰度规则
+func updateGrayReleaseRule(id uint64, req *models.GrayReleaseRuleRequest, adminID uint64, adminName string) error {
+	_, err := db.MySQLDBGameWeb.Exec(`
+		UPDATE grayReleaseRule
+		SET name = ?, priority = ?, enabled = ?, userids = ?, channels = ?, minVersion = NULLIF(?, ''), maxVersion = NULLIF(?, ''),
+			percentage = ?, gateNodes = ?, gameNodes = ?, comment = NULLIF(?, ''), updatedBy = ?, updatedByName = ?
+		WHERE id = ?`,
+		req.Name, req.Priority, req.Enabled,
+		encodeJSONList(req.Userids, len(req.Userids)), encodeJSONList(req.Channels, len(req.Channels)),
+		req.MinVersion, req.MaxVersion, req.Percentage,
+		encodeJSONList(req.GateNodes, len(req.GateNodes)), encodeJSONList(req.GameNodes, len(req.GameNodes)),
+		req.Comment, adminID, adminName, id)
+	return err
+}
@@ -0,0 +1,558 @@
+package controller
+
+import (
+	"database/sql"
+	"errors"
+	"gameWeb/db"
+	"gameWeb/log"
+	"gameWeb/loginprovider"
+	"gameWeb/middleware"
+	"gameWeb/models"
+	"gameWeb/wechat"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// accountLoginType 账号密码登录（account 表，由登录服维护）
+const accountLoginType = "account"
+
+var (
+	errIdentityBoundToOther = errors.New("identity already bound to another user")
+	errLoginTypeBound       = errors.New("login type already bound")
+	errLastIdentity         = errors.New("cannot remove the last login identity")
+	errIdentityNotBound     = errors.New("login type not bound")
+)
+
+// identityTable 登录方式与账号表的对应关系
+type identityTable struct {
+	loginType string
+	table     string // 固定表名，不来自客户端输入
+}
+
+// identityTables 全部账号表：account 加上已注册的登录方式
+func identityTables() []identityTable {
+	tables := []identityTable{{loginType: accountLoginType, table: "account"}}
+	for _, p := range loginprovider.All() {
+		tables = append(tables, identityTable{loginType: p.Name(), table: p.Table()})
+	}
+	return tables
+}
+
+// findIdentityTable 按登录方式查找账号表
+func findIdentityTable(loginType string) (identityTable, bool) {
+	for _, t := range identityTables() {
+		if t.loginType == loginType {
+			return t, true
+		}
+	}
+	return identityTable{}, false
+}
+
+// GetClientBindings 客户端查询当前用户绑定的登录方式
+func GetClientBindings(c *gin.Context) {
+	identities, err := getUserIdentities(c.GetInt64("userid"))
+	if err != nil {
+		log.Errorf("Failed to query user identities: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "Success",
+		"data":    identities,
+	})
+}
+
+// BindLoginIdentity 客户端为当前用户绑定另一种登录方式，登录数据与 ThirdLogin 相同
+func BindLoginIdentity(c *gin.Context) {
+	var req struct {
+		Appid     int    `json:"appid" binding:"required"`
+		LoginType string `json:"loginType" binding:"required"`
+		LoginData string `json:"loginData" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request",
+		})
+		return
+	}
+
+	provider, ok := loginprovider.Get(req.LoginType)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Unsupported login type",
+		})
+		return
+	}
+	app, ok := loginprovider.FindApp(req.Appid, provider.Name())
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Login type not enabled for this app",
+		})
+		return
+	}
+
+	identity, err := provider.Login(c.Request.Context(), app, req.LoginData)
+	if err != nil {
+		if err == loginprovider.ErrInvalidLoginData {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Invalid login data",
+			})
+			return
+		}
+		log.Errorf("Failed to verify %s login data for binding: %v", provider.Name(), err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"code":    502,
+			"message": "Failed to verify login data",
+		})
+		return
+	}
+
+	userid := c.GetInt64("userid")
+	_, password := thirdAccountPassword(identity.SessionKey)
+	err = bindIdentity(userid, identityTable{loginType: provider.Name(), table: provider.Table()}, identity.OpenID, password)
+	switch err {
+	case nil:
+	case errIdentityBoundToOther:
+		log.Warnf("Bind conflict: %s identity already bound to another user, userid=%d", provider.Name(), userid)
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": "This identity is already bound to another user",
+		})
+		return
+	case errLoginTypeBound:
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": "Login type already bound, unbind it first",
+		})
+		return
+	default:
+		log.Errorf("Failed to bind login identity: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to bind login identity",
+		})
+		return
+	}
+
+	if provider.Name() == loginprovider.WechatMiniGame && identity.SessionKey != "" {
+		if err := wechat.SaveSessionKey(app.AppID, identity.OpenID, identity.SessionKey); err != nil {
+			log.Errorf("Failed to save wechat session key: %v", err)
+		}
+	}
+	log.Infof("Login identity bound: userid=%d, loginType=%s", userid, provider.Name())
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "Success",
+		"data":    gin.H{"loginType": provider.Name(), "openid": identity.OpenID},
+	})
+}
+
+// UnbindLoginIdentity 客户端解绑一种登录方式；不能解绑当前登录使用的方式，也不能解绑最后一种
+func UnbindLoginIdentity(c *gin.Context) {
+	var req struct {
+		LoginType string `json:"loginType" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request",
+		})
+		return
+	}
+
+	t, ok := findIdentityTable(req.LoginType)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Unsupported login type",
+		})
+		return
+	}
+	if req.LoginType == c.GetString("channelid") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Cannot unbind the login type of the current session",
+		})
+		return
+	}
+
+	userid := c.GetInt64("userid")
+	if _, err := unbindIdentity(userid, t); err != nil {
+		switch err {
+		case errIdentityNotBound:
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "Login type not bound",
+			})
+		case errLastIdentity:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Cannot unbind the last login type",
+			})
+		default:
+			log.Errorf("Failed to unbind login identity: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "Failed to unbind login identity",
+			})
+		}
+		return
+	}
+	log.Infof("Login identity unbound: userid=%d, loginType=%s", userid, t.loginType)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "Success",
+	})
+}
+
+// GetUserIdentities 管理后台查看用户绑定的全部登录身份
+func GetUserIdentities(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("userid"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	identities, err := getUserIdentities(userID)
+	if err != nil {
+		log.Errorf("查询用户登录身份失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "获取成功",
+		Data:    identities,
+	})
+}
+
+// UnlinkUserIdentity 管理后台解绑用户的一种登录身份，不能解绑最后一种
+func UnlinkUserIdentity(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("userid"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+	t, ok := findIdentityTable(c.Param("loginType"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "不支持的登录方式",
+		})
+		return
+	}
+
+	middleware.SetAuditTarget(c, "user", userID)
+	username, err := unbindIdentity(userID, t)
+	if err != nil {
+		switch err {
+		case errIdentityNotBound:
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Code:    404,
+				Message: "用户未绑定该登录方式",
+			})
+		case errLastIdentity:
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Code:    400,
+				Message: "不能解绑用户唯一的登录方式",
+			})
+		default:
+			log.Errorf("解绑用户登录身份失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Code:    500,
+				Message: "系统错误",
+			})
+		}
+		return
+	}
+	middleware.SetAuditBefore(c, gin.H{"loginType": t.loginType, "username": username})
+
+	adminId, _ := c.Get("adminId")
+	log.Infof("解绑用户登录身份: 用户ID=%d, 登录方式=%s, 操作者=%v, IP=%s", userID, t.loginType, adminId, c.ClientIP())
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "解绑成功",
+	})
+}
+
+// MergeUserIdentities 管理后台把另一个用户的全部登录身份移到指定用户下，用于玩家误用新身份登录产生了重复账号
+// 只移动登录身份，不合并游戏数据；被移走身份的用户令牌会被吊销
+func MergeUserIdentities(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("userid"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	var req models.UserIdentityMergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if req.FromUserid == userID {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "不能与自身合并",
+		})
+		return
+	}
+
+	if _, err := getUserDetailByID(userID); err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Code:    404,
+				Message: "用户不存在",
+			})
+			return
+		}
+		log.Errorf("查询用户详情失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	middleware.SetAuditTarget(c, "user", userID)
+	moved, conflicts, err := mergeIdentities(req.FromUserid, userID)
+	if err != nil {
+		log.Errorf("合并用户登录身份失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if len(conflicts) > 0 {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Code:    409,
+			Message: "目标用户已绑定以下登录方式，请先解绑: " + strings.Join(conflicts, ", "),
+			Data:    gin.H{"conflicts": conflicts},
+		})
+		return
+	}
+	if len(moved) == 0 {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Code:    404,
+			Message: "来源用户没有登录身份",
+		})
+		return
+	}
+	middleware.SetAuditAfter(c, gin.H{"fromUserid": req.FromUserid, "moved": moved, "reason": req.Reason})
+
+	if _, err := middleware.RevokeAllClientTokens(req.FromUserid); err != nil {
+		log.Errorf("吊销来源用户令牌失败: %v", err)
+	}
+
+	adminId, _ := c.Get("adminId")
+	log.Infof("合并用户登录身份: %d -> %d, 登录方式=%v, 原因=%s, 操作者=%v, IP=%s",
+		req.FromUserid, userID, moved, req.Reason, adminId, c.ClientIP())
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "合并成功",
+		Data:    gin.H{"moved": moved},
+	})
+}
+
+// 数据库操作函数
+
+// getUserIdentities 查询用户在全部账号表中的登录身份
+func getUserIdentities(userid int64) ([]models.UserIdentity, error) {
+	identities := []models.UserIdentity{}
+	for _, t := range identityTables() {
+		rows, err := db.MySQLDB.Query("SELECT username, created_at FROM `"+t.table+"` WHERE userid = ?", userid)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			identity := models.UserIdentity{LoginType: t.loginType}
+			if err := rows.Scan(&identity.Username, &identity.CreatedAt); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			identities = append(identities, identity)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return identities, nil
+}
+
+// getUserLatestLoginType 查询用户最近使用的登录方式（登录时会更新账号表，取 updated_at 最新的一条）
+// 用户没有任何登录身份时返回空字符串
+func getUserLatestLoginType(userid int64) (string, error) {
+	latest := ""
+	var latestTime time.Time
+	for _, t := range identityTables() {
+		var updated sql.NullTime
+		err := db.MySQLDB.QueryRow("SELECT MAX(COALESCE(updated_at, created_at)) FROM `"+t.table+"` WHERE userid = ?", userid).Scan(&updated)
+		if err != nil {
+			return "", err
+		}
+		if updated.Valid && (latest == "" || updated.Time.After(latestTime)) {
+			latest, latestTime = t.loginType, updated.Time
+		}
+	}
+	return latest, nil
+}
+
+// countUserIdentities 在事务中统计用户的登录身份数量并加锁
+func countUserIdentities(tx *sql.Tx, userid int64) (int, error) {
+	total := 0
+	for _, t := range identityTables() {
+		var n int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM `"+t.table+"` WHERE userid = ? FOR UPDATE", userid).Scan(&n); err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// bindIdentity 把登录身份绑定到用户：身份已属于该用户时只更新密码；属于其他用户时冲突；
+// 用户已绑定同一登录方式的其他身份时冲突；身份存在但登录服尚未分配userid时直接归属该用户
+func bindIdentity(userid int64, t identityTable, username, password string) error {
+	tx, err := db.MySQLDB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var owner int64
+	err = tx.QueryRow("SELECT userid FROM `"+t.table+"` WHERE username = ? FOR UPDATE", username).Scan(&owner)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil && owner > 0 && owner != userid {
+		return errIdentityBoundToOther
+	}
+	if err == nil && owner == userid {
+		if _, err := tx.Exec("UPDATE `"+t.table+"` SET password = ? WHERE username = ?", password, username); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	var existing string
+	err = tx.QueryRow("SELECT username FROM `"+t.table+"` WHERE userid = ? LIMIT 1 FOR UPDATE", userid).Scan(&existing)
+	if err == nil {
+		return errLoginTypeBound
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO `"+t.table+"` (username, userid, password, type) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE userid = ?, password = ?",
+		username, userid, password, t.loginType, userid, password)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// unbindIdentity 删除用户在该账号表中的登录身份，返回被删除的用户标识
+func unbindIdentity(userid int64, t identityTable) (string, error) {
+	tx, err := db.MySQLDB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	total, err := countUserIdentities(tx, userid)
+	if err != nil {
+		return "", err
+	}
+	var username string
+	err = tx.QueryRow("SELECT username FROM `"+t.table+"` WHERE userid = ? LIMIT 1", userid).Scan(&username)
+	if err == sql.ErrNoRows {
+		return "", errIdentityNotBound
+	}
+	if err != nil {
+		return "", err
+	}
+	result, err := tx.Exec("DELETE FROM `"+t.table+"` WHERE userid = ?", userid)
+	if err != nil {
+		return "", err
+	}
+	if n, _ := result.RowsAffected(); int(n) >= total {
+		return "", errLastIdentity
+	}
+	return username, tx.Commit()
+}
+
+// mergeIdentities 把 from 用户的全部登录身份移到 to 用户，to 已绑定同一登录方式时返回冲突列表且不做修改
+func mergeIdentities(from, to int64) ([]string, []string, error) {
+	tx, err := db.MySQLDB.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var moved, conflicts []string
+	var pending []identityTable
+	for _, t := range identityTables() {
+		var fromCount, toCount int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM `"+t.table+"` WHERE userid = ? FOR UPDATE", from).Scan(&fromCount); err != nil {
+			return nil, nil, err
+		}
+		if fromCount == 0 {
+			continue
+		}
+		if err := tx.QueryRow("SELECT COUNT(*) FROM `"+t.table+"` WHERE userid = ? FOR UPDATE", to).Scan(&toCount); err != nil {
+			return nil, nil, err
+		}
+		if toCount > 0 {
+			conflicts = append(conflicts, t.loginType)
+			continue
+		}
+		pending = append(pending, t)
+	}
+	if len(conflicts) > 0 {
+		return nil, conflicts, nil
+	}
+
+	for _, t := range pending {
+		if _, err := tx.Exec("UPDATE `"+t.table+"` SET userid = ? WHERE userid = ?", to, from); err != nil {
+			return nil, nil, err
+		}
+		moved = append(moved, t.loginType)
+	}
+	return moved, nil, tx.Commit()
+}
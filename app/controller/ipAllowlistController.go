@@ -0,0 +1,289 @@
+package controller
+
+import (
+	"database/sql"
+	"gameWeb/db"
+	"gameWeb/log"
+	"gameWeb/middleware"
+	"gameWeb/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetIPAllowlist 查询IP白名单（仅超级管理员），adminId=0 查询全局白名单
+func GetIPAllowlist(c *gin.Context) {
+	var req models.AdminIPAllowlistQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	entries, err := getIPAllowlistEntries(req.AdminID)
+	if err != nil {
+		log.Errorf("查询IP白名单失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "获取成功",
+		Data:    entries,
+	})
+}
+
+// AddIPAllowlist 添加IP白名单条目（仅超级管理员）
+func AddIPAllowlist(c *gin.Context) {
+	var req models.AdminIPAllowlistCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("添加IP白名单参数绑定失败: %v", err)
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	cidr, err := middleware.NormalizeCIDR(req.CIDR)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if req.AdminID != middleware.GlobalIPAllowlistID {
+		if _, err := getAdminByID(req.AdminID); err != nil {
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusNotFound, models.APIResponse{
+					Code:    404,
+					Message: "管理员不存在",
+				})
+				return
+			}
+			log.Errorf("查询管理员失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Code:    500,
+				Message: "系统错误",
+			})
+			return
+		}
+	}
+
+	// 新条目会让白名单从“不限制”变为“限制”，需要确认操作者自己不会因此被拒绝
+	adminId, _ := c.Get("adminId")
+	if ok := checkIPAllowlistChange(c, adminId.(uint64), req.AdminID, cidr, true); !ok {
+		return
+	}
+
+	if exists, err := checkIPAllowlistEntryExists(req.AdminID, cidr); err != nil {
+		log.Errorf("检查IP白名单失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	} else if exists {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "该IP段已在白名单中",
+		})
+		return
+	}
+
+	id, err := createIPAllowlistEntry(req.AdminID, cidr, req.Remark, adminId.(uint64))
+	if err != nil {
+		log.Errorf("添加IP白名单失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "添加失败",
+		})
+		return
+	}
+	middleware.ClearAdminIPAllowlistCache(req.AdminID)
+	middleware.SetAuditTarget(c, "ipAllowlist", id)
+
+	log.Infof("添加IP白名单: ID=%d, 管理员ID=%d, CIDR=%s, 操作者=%v", id, req.AdminID, cidr, adminId)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "添加成功",
+		Data:    gin.H{"id": id, "cidr": cidr},
+	})
+}
+
+// DeleteIPAllowlist 删除IP白名单条目（仅超级管理员）
+func DeleteIPAllowlist(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的条目ID",
+		})
+		return
+	}
+
+	entry, err := getIPAllowlistEntryByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Code:    404,
+				Message: "白名单条目不存在",
+			})
+			return
+		}
+		log.Errorf("查询IP白名单失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	adminId, _ := c.Get("adminId")
+	if ok := checkIPAllowlistChange(c, adminId.(uint64), entry.AdminID, entry.CIDR, false); !ok {
+		return
+	}
+
+	middleware.SetAuditTarget(c, "ipAllowlist", id)
+	middleware.SetAuditBefore(c, entry)
+
+	if _, err := db.MySQLDBGameWeb.Exec("DELETE FROM adminIpAllowlist WHERE id = ?", id); err != nil {
+		log.Errorf("删除IP白名单失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "删除失败",
+		})
+		return
+	}
+	middleware.ClearAdminIPAllowlistCache(entry.AdminID)
+
+	log.Infof("删除IP白名单: ID=%d, 管理员ID=%d, CIDR=%s, 操作者=%v", id, entry.AdminID, entry.CIDR, adminId)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "删除成功",
+	})
+}
+
+// checkIPAllowlistChange 变更涉及操作者自己（全局白名单或操作者的白名单）时，
+// 确认变更后操作者当前IP仍然可以访问，避免把自己锁在外面
+func checkIPAllowlistChange(c *gin.Context, operatorID, targetAdminID uint64, cidr string, adding bool) bool {
+	if targetAdminID != middleware.GlobalIPAllowlistID && targetAdminID != operatorID {
+		return true
+	}
+
+	lists := map[uint64][]string{}
+	for _, id := range []uint64{middleware.GlobalIPAllowlistID, operatorID} {
+		cidrs, err := middleware.GetAdminIPAllowlist(id)
+		if err != nil {
+			log.Errorf("查询IP白名单失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Code:    500,
+				Message: "系统错误",
+			})
+			return false
+		}
+		lists[id] = cidrs
+	}
+
+	// 只修改目标白名单，另一份保持不变
+	changed := []string{}
+	for _, item := range lists[targetAdminID] {
+		if adding || item != cidr {
+			changed = append(changed, item)
+		}
+	}
+	if adding {
+		changed = append(changed, cidr)
+	}
+	lists[targetAdminID] = changed
+
+	combined := append(append([]string{}, lists[middleware.GlobalIPAllowlistID]...), lists[operatorID]...)
+	if middleware.IPMatchesAllowlist(c.ClientIP(), combined) {
+		return true
+	}
+
+	c.JSON(http.StatusBadRequest, models.APIResponse{
+		Code:    400,
+		Message: "操作后当前IP(" + c.ClientIP() + ")将无法访问管理后台，请先添加当前IP",
+	})
+	return false
+}
+
+// getIPAllowlistEntries 查询白名单条目，adminID为空时返回全部
+func getIPAllowlistEntries(adminID *uint64) ([]models.AdminIPAllowlist, error) {
+	query := "SELECT id, adminId, cidr, COALESCE(remark, ''), createdBy, createdTime FROM adminIpAllowlist"
+	args := []interface{}{}
+	if adminID != nil {
+		query += " WHERE adminId = ?"
+		args = append(args, *adminID)
+	}
+	query += " ORDER BY adminId, id"
+
+	rows, err := db.MySQLDBGameWeb.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AdminIPAllowlist{}
+	for rows.Next() {
+		var item models.AdminIPAllowlist
+		if err := rows.Scan(&item.ID, &item.AdminID, &item.CIDR, &item.Remark, &item.CreatedBy, &item.CreatedTime); err != nil {
+			return nil, err
+		}
+		entries = append(entries, item)
+	}
+	return entries, rows.Err()
+}
+
+// getIPAllowlistEntryByID 根据ID查询白名单条目
+func getIPAllowlistEntryByID(id uint64) (*models.AdminIPAllowlist, error) {
+	var item models.AdminIPAllowlist
+	query := "SELECT id, adminId, cidr, COALESCE(remark, ''), createdBy, createdTime FROM adminIpAllowlist WHERE id = ?"
+	err := db.MySQLDBGameWeb.QueryRow(query, id).Scan(&item.ID, &item.AdminID, &item.CIDR, &item.Remark, &item.CreatedBy, &item.CreatedTime)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// checkIPAllowlistEntryExists 检查白名单条目是否已存在
+func checkIPAllowlistEntryExists(adminID uint64, cidr string) (bool, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM adminIpAllowlist WHERE adminId = ? AND cidr = ?"
+	if err := db.MySQLDBGameWeb.QueryRow(query, adminID, cidr).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// createIPAllowlistEntry 新增白名单条目
+func createIPAllowlistEntry(adminID uint64, cidr, remark string, createdBy uint64) (uint64, error) {
+	var remarkValue interface{}
+	if remark != "" {
+		remarkValue = remark
+	}
+	result, err := db.MySQLDBGameWeb.Exec(
+		"INSERT INTO adminIpAllowlist (adminId, cidr, remark, createdBy) VALUES (?, ?, ?, ?)",
+		adminID, cidr, remarkValue, createdBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(id), nil
+}
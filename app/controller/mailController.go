@@ -9,6 +9,7 @@ import (
 	"gameWeb/config"
 	"gameWeb/db"
 	"gameWeb/log"
+	"gameWeb/middleware"
 	"gameWeb/models"
 	"net/http"
 	"strconv"
@@ -697,6 +698,14 @@ func SendSystemMail(c *gin.Context) {
 		return
 	}
 
+	// 记录审计信息
+	middleware.SetAuditTarget(c, "mail", mailID)
+	middleware.SetAuditAfter(c, gin.H{
+		"mailId":        mailID,
+		"request":       req,
+		"affectedUsers": affectedUsers,
+	})
+
 	// 记录操作日志
 	mailTypeStr := "全服邮件"
 	if req.Type == 1 {
@@ -1488,6 +1497,12 @@ func UpdateMailStatus(c *gin.Context) {
 		return
 	}
 
+	// 记录审计快照
+	middleware.SetAuditTarget(c, "mail", mailID)
+	if before, err := getMailSystemTimeRange(mailID); err == nil {
+		middleware.SetAuditBefore(c, before)
+	}
+
 	// 执行操作
 	switch req.Action {
 	case "extend":
@@ -1540,6 +1555,10 @@ func UpdateMailStatus(c *gin.Context) {
 		return
 	}
 
+	if after, err := getMailSystemTimeRange(mailID); err == nil {
+		middleware.SetAuditAfter(c, gin.H{"action": req.Action, "mail": after})
+	}
+
 	// 获取管理员信息记录操作日志
 	adminId, _ := c.Get("adminId")
 	username, _ := c.Get("username")
@@ -1552,6 +1571,20 @@ func UpdateMailStatus(c *gin.Context) {
 	})
 }
 
+// getMailSystemTimeRange 查询系统邮件的生效时间范围（用于审计快照），非全服邮件返回空结果
+func getMailSystemTimeRange(mailID int64) (gin.H, error) {
+	var startTime, endTime time.Time
+	query := "SELECT startTime, endTime FROM mailSystem WHERE mailid = ?"
+	err := db.MySQLDBGameWeb.QueryRow(query, mailID).Scan(&startTime, &endTime)
+	if err == sql.ErrNoRows {
+		return gin.H{"mailId": mailID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{"mailId": mailID, "startTime": startTime, "endTime": endTime}, nil
+}
+
 // GetMailStats 获取邮件统计
 func GetMailStats(c *gin.Context) {
 	// 查询邮件统计信息
@@ -0,0 +1,559 @@
+package controller
+
+import (
+	"database/sql"
+	"encoding/json"
+	"gameWeb/db"
+	"gameWeb/log"
+	"gameWeb/loginprovider"
+	"gameWeb/maintenance"
+	"gameWeb/middleware"
+	"gameWeb/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMaintenanceStatus 查看当前生效和计划中的维护窗口（直接查询数据库，不经过缓存）
+func GetMaintenanceStatus(c *gin.Context) {
+	windows, err := maintenance.LoadOpenWindows(time.Now())
+	if err != nil {
+		log.Errorf("查询维护窗口失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	active := []models.MaintenanceWindow{}
+	scheduled := []models.MaintenanceWindow{}
+	for _, w := range windows {
+		if w.State == maintenance.StateActive {
+			active = append(active, w)
+		} else {
+			scheduled = append(scheduled, w)
+		}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "获取成功",
+		Data: gin.H{
+			"inMaintenance": len(active) > 0,
+			"active":        active,
+			"scheduled":     scheduled,
+		},
+	})
+}
+
+// GetMaintenanceWindows 分页查询维护窗口
+func GetMaintenanceWindows(c *gin.Context) {
+	var req models.MaintenanceWindowQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	windows, total, err := getMaintenanceWindowList(req)
+	if err != nil {
+		log.Errorf("查询维护窗口失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "获取成功",
+		Data: models.PaginationResponse{
+			Total:    total,
+			Page:     req.Page,
+			PageSize: req.PageSize,
+			Data:     windows,
+		},
+	})
+}
+
+// StartMaintenance 立即开始维护
+func StartMaintenance(c *gin.Context) {
+	var req models.MaintenanceWindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+	now := time.Now()
+	req.StartTime = &now
+
+	createMaintenance(c, &req)
+}
+
+// ScheduleMaintenance 计划维护，到开始时间后自动生效
+func ScheduleMaintenance(c *gin.Context) {
+	var req models.MaintenanceWindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if req.StartTime == nil || !req.StartTime.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "计划维护的开始时间必须晚于当前时间，立即维护请使用开始维护接口",
+		})
+		return
+	}
+
+	createMaintenance(c, &req)
+}
+
+// UpdateMaintenanceWindow 修改未结束的维护窗口（如延长维护时间、修改公告）
+// 维护已经开始时不能修改开始时间
+func UpdateMaintenanceWindow(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的维护窗口ID",
+		})
+		return
+	}
+	var req models.MaintenanceWindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	before, ok := loadOpenMaintenanceWindow(c, id)
+	if !ok {
+		return
+	}
+	if before.State == maintenance.StateActive || req.StartTime == nil {
+		req.StartTime = &before.StartTime
+	}
+	if msg := validateMaintenanceRequest(&req); msg != "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: msg,
+		})
+		return
+	}
+	middleware.SetAuditTarget(c, "maintenanceWindow", id)
+	middleware.SetAuditBefore(c, before)
+
+	if err := updateMaintenanceWindow(id, &req); err != nil {
+		log.Errorf("修改维护窗口失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "修改失败",
+		})
+		return
+	}
+	reloadMaintenance()
+	middleware.SetAuditAfter(c, req)
+	log.Infof("修改维护窗口: ID=%d, 操作者=%d", id, c.GetUint64("adminId"))
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "修改成功",
+	})
+}
+
+// StopMaintenanceWindow 手动结束维护中的窗口，或取消计划中的窗口
+func StopMaintenanceWindow(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的维护窗口ID",
+		})
+		return
+	}
+
+	before, ok := loadOpenMaintenanceWindow(c, id)
+	if !ok {
+		return
+	}
+	middleware.SetAuditTarget(c, "maintenanceWindow", id)
+	middleware.SetAuditBefore(c, gin.H{"state": before.State})
+
+	adminID := c.GetUint64("adminId")
+	if err := stopMaintenanceWindow(id, adminID); err != nil {
+		log.Errorf("结束维护窗口失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "操作失败",
+		})
+		return
+	}
+	reloadMaintenance()
+	middleware.SetAuditAfter(c, gin.H{"state": maintenance.StateStopped})
+	log.Infof("结束维护窗口: ID=%d, 原阶段=%s, 操作者=%d", id, before.State, adminID)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "操作成功",
+	})
+}
+
+// GetMaintenanceAllowlist 查询维护放行名单
+func GetMaintenanceAllowlist(c *gin.Context) {
+	entries, err := maintenance.LoadAllowlist()
+	if err != nil {
+		log.Errorf("查询维护放行名单失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "获取成功",
+		Data:    entries,
+	})
+}
+
+// AddMaintenanceAllowlist 添加维护放行名单条目，IP 支持单个地址或 CIDR
+func AddMaintenanceAllowlist(c *gin.Context) {
+	var req models.MaintenanceAllowlistCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	value := req.Value
+	if req.Type == maintenance.AllowIP {
+		cidr, err := middleware.NormalizeCIDR(req.Value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Code:    400,
+				Message: err.Error(),
+			})
+			return
+		}
+		value = cidr
+	} else {
+		userid, err := strconv.ParseInt(req.Value, 10, 64)
+		if err != nil || userid <= 0 {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Code:    400,
+				Message: "无效的用户ID",
+			})
+			return
+		}
+		value = strconv.FormatInt(userid, 10)
+	}
+
+	adminID := c.GetUint64("adminId")
+	id, created, err := createMaintenanceAllowlistEntry(req.Type, value, req.Remark, adminID)
+	if err != nil {
+		log.Errorf("添加维护放行名单失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "添加失败",
+		})
+		return
+	}
+	if !created {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "该条目已在放行名单中",
+		})
+		return
+	}
+	reloadMaintenance()
+	middleware.SetAuditTarget(c, "maintenanceAllowlist", id)
+	log.Infof("添加维护放行名单: ID=%d, %s=%s, 操作者=%d", id, req.Type, value, adminID)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "添加成功",
+		Data:    gin.H{"id": id, "type": req.Type, "value": value},
+	})
+}
+
+// DeleteMaintenanceAllowlist 删除维护放行名单条目
+func DeleteMaintenanceAllowlist(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的条目ID",
+		})
+		return
+	}
+	middleware.SetAuditTarget(c, "maintenanceAllowlist", id)
+
+	affected, err := deleteMaintenanceAllowlistEntry(id)
+	if err != nil {
+		log.Errorf("删除维护放行名单失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "删除失败",
+		})
+		return
+	}
+	if affected == 0 {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Code:    404,
+			Message: "放行名单条目不存在",
+		})
+		return
+	}
+	reloadMaintenance()
+	log.Infof("删除维护放行名单: ID=%d, 操作者=%d", id, c.GetUint64("adminId"))
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "删除成功",
+	})
+}
+
+// createMaintenance 校验并创建维护窗口
+func createMaintenance(c *gin.Context, req *models.MaintenanceWindowRequest) {
+	if msg := validateMaintenanceRequest(req); msg != "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: msg,
+		})
+		return
+	}
+
+	adminID := c.GetUint64("adminId")
+	id, err := createMaintenanceWindow(req, adminID, c.GetString("username"))
+	if err != nil {
+		log.Errorf("创建维护窗口失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "创建失败",
+		})
+		return
+	}
+	reloadMaintenance()
+	middleware.SetAuditTarget(c, "maintenanceWindow", id)
+	middleware.SetAuditAfter(c, req)
+	log.Infof("创建维护窗口: ID=%d, 开始时间=%s, 操作者=%d", id, req.StartTime.Format(time.RFC3339), adminID)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "创建成功",
+		Data:    gin.H{"id": id},
+	})
+}
+
+// validateMaintenanceRequest 校验登录方式和结束时间，返回错误信息，为空表示通过
+func validateMaintenanceRequest(req *models.MaintenanceWindowRequest) string {
+	for _, channel := range req.Channels {
+		if _, ok := loginprovider.Get(channel); !ok {
+			return "未知的登录方式: " + channel
+		}
+	}
+	for channel, notice := range req.ChannelNotices {
+		if _, ok := loginprovider.Get(channel); !ok {
+			return "未知的登录方式: " + channel
+		}
+		if len([]rune(notice)) > 1000 {
+			return "维护公告不能超过1000个字符: " + channel
+		}
+	}
+	if req.EndTime != nil {
+		if !req.EndTime.After(*req.StartTime) {
+			return "结束时间必须晚于开始时间"
+		}
+		if !req.EndTime.After(time.Now()) {
+			return "结束时间必须晚于当前时间"
+		}
+	}
+	return ""
+}
+
+// loadOpenMaintenanceWindow 查询未结束的维护窗口，不存在或已结束时写入响应并返回 false
+func loadOpenMaintenanceWindow(c *gin.Context, id uint64) (models.MaintenanceWindow, bool) {
+	w, err := getMaintenanceWindowByID(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Code:    404,
+			Message: "维护窗口不存在",
+		})
+		return w, false
+	}
+	if err != nil {
+		log.Errorf("查询维护窗口失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return w, false
+	}
+	if w.State != maintenance.StateActive && w.State != maintenance.StateScheduled {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "维护窗口已结束",
+		})
+		return w, false
+	}
+	return w, true
+}
+
+// reloadMaintenance 通知所有实例重新加载维护配置，本实例立即重新加载
+func reloadMaintenance() {
+	if err := maintenance.NotifyChanged(); err != nil {
+		log.Warnf("发送停服维护变更通知失败: %v", err)
+	}
+	if err := maintenance.Reload(); err != nil {
+		log.Warnf("重新加载停服维护配置失败: %v", err)
+	}
+}
+
+// respondMaintenance 维护期间拒绝客户端请求，返回维护公告和时间；结束时间确定时设置 Retry-After
+func respondMaintenance(c *gin.Context, w *models.MaintenanceWindow, channel string) {
+	info := gin.H{
+		"id":        w.ID,
+		"notice":    maintenance.Notice(w, channel),
+		"startTime": w.StartTime,
+	}
+	if w.EndTime != nil {
+		info["endTime"] = *w.EndTime
+		if wait := int(time.Until(*w.EndTime).Seconds()); wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(wait))
+		}
+	}
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"code":    503,
+		"message": "Server under maintenance",
+		"data":    gin.H{"maintenance": info},
+	})
+}
+
+// 数据库操作函数
+
+// createMaintenanceWindow 创建维护窗口
+func createMaintenanceWindow(req *models.MaintenanceWindowRequest, adminID uint64, adminName string) (int64, error) {
+	channels, notices := encodeMaintenanceTargets(req)
+	result, err := db.MySQLDBGameWeb.Exec(`
+		INSERT INTO maintenanceWindow (title, channels, notice, channelNotices, startTime, endTime, createdBy, createdByName)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		req.Title, channels, req.Notice, notices, *req.StartTime, req.EndTime, adminID, adminName)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// updateMaintenanceWindow 修改维护窗口
+func updateMaintenanceWindow(id uint64, req *models.MaintenanceWindowRequest) error {
+	channels, notices := encodeMaintenanceTargets(req)
+	_, err := db.MySQLDBGameWeb.Exec(`
+		UPDATE maintenanceWindow
+		SET title = ?, channels = ?, notice = ?, channelNotices = ?, startTime = ?, endTime = ?
+		WHERE id = ? AND status = ?`,
+		req.Title, channels, req.Notice, notices, *req.StartTime, req.EndTime, id, maintenance.StatusNormal)
+	return err
+}
+
+// encodeMaintenanceTargets 登录方式和按登录方式的公告序列化为JSON，为空时保存 NULL
+func encodeMaintenanceTargets(req *models.MaintenanceWindowRequest) (interface{}, interface{}) {
+	var channels, notices interface{}
+	if len(req.Channels) > 0 {
+		data, _ := json.Marshal(req.Channels)
+		channels = string(data)
+	}
+	if len(req.ChannelNotices) > 0 {
+		data, _ := json.Marshal(req.ChannelNotices)
+		notices = string(data)
+	}
+	return channels, notices
+}
+
+// stopMaintenanceWindow 手动结束或取消维护窗口
+func stopMaintenanceWindow(id, adminID uint64) error {
+	_, err := db.MySQLDBGameWeb.Exec(`
+		UPDATE maintenanceWindow SET status = ?, stoppedBy = ?, stoppedTime = ?
+		WHERE id = ? AND status = ?`,
+		maintenance.StatusStopped, adminID, time.Now(), id, maintenance.StatusNormal)
+	return err
+}
+
+// getMaintenanceWindowByID 查询维护窗口
+func getMaintenanceWindowByID(id uint64) (models.MaintenanceWindow, error) {
+	row := db.MySQLDBGameWeb.QueryRow("SELECT "+maintenance.WindowColumns+" FROM maintenanceWindow WHERE id = ?", id)
+	return maintenance.ScanWindow(row)
+}
+
+// getMaintenanceWindowList 分页查询维护窗口，按开始时间倒序
+func getMaintenanceWindowList(req models.MaintenanceWindowQueryRequest) ([]models.MaintenanceWindow, int64, error) {
+	where := "1=1"
+	args := []interface{}{}
+	if req.Status != nil {
+		where += " AND status = ?"
+		args = append(args, *req.Status)
+	}
+
+	var total int64
+	if err := db.MySQLDBGameWeb.QueryRow("SELECT COUNT(*) FROM maintenanceWindow WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, req.PageSize, (req.Page-1)*req.PageSize)
+	rows, err := db.MySQLDBGameWeb.Query(
+		"SELECT "+maintenance.WindowColumns+" FROM maintenanceWindow WHERE "+where+" ORDER BY startTime DESC, id DESC LIMIT ? OFFSET ?",
+		args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	windows := []models.MaintenanceWindow{}
+	for rows.Next() {
+		w, err := maintenance.ScanWindow(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, total, rows.Err()
+}
+
+// createMaintenanceAllowlistEntry 添加放行名单条目，已存在时 created 为 false
+func createMaintenanceAllowlistEntry(entryType, value, remark string, adminID uint64) (int64, bool, error) {
+	result, err := db.MySQLDBGameWeb.Exec(`
+		INSERT IGNORE INTO maintenanceAllowlist (type, value, remark, createdBy)
+		VALUES (?, ?, ?, ?)`, entryType, value, remark, adminID)
+	if err != nil {
+		return 0, false, err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return 0, false, nil
+	}
+	id, err := result.LastInsertId()
+	return id, true, err
+}
+
+// deleteMaintenanceAllowlistEntry 删除放行名单条目
+func deleteMaintenanceAllowlistEntry(id uint64) (int64, error) {
+	result, err := db.MySQLDBGameWeb.Exec("DELETE FROM maintenanceAllowlist WHERE id = ?", id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,657 @@
+package controller
+
+import (
+	"database/sql"
+	"fmt"
+	"gameWeb/db"
+	"gameWeb/log"
+	"gameWeb/middleware"
+	"gameWeb/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPermissionList 获取全部权限定义
+func GetPermissionList(c *gin.Context) {
+	perms, err := getAllPermissions()
+	if err != nil {
+		log.Errorf("查询权限列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "查询成功",
+		Data:    perms,
+	})
+}
+
+// GetRoleList 获取角色列表（包含每个角色的权限）
+func GetRoleList(c *gin.Context) {
+	roles, err := getAllRoles()
+	if err != nil {
+		log.Errorf("查询角色列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "查询成功",
+		Data:    roles,
+	})
+}
+
+// CreateRole 创建角色
+func CreateRole(c *gin.Context) {
+	var req struct {
+		Name        string   `json:"name" binding:"required,min=1,max=50"`
+		Description string   `json:"description" binding:"max=255"`
+		Permissions []string `json:"permissions"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("创建角色参数绑定失败: %v", err)
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if invalid, err := findInvalidPermissions(req.Permissions); err != nil {
+		log.Errorf("校验权限编码失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	} else if len(invalid) > 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的权限编码: " + strings.Join(invalid, ", "),
+		})
+		return
+	}
+
+	if exists, err := checkRoleNameExists(req.Name, 0); err != nil {
+		log.Errorf("检查角色名称失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	} else if exists {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "角色名称已存在",
+		})
+		return
+	}
+
+	adminId, _ := c.Get("adminId")
+	roleID, err := createRole(req.Name, req.Description, req.Permissions, adminId.(uint64))
+	if err != nil {
+		log.Errorf("创建角色失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "创建失败",
+		})
+		return
+	}
+
+	middleware.SetAuditTarget(c, "role", roleID)
+
+	log.Infof("角色创建成功: ID=%d, 名称=%s, 权限=%v, 操作者=%v", roleID, req.Name, req.Permissions, adminId)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "创建成功",
+		Data:    gin.H{"id": roleID},
+	})
+}
+
+// UpdateRole 更新角色名称、描述或权限
+func UpdateRole(c *gin.Context) {
+	roleID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的角色ID",
+		})
+		return
+	}
+
+	var req struct {
+		Name        *string   `json:"name" binding:"omitempty,min=1,max=50"`
+		Description *string   `json:"description" binding:"omitempty,max=255"`
+		Permissions *[]string `json:"permissions"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("更新角色参数绑定失败: %v", err)
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	before, err := getRoleByID(roleID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Code:    404,
+				Message: "角色不存在",
+			})
+			return
+		}
+		log.Errorf("查询角色失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	middleware.SetAuditTarget(c, "role", roleID)
+	middleware.SetAuditBefore(c, before)
+
+	if req.Name != nil {
+		if exists, err := checkRoleNameExists(*req.Name, roleID); err != nil {
+			log.Errorf("检查角色名称失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Code:    500,
+				Message: "系统错误",
+			})
+			return
+		} else if exists {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Code:    400,
+				Message: "角色名称已存在",
+			})
+			return
+		}
+	}
+
+	if req.Permissions != nil {
+		if invalid, err := findInvalidPermissions(*req.Permissions); err != nil {
+			log.Errorf("校验权限编码失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Code:    500,
+				Message: "系统错误",
+			})
+			return
+		} else if len(invalid) > 0 {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Code:    400,
+				Message: "无效的权限编码: " + strings.Join(invalid, ", "),
+			})
+			return
+		}
+	}
+
+	adminId, _ := c.Get("adminId")
+	if err := updateRole(roleID, req.Name, req.Description, req.Permissions, adminId.(uint64)); err != nil {
+		log.Errorf("更新角色失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "更新失败",
+		})
+		return
+	}
+
+	// 角色权限变化会影响所有拥有该角色的管理员
+	if req.Permissions != nil {
+		middleware.ClearAllAdminPermissionCache()
+	}
+
+	if after, err := getRoleByID(roleID); err == nil {
+		middleware.SetAuditAfter(c, after)
+	}
+
+	log.Infof("角色更新成功: ID=%d, 操作者=%v", roleID, adminId)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "更新成功",
+	})
+}
+
+// DeleteRole 删除角色，同时解除所有管理员与该角色的关联
+func DeleteRole(c *gin.Context) {
+	roleID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的角色ID",
+		})
+		return
+	}
+
+	role, err := getRoleByID(roleID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Code:    404,
+				Message: "角色不存在",
+			})
+			return
+		}
+		log.Errorf("查询角色失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	middleware.SetAuditTarget(c, "role", roleID)
+	middleware.SetAuditBefore(c, role)
+
+	if err := deleteRoleByID(roleID); err != nil {
+		log.Errorf("删除角色失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "删除失败",
+		})
+		return
+	}
+	middleware.ClearAllAdminPermissionCache()
+
+	adminId, _ := c.Get("adminId")
+	log.Infof("角色删除成功: ID=%d, 名称=%s, 操作者=%v", roleID, role.Name, adminId)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "删除成功",
+	})
+}
+
+// GetAdminRoles 获取指定管理员的角色和最终权限
+func GetAdminRoles(c *gin.Context) {
+	targetAdminID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的管理员ID格式",
+		})
+		return
+	}
+
+	roles, err := getRolesByAdminID(targetAdminID)
+	if err != nil {
+		log.Errorf("查询管理员角色失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	perms, err := middleware.GetAdminPermissions(targetAdminID)
+	if err != nil {
+		log.Errorf("查询管理员权限失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "查询成功",
+		Data: gin.H{
+			"roles":       roles,
+			"permissions": perms,
+		},
+	})
+}
+
+// SetAdminRoles 设置管理员的角色（整体替换）
+func SetAdminRoles(c *gin.Context) {
+	targetAdminID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的管理员ID格式",
+		})
+		return
+	}
+
+	var req struct {
+		RoleIDs []uint64 `json:"roleIds"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("设置管理员角色参数绑定失败: %v", err)
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if _, err := getAdminByID(targetAdminID); err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Code:    404,
+				Message: "管理员不存在",
+			})
+			return
+		}
+		log.Errorf("查询目标管理员信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	for _, roleID := range req.RoleIDs {
+		if _, err := getRoleByID(roleID); err != nil {
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusBadRequest, models.APIResponse{
+					Code:    400,
+					Message: fmt.Sprintf("角色不存在: %d", roleID),
+				})
+				return
+			}
+			log.Errorf("查询角色失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Code:    500,
+				Message: "系统错误",
+			})
+			return
+		}
+	}
+
+	middleware.SetAuditTarget(c, "admin", targetAdminID)
+	if before, err := getRolesByAdminID(targetAdminID); err == nil {
+		middleware.SetAuditBefore(c, before)
+	}
+
+	adminId, _ := c.Get("adminId")
+	if err := setAdminRoles(targetAdminID, req.RoleIDs, adminId.(uint64)); err != nil {
+		log.Errorf("设置管理员角色失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "设置失败",
+		})
+		return
+	}
+	middleware.ClearAdminPermissionCache(targetAdminID)
+
+	log.Infof("管理员角色设置成功: 管理员ID=%d, 角色=%v, 操作者=%v", targetAdminID, req.RoleIDs, adminId)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "设置成功",
+	})
+}
+
+// ==================== 数据库操作函数 ====================
+
+// getAllPermissions 查询全部权限定义
+func getAllPermissions() ([]models.AdminPermission, error) {
+	rows, err := db.MySQLDBGameWeb.Query("SELECT code, name, COALESCE(description, '') FROM adminPermission ORDER BY code")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	perms := []models.AdminPermission{}
+	for rows.Next() {
+		var perm models.AdminPermission
+		if err := rows.Scan(&perm.Code, &perm.Name, &perm.Description); err != nil {
+			return nil, err
+		}
+		perms = append(perms, perm)
+	}
+	return perms, rows.Err()
+}
+
+// findInvalidPermissions 返回不存在于adminPermission表中的权限编码
+func findInvalidPermissions(codes []string) ([]string, error) {
+	if len(codes) == 0 {
+		return nil, nil
+	}
+	perms, err := getAllPermissions()
+	if err != nil {
+		return nil, err
+	}
+	valid := make(map[string]bool, len(perms))
+	for _, perm := range perms {
+		valid[perm.Code] = true
+	}
+
+	var invalid []string
+	for _, code := range codes {
+		if !valid[code] {
+			invalid = append(invalid, code)
+		}
+	}
+	return invalid, nil
+}
+
+// checkRoleNameExists 检查角色名称是否存在（排除指定ID）
+func checkRoleNameExists(name string, excludeID uint64) (bool, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM adminRole WHERE name = ? AND id != ?"
+	err := db.MySQLDBGameWeb.QueryRow(query, name, excludeID).Scan(&count)
+	return count > 0, err
+}
+
+// getRoleByID 根据ID查询角色（不含权限）
+func getRoleByID(roleID uint64) (*models.AdminRole, error) {
+	role := &models.AdminRole{}
+	query := `
+		SELECT id, name, COALESCE(description, ''), createdBy, updatedBy, createdTime, updatedTime
+		FROM adminRole
+		WHERE id = ?
+	`
+	err := db.MySQLDBGameWeb.QueryRow(query, roleID).Scan(
+		&role.ID, &role.Name, &role.Description, &role.CreatedBy, &role.UpdatedBy,
+		&role.CreatedTime, &role.UpdatedTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// getAllRoles 查询全部角色及其权限
+func getAllRoles() ([]*models.AdminRole, error) {
+	query := `
+		SELECT id, name, COALESCE(description, ''), createdBy, updatedBy, createdTime, updatedTime
+		FROM adminRole
+		ORDER BY id
+	`
+	return queryRolesWithPermissions(query)
+}
+
+// getRolesByAdminID 查询管理员拥有的角色及其权限
+func getRolesByAdminID(adminID uint64) ([]*models.AdminRole, error) {
+	query := `
+		SELECT r.id, r.name, COALESCE(r.description, ''), r.createdBy, r.updatedBy, r.createdTime, r.updatedTime
+		FROM adminRole r
+		INNER JOIN adminAccountRole ar ON r.id = ar.roleId
+		WHERE ar.adminId = ?
+		ORDER BY r.id
+	`
+	return queryRolesWithPermissions(query, adminID)
+}
+
+// queryRolesWithPermissions 查询角色列表并补充每个角色的权限
+func queryRolesWithPermissions(query string, args ...interface{}) ([]*models.AdminRole, error) {
+	rows, err := db.MySQLDBGameWeb.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []*models.AdminRole{}
+	roleMap := make(map[uint64]*models.AdminRole)
+	for rows.Next() {
+		role := &models.AdminRole{Permissions: []string{}}
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.CreatedBy, &role.UpdatedBy,
+			&role.CreatedTime, &role.UpdatedTime); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+		roleMap[role.ID] = role
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(roles) == 0 {
+		return roles, nil
+	}
+
+	// 批量查询角色权限
+	placeholders := make([]string, len(roles))
+	roleArgs := make([]interface{}, len(roles))
+	for i, role := range roles {
+		placeholders[i] = "?"
+		roleArgs[i] = role.ID
+	}
+	permQuery := fmt.Sprintf("SELECT roleId, permissionCode FROM adminRolePermission WHERE roleId IN (%s) ORDER BY permissionCode",
+		strings.Join(placeholders, ","))
+
+	permRows, err := db.MySQLDBGameWeb.Query(permQuery, roleArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer permRows.Close()
+
+	for permRows.Next() {
+		var roleID uint64
+		var code string
+		if err := permRows.Scan(&roleID, &code); err != nil {
+			return nil, err
+		}
+		if role, ok := roleMap[roleID]; ok {
+			role.Permissions = append(role.Permissions, code)
+		}
+	}
+	return roles, permRows.Err()
+}
+
+// createRole 创建角色并写入权限
+func createRole(name, description string, permissions []string, createdBy uint64) (uint64, error) {
+	tx, err := db.MySQLDBGameWeb.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO adminRole (name, description, createdBy) VALUES (?, ?, ?)",
+		name, description, createdBy)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := replaceRolePermissions(tx, uint64(id), permissions); err != nil {
+		return 0, err
+	}
+	return uint64(id), tx.Commit()
+}
+
+// updateRole 更新角色，permissions不为nil时整体替换权限
+func updateRole(roleID uint64, name, description *string, permissions *[]string, updatedBy uint64) error {
+	tx, err := db.MySQLDBGameWeb.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var setParts []string
+	var args []interface{}
+	if name != nil {
+		setParts = append(setParts, "name = ?")
+		args = append(args, *name)
+	}
+	if description != nil {
+		setParts = append(setParts, "description = ?")
+		args = append(args, *description)
+	}
+	setParts = append(setParts, "updatedBy = ?", "updatedTime = CURRENT_TIMESTAMP")
+	args = append(args, updatedBy, roleID)
+
+	query := fmt.Sprintf("UPDATE adminRole SET %s WHERE id = ?", strings.Join(setParts, ", "))
+	if _, err := tx.Exec(query, args...); err != nil {
+		return err
+	}
+
+	if permissions != nil {
+		if err := replaceRolePermissions(tx, roleID, *permissions); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// replaceRolePermissions 替换角色的全部权限
+func replaceRolePermissions(tx *sql.Tx, roleID uint64, permissions []string) error {
+	if _, err := tx.Exec("DELETE FROM adminRolePermission WHERE roleId = ?", roleID); err != nil {
+		return err
+	}
+	for _, code := range permissions {
+		if _, err := tx.Exec("INSERT IGNORE INTO adminRolePermission (roleId, permissionCode) VALUES (?, ?)",
+			roleID, code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteRoleByID 删除角色及其关联数据
+func deleteRoleByID(roleID uint64) error {
+	tx, err := db.MySQLDBGameWeb.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM adminAccountRole WHERE roleId = ?", roleID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM adminRolePermission WHERE roleId = ?", roleID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM adminRole WHERE id = ?", roleID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// setAdminRoles 整体替换管理员的角色
+func setAdminRoles(adminID uint64, roleIDs []uint64, createdBy uint64) error {
+	tx, err := db.MySQLDBGameWeb.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM adminAccountRole WHERE adminId = ?", adminID); err != nil {
+		return err
+	}
+	for _, roleID := range roleIDs {
+		if _, err := tx.Exec("INSERT IGNORE INTO adminAccountRole (adminId, roleId, createdBy) VALUES (?, ?, ?)",
+			adminID, roleID, createdBy); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
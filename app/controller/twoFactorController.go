@@ -0,0 +1,1009 @@
+package controller
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"gameWeb/config"
+	"gameWeb/db"
+	"gameWeb/log"
+	"gameWeb/models"
+	"gameWeb/totp"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// adminPreAuthTTL 预认证令牌有效期：密码验证通过到完成两步验证之间的最长时间
+	adminPreAuthTTL = 5 * time.Minute
+	// adminPreAuthMaxAttempts 单个预认证令牌允许的验证码尝试次数
+	adminPreAuthMaxAttempts = 5
+	// recoveryCodeCount 每次生成的恢复码数量
+	recoveryCodeCount = 10
+
+	// settingRequireTwoFactor 全局设置：是否要求所有管理员启用两步验证
+	settingRequireTwoFactor = "require_two_factor"
+	// twoFactorSecretPrefix 加密保存的TOTP密钥前缀
+	twoFactorSecretPrefix = "enc:"
+)
+
+// adminPreAuth 预认证令牌在Redis中保存的数据
+type adminPreAuth struct {
+	AdminID uint64 `json:"adminId"`
+	IP      string `json:"ip"`
+}
+
+// ==================== 登录流程 ====================
+
+// adminNeedsTwoFactor 判断管理员登录是否需要两步验证，以及是否需要先绑定
+func adminNeedsTwoFactor(adminID uint64) (bool, bool, error) {
+	tf, err := getAdminTwoFactor(adminID)
+	if err != nil {
+		return false, false, err
+	}
+	if tf != nil && tf.Enabled == 1 {
+		return true, false, nil
+	}
+
+	required, err := isTwoFactorRequired()
+	if err != nil {
+		return false, false, err
+	}
+	return required, required, nil
+}
+
+// createAdminPreAuth 生成预认证令牌并保存到Redis
+func createAdminPreAuth(adminID uint64, ip string) (string, error) {
+	token, err := generateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(adminPreAuth{AdminID: adminID, IP: ip})
+	if err != nil {
+		return "", err
+	}
+	if err := db.SetRedisWithExpire("admin_preauth:"+token, string(data), adminPreAuthTTL); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// loadAdminPreAuth 读取预认证令牌并校验IP和尝试次数
+func loadAdminPreAuth(c *gin.Context, token string) (*adminPreAuth, bool) {
+	data, err := db.GetRedis("admin_preauth:" + token)
+	if err != nil || data == "" {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Code:    401,
+			Message: "预认证令牌无效或已过期，请重新登录",
+		})
+		return nil, false
+	}
+
+	var preAuth adminPreAuth
+	if err := json.Unmarshal([]byte(data), &preAuth); err != nil || preAuth.IP != c.ClientIP() {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Code:    401,
+			Message: "预认证令牌无效或已过期，请重新登录",
+		})
+		return nil, false
+	}
+
+	attemptsKey := "admin_preauth_attempts:" + token
+	attempts, err := db.IncrRedis(attemptsKey)
+	if err != nil {
+		log.Errorf("记录预认证尝试次数失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return nil, false
+	}
+	if attempts == 1 {
+		if err := db.ExpireRedis(attemptsKey, adminPreAuthTTL); err != nil {
+			log.Errorf("设置预认证尝试次数过期时间失败: %v", err)
+		}
+	}
+	if attempts > adminPreAuthMaxAttempts {
+		deleteAdminPreAuth(token)
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Code:    401,
+			Message: "验证失败次数过多，请重新登录",
+		})
+		return nil, false
+	}
+
+	return &preAuth, true
+}
+
+// deleteAdminPreAuth 删除预认证令牌
+func deleteAdminPreAuth(token string) {
+	if err := db.DelRedis("admin_preauth:" + token); err != nil {
+		log.Errorf("删除预认证令牌失败: %v", err)
+	}
+	if err := db.DelRedis("admin_preauth_attempts:" + token); err != nil {
+		log.Errorf("删除预认证尝试次数失败: %v", err)
+	}
+}
+
+// AdminLoginTwoFactorSetup 登录过程中绑定两步验证（系统要求启用但管理员尚未绑定时使用）
+func AdminLoginTwoFactorSetup(c *gin.Context) {
+	var req struct {
+		PreAuthToken string `json:"preAuthToken" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("两步验证绑定参数绑定失败: %v", err)
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	preAuth, ok := loadAdminPreAuth(c, req.PreAuthToken)
+	if !ok {
+		return
+	}
+
+	admin, err := getAdminByID(preAuth.AdminID)
+	if err != nil {
+		log.Errorf("查询管理员信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	respondTwoFactorSetup(c, admin)
+}
+
+// AdminLoginTwoFactor 登录第二步：校验TOTP验证码或恢复码，通过后签发正式JWT
+func AdminLoginTwoFactor(c *gin.Context) {
+	var req struct {
+		PreAuthToken string `json:"preAuthToken" binding:"required"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recoveryCode"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("两步验证参数绑定失败: %v", err)
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "验证码和恢复码不能同时为空",
+		})
+		return
+	}
+
+	preAuth, ok := loadAdminPreAuth(c, req.PreAuthToken)
+	if !ok {
+		return
+	}
+
+	admin, err := getAdminByID(preAuth.AdminID)
+	if err != nil {
+		log.Errorf("查询管理员信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if admin.Status != 1 {
+		deleteAdminPreAuth(req.PreAuthToken)
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Code:    401,
+			Message: "账户已被禁用",
+		})
+		return
+	}
+
+	clientIP := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// 预认证之后账户或IP可能已被锁定（如其他会话连续输错），锁定期间不再校验验证码
+	if remaining, err := checkAdminLoginLocked(admin.Username, clientIP); err != nil {
+		log.Errorf("检查管理员登录锁定失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	} else if remaining > 0 {
+		log.Warnf("管理员两步验证被拒绝 - 已锁定: %s, IP: %s, 剩余: %v", admin.Username, clientIP, remaining)
+		recordAdminLoginLog(&admin.ID, admin.Username, clientIP, userAgent, 0, "已锁定")
+		deleteAdminPreAuth(req.PreAuthToken)
+		respondAdminLoginLocked(c, remaining)
+		return
+	}
+
+	tf, err := getAdminTwoFactor(admin.ID)
+	if err != nil {
+		log.Errorf("查询管理员两步验证信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if tf == nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "请先绑定两步验证",
+		})
+		return
+	}
+
+	// 尚未启用：本次验证同时完成绑定，只接受TOTP验证码
+	if tf.Enabled != 1 {
+		step, valid := totp.Validate(tf.Secret, req.Code, time.Now(), tf.LastUsedStep)
+		if !valid {
+			recordAdminLoginLog(&admin.ID, admin.Username, clientIP, userAgent, 0, "两步验证码错误")
+			recordAdminLoginFailure(admin.Username, clientIP)
+			c.JSON(http.StatusUnauthorized, models.APIResponse{
+				Code:    401,
+				Message: "验证码错误",
+			})
+			return
+		}
+
+		codes, err := enableAdminTwoFactor(admin.ID, step)
+		if err != nil {
+			log.Errorf("启用两步验证失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Code:    500,
+				Message: "系统错误",
+			})
+			return
+		}
+		if codes == nil {
+			// 并发请求已使用同一验证码完成绑定
+			c.JSON(http.StatusUnauthorized, models.APIResponse{
+				Code:    401,
+				Message: "验证码已使用，请重新登录",
+			})
+			return
+		}
+		deleteAdminPreAuth(req.PreAuthToken)
+		log.Infof("管理员登录时完成两步验证绑定: %s (ID: %d)", admin.Username, admin.ID)
+		completeAdminLogin(c, admin, codes)
+		return
+	}
+
+	valid, err := verifyAdminTwoFactor(tf, req.Code, req.RecoveryCode)
+	if err != nil {
+		log.Errorf("校验两步验证失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if !valid {
+		log.Warnf("管理员两步验证失败: %s, IP: %s", admin.Username, clientIP)
+		recordAdminLoginLog(&admin.ID, admin.Username, clientIP, userAgent, 0, "两步验证码错误")
+		if remaining := recordAdminLoginFailure(admin.Username, clientIP); remaining > 0 {
+			deleteAdminPreAuth(req.PreAuthToken)
+			respondAdminLoginLocked(c, remaining)
+			return
+		}
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Code:    401,
+			Message: "验证码错误",
+		})
+		return
+	}
+
+	deleteAdminPreAuth(req.PreAuthToken)
+	completeAdminLogin(c, admin, nil)
+}
+
+// ==================== 个人两步验证管理 ====================
+
+// GetTwoFactorStatus 获取当前管理员的两步验证状态
+func GetTwoFactorStatus(c *gin.Context) {
+	adminId, _ := c.Get("adminId")
+
+	tf, err := getAdminTwoFactor(adminId.(uint64))
+	if err != nil {
+		log.Errorf("查询管理员两步验证信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	required, err := isTwoFactorRequired()
+	if err != nil {
+		log.Errorf("查询两步验证策略失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	data := gin.H{
+		"enabled":                false,
+		"required":               required,
+		"recoveryCodesRemaining": 0,
+	}
+	if tf != nil && tf.Enabled == 1 {
+		data["enabled"] = true
+		data["recoveryCodesRemaining"] = len(tf.RecoveryCodes)
+		data["enabledTime"] = tf.UpdatedTime
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "获取成功",
+		Data:    data,
+	})
+}
+
+// SetupTwoFactor 生成新的TOTP密钥（待验证状态），返回二维码配置URI
+func SetupTwoFactor(c *gin.Context) {
+	adminId, _ := c.Get("adminId")
+
+	admin, err := getAdminByID(adminId.(uint64))
+	if err != nil {
+		log.Errorf("查询管理员信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	respondTwoFactorSetup(c, admin)
+}
+
+// respondTwoFactorSetup 为管理员生成待验证的TOTP密钥并返回
+func respondTwoFactorSetup(c *gin.Context, admin *models.AdminAccount) {
+	tf, err := getAdminTwoFactor(admin.ID)
+	if err != nil {
+		log.Errorf("查询管理员两步验证信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if tf != nil && tf.Enabled == 1 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "两步验证已启用，如需重新绑定请先关闭",
+		})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		log.Errorf("生成TOTP密钥失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if err := saveAdminTwoFactorSecret(admin.ID, secret); err != nil {
+		log.Errorf("保存TOTP密钥失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "请使用身份验证器扫描二维码后输入验证码完成绑定",
+		Data: models.AdminTwoFactorSetupResponse{
+			Secret:          secret,
+			ProvisioningURI: totp.ProvisioningURI(config.AppConfig.Admin.TwoFactorIssuer, admin.Username, secret),
+		},
+	})
+}
+
+// EnableTwoFactor 校验验证码后启用两步验证，返回恢复码（只显示一次）
+func EnableTwoFactor(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("启用两步验证参数绑定失败: %v", err)
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	adminId, _ := c.Get("adminId")
+	tf, err := getAdminTwoFactor(adminId.(uint64))
+	if err != nil {
+		log.Errorf("查询管理员两步验证信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if tf == nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "请先获取绑定二维码",
+		})
+		return
+	}
+	if tf.Enabled == 1 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "两步验证已启用",
+		})
+		return
+	}
+
+	step, valid := totp.Validate(tf.Secret, req.Code, time.Now(), tf.LastUsedStep)
+	if !valid {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "验证码错误",
+		})
+		return
+	}
+
+	codes, err := enableAdminTwoFactor(tf.AdminID, step)
+	if err != nil {
+		log.Errorf("启用两步验证失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if codes == nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "验证码已使用",
+		})
+		return
+	}
+
+	log.Infof("管理员启用两步验证: ID=%v, IP=%s", adminId, c.ClientIP())
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "两步验证已启用，请妥善保存恢复码",
+		Data:    gin.H{"recoveryCodes": codes},
+	})
+}
+
+// DisableTwoFactor 关闭两步验证，需要同时校验密码和验证码
+func DisableTwoFactor(c *gin.Context) {
+	var req struct {
+		Password     string `json:"password" binding:"required"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recoveryCode"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("关闭两步验证参数绑定失败: %v", err)
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	required, err := isTwoFactorRequired()
+	if err != nil {
+		log.Errorf("查询两步验证策略失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if required {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "系统要求所有管理员启用两步验证，无法关闭",
+		})
+		return
+	}
+
+	adminId, _ := c.Get("adminId")
+	admin, err := getAdminByID(adminId.(uint64))
+	if err != nil {
+		log.Errorf("查询管理员信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(req.Password)); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "密码错误",
+		})
+		return
+	}
+
+	tf, err := getAdminTwoFactor(admin.ID)
+	if err != nil {
+		log.Errorf("查询管理员两步验证信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if tf == nil || tf.Enabled != 1 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "两步验证未启用",
+		})
+		return
+	}
+
+	valid, err := verifyAdminTwoFactor(tf, req.Code, req.RecoveryCode)
+	if err != nil {
+		log.Errorf("校验两步验证失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "验证码错误",
+		})
+		return
+	}
+
+	if err := deleteAdminTwoFactor(admin.ID); err != nil {
+		log.Errorf("关闭两步验证失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	log.Infof("管理员关闭两步验证: ID=%d, IP=%s", admin.ID, c.ClientIP())
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "两步验证已关闭",
+	})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码全部失效
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("重新生成恢复码参数绑定失败: %v", err)
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	adminId, _ := c.Get("adminId")
+	tf, err := getAdminTwoFactor(adminId.(uint64))
+	if err != nil {
+		log.Errorf("查询管理员两步验证信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if tf == nil || tf.Enabled != 1 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "两步验证未启用",
+		})
+		return
+	}
+
+	valid, err := verifyAdminTwoFactor(tf, req.Code, "")
+	if err != nil {
+		log.Errorf("校验两步验证失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "验证码错误",
+		})
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		log.Errorf("生成恢复码失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+	if err := updateAdminRecoveryCodes(tf.AdminID, hashes); err != nil {
+		log.Errorf("保存恢复码失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	log.Infof("管理员重新生成恢复码: ID=%v, IP=%s", adminId, c.ClientIP())
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "恢复码已重新生成，请妥善保存",
+		Data:    gin.H{"recoveryCodes": codes},
+	})
+}
+
+// ==================== 全局策略（仅超级管理员） ====================
+
+// GetTwoFactorPolicy 获取两步验证全局策略
+func GetTwoFactorPolicy(c *gin.Context) {
+	required, err := isTwoFactorRequired()
+	if err != nil {
+		log.Errorf("查询两步验证策略失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "获取成功",
+		Data:    gin.H{"required": required},
+	})
+}
+
+// UpdateTwoFactorPolicy 设置是否要求所有管理员启用两步验证
+func UpdateTwoFactorPolicy(c *gin.Context) {
+	var req struct {
+		Required *bool `json:"required" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("两步验证策略参数绑定失败: %v", err)
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	adminId, _ := c.Get("adminId")
+	if err := setAdminSetting(settingRequireTwoFactor, strconv.FormatBool(*req.Required), adminId.(uint64)); err != nil {
+		log.Errorf("保存两步验证策略失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	log.Infof("管理员修改两步验证策略: required=%v, 操作者=%v, IP=%s", *req.Required, adminId, c.ClientIP())
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "设置成功",
+	})
+}
+
+// ResetAdminTwoFactor 重置指定管理员的两步验证（管理员丢失设备时使用）
+func ResetAdminTwoFactor(c *gin.Context) {
+	targetAdminID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的管理员ID格式",
+		})
+		return
+	}
+
+	if err := deleteAdminTwoFactor(targetAdminID); err != nil {
+		log.Errorf("重置管理员两步验证失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	adminId, _ := c.Get("adminId")
+	log.Infof("管理员两步验证已重置: 目标ID=%d, 操作者=%v, IP=%s", targetAdminID, adminId, c.ClientIP())
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "重置成功",
+	})
+}
+
+// ==================== 辅助函数 ====================
+
+// verifyAdminTwoFactor 校验TOTP验证码或恢复码，成功时更新防重放时间步或消耗恢复码
+// 两者都用条件更新，并发请求使用同一验证码或恢复码时只有一个成功
+func verifyAdminTwoFactor(tf *models.AdminTwoFactor, code, recoveryCode string) (bool, error) {
+	if code != "" {
+		step, valid := totp.Validate(tf.Secret, code, time.Now(), tf.LastUsedStep)
+		if !valid {
+			return false, nil
+		}
+		return advanceAdminTwoFactorStep(tf.AdminID, step)
+	}
+
+	if recoveryCode == "" {
+		return false, nil
+	}
+	hash := hashRecoveryCode(recoveryCode)
+	for i, stored := range tf.RecoveryCodes {
+		if stored == hash {
+			remaining := append(append([]string{}, tf.RecoveryCodes[:i]...), tf.RecoveryCodes[i+1:]...)
+			consumed, err := consumeAdminRecoveryCode(tf.AdminID, tf.RecoveryCodes, remaining)
+			if consumed {
+				log.Infof("管理员使用恢复码: ID=%d, 剩余=%d", tf.AdminID, len(remaining))
+			}
+			return consumed, err
+		}
+	}
+	return false, nil
+}
+
+// sealTwoFactorSecret 使用服务端密钥（AES-256-GCM）加密TOTP密钥
+func sealTwoFactorSecret(secret string) (string, error) {
+	gcm, err := twoFactorSecretCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return twoFactorSecretPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// openTwoFactorSecret 解密数据库中的TOTP密钥，没有加密前缀的数据视为无效
+func openTwoFactorSecret(stored string) (string, error) {
+	if !strings.HasPrefix(stored, twoFactorSecretPrefix) {
+		return "", fmt.Errorf("解析TOTP密钥失败: 缺少加密前缀")
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(stored, twoFactorSecretPrefix))
+	if err != nil {
+		return "", fmt.Errorf("解析TOTP密钥失败: %v", err)
+	}
+	gcm, err := twoFactorSecretCipher()
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("解析TOTP密钥失败: 数据长度错误")
+	}
+	secret, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("解密TOTP密钥失败（admin.twoFactorSecretKey 是否被修改）: %v", err)
+	}
+	return string(secret), nil
+}
+
+// twoFactorSecretCipher 由配置的服务端密钥派生AES-256-GCM
+func twoFactorSecretCipher() (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(config.AppConfig.Admin.TwoFactorSecretKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// generateRecoveryCodes 生成恢复码，返回明文和对应的哈希
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := generateSecureToken(5)
+		if err != nil {
+			return nil, nil, err
+		}
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode 计算恢复码哈希（忽略大小写和分隔符）
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// isTwoFactorRequired 查询是否要求所有管理员启用两步验证
+func isTwoFactorRequired() (bool, error) {
+	value, err := getAdminSetting(settingRequireTwoFactor)
+	if err != nil {
+		return false, err
+	}
+	required, _ := strconv.ParseBool(value)
+	return required, nil
+}
+
+// ==================== 数据库操作函数 ====================
+
+// getAdminTwoFactor 查询管理员两步验证信息，不存在时返回nil
+func getAdminTwoFactor(adminID uint64) (*models.AdminTwoFactor, error) {
+	tf := &models.AdminTwoFactor{}
+	var recoveryCodes sql.NullString
+	query := `
+		SELECT adminId, secret, enabled, recoveryCodes, lastUsedStep, createdTime, updatedTime
+		FROM adminTwoFactor
+		WHERE adminId = ?
+	`
+	err := db.MySQLDBGameWeb.QueryRow(query, adminID).Scan(
+		&tf.AdminID, &tf.Secret, &tf.Enabled, &recoveryCodes, &tf.LastUsedStep,
+		&tf.CreatedTime, &tf.UpdatedTime,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if tf.Secret, err = openTwoFactorSecret(tf.Secret); err != nil {
+		return nil, err
+	}
+	if recoveryCodes.Valid && recoveryCodes.String != "" {
+		if err := json.Unmarshal([]byte(recoveryCodes.String), &tf.RecoveryCodes); err != nil {
+			return nil, fmt.Errorf("解析恢复码失败: %v", err)
+		}
+	}
+	return tf, nil
+}
+
+// saveAdminTwoFactorSecret 加密保存待验证的TOTP密钥（覆盖之前未启用的密钥）
+func saveAdminTwoFactorSecret(adminID uint64, secret string) error {
+	sealed, err := sealTwoFactorSecret(secret)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO adminTwoFactor (adminId, secret, enabled, recoveryCodes, lastUsedStep)
+		VALUES (?, ?, 0, NULL, 0)
+		ON DUPLICATE KEY UPDATE secret = VALUES(secret), enabled = 0, recoveryCodes = NULL, lastUsedStep = 0
+	`
+	_, err = db.MySQLDBGameWeb.Exec(query, adminID, sealed)
+	return err
+}
+
+// enableAdminTwoFactor 启用两步验证并生成恢复码
+// 只更新尚未启用且时间步未被使用的记录，并发请求已完成启用时返回 nil
+func enableAdminTwoFactor(adminID uint64, step int64) ([]string, error) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE adminTwoFactor SET enabled = 1, recoveryCodes = ?, lastUsedStep = ?
+		WHERE adminId = ? AND enabled = 0 AND lastUsedStep < ?
+	`
+	result, err := db.MySQLDBGameWeb.Exec(query, string(data), step, adminID, step)
+	if err != nil {
+		return nil, err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// advanceAdminTwoFactorStep 把最后使用的时间步推进到 step，时间步已被使用（不小于 step）时返回 false
+func advanceAdminTwoFactorStep(adminID uint64, step int64) (bool, error) {
+	result, err := db.MySQLDBGameWeb.Exec(
+		"UPDATE adminTwoFactor SET lastUsedStep = ? WHERE adminId = ? AND lastUsedStep < ?", step, adminID, step)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// consumeAdminRecoveryCode 把恢复码列表从 current 更新为 remaining，列表已被其他请求修改时返回 false
+// 恢复码列表总是由 json.Marshal 写入，重新编码 current 与库中保存的内容一致
+func consumeAdminRecoveryCode(adminID uint64, current, remaining []string) (bool, error) {
+	currentData, err := json.Marshal(current)
+	if err != nil {
+		return false, err
+	}
+	remainingData, err := json.Marshal(remaining)
+	if err != nil {
+		return false, err
+	}
+	result, err := db.MySQLDBGameWeb.Exec(
+		"UPDATE adminTwoFactor SET recoveryCodes = ? WHERE adminId = ? AND recoveryCodes = ?",
+		string(remainingData), adminID, string(currentData))
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// updateAdminRecoveryCodes 更新恢复码哈希列表
+func updateAdminRecoveryCodes(adminID uint64, hashes []string) error {
+	data, err := json.Marshal(hashes)
+	if err != nil {
+		return err
+	}
+	_, err = db.MySQLDBGameWeb.Exec("UPDATE adminTwoFactor SET recoveryCodes = ? WHERE adminId = ?", string(data), adminID)
+	return err
+}
+
+// deleteAdminTwoFactor 删除管理员两步验证信息
+func deleteAdminTwoFactor(adminID uint64) error {
+	_, err := db.MySQLDBGameWeb.Exec("DELETE FROM adminTwoFactor WHERE adminId = ?", adminID)
+	return err
+}
+
+// getAdminSetting 查询全局设置，不存在时返回空字符串
+func getAdminSetting(key string) (string, error) {
+	var value string
+	err := db.MySQLDBGameWeb.QueryRow("SELECT settingValue FROM adminSetting WHERE settingKey = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+// setAdminSetting 保存全局设置
+func setAdminSetting(key, value string, updatedBy uint64) error {
+	query := `
+		INSERT INTO adminSetting (settingKey, settingValue, updatedBy)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE settingValue = VALUES(settingValue), updatedBy = VALUES(updatedBy)
+	`
+	_, err := db.MySQLDBGameWeb.Exec(query, key, value, updatedBy)
+	return err
+}
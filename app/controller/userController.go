@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"gameWeb/db"
 	"gameWeb/log"
+	"gameWeb/middleware"
 	"gameWeb/models"
 	"net/http"
 	"strconv"
@@ -147,6 +148,15 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
+	// 修改财富需要单独的权限
+	if len(req.Riches) > 0 && !middleware.HasPermission(c, middleware.PermUserRichesWrite) {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Code:    403,
+			Message: "没有修改用户财富的权限",
+		})
+		return
+	}
+
 	// 验证用户是否存在
 	exists, err := checkUserExists(userID)
 	if err != nil {
@@ -165,6 +175,12 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
+	// 记录审计快照
+	middleware.SetAuditTarget(c, "user", userID)
+	if before, err := getUserDetailByID(userID); err == nil {
+		middleware.SetAuditBefore(c, before)
+	}
+
 	// 开始事务
 	tx, err := db.MySQLDB.Begin()
 	if err != nil {
@@ -223,6 +239,10 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
+	if after, err := getUserDetailByID(userID); err == nil {
+		middleware.SetAuditAfter(c, after)
+	}
+
 	// 获取管理员信息记录操作日志
 	adminId, _ := c.Get("adminId")
 	username, _ := c.Get("username")
@@ -235,6 +255,55 @@ func UpdateUser(c *gin.Context) {
 	})
 }
 
+// RevokeUserTokens 吊销用户的全部客户端令牌（踢下线），用户需要重新登录
+func RevokeUserTokens(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("userid"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Code:    400,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	if _, err := getUserDetailByID(userID); err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Code:    404,
+				Message: "用户不存在",
+			})
+			return
+		}
+		log.Errorf("查询用户详情失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	middleware.SetAuditTarget(c, "user", userID)
+
+	count, err := middleware.RevokeAllClientTokens(userID)
+	if err != nil {
+		log.Errorf("吊销用户令牌失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "系统错误",
+		})
+		return
+	}
+
+	adminId, _ := c.Get("adminId")
+	log.Infof("吊销用户令牌: 用户ID=%d, 令牌族数=%d, 操作者=%v, IP=%s", userID, count, adminId, c.ClientIP())
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Code:    200,
+		Message: "已吊销用户全部令牌",
+		Data:    gin.H{"revokedRefreshTokens": count},
+	})
+}
+
 // 数据库操作函数
 
 // getUserCount 获取用户总数
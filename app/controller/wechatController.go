@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"gameWeb/db"
+	"gameWeb/log"
+	"gameWeb/loginprovider"
+	"gameWeb/wechat"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userData 资料字段长度上限，与 sql/userData.sql 一致
+const (
+	userDataNicknameMaxLen = 64
+	userDataHeadurlMaxLen  = 512
+	userDataRegionMaxLen   = 64
+)
+
+// UpdateWechatUserInfo 客户端提交 wx.getUserInfo 返回的加密数据，解密后更新 userData 中的资料
+func UpdateWechatUserInfo(c *gin.Context) {
+	var req struct {
+		Appid         int    `json:"appid" binding:"required"`
+		EncryptedData string `json:"encryptedData" binding:"required"`
+		Iv            string `json:"iv" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request",
+		})
+		return
+	}
+
+	app, ok := loginprovider.FindApp(req.Appid, loginprovider.WechatMiniGame)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Login type not enabled for this app",
+		})
+		return
+	}
+
+	userid := c.GetInt64("userid")
+	openids, err := getWechatOpenIDs(userid)
+	if err != nil {
+		log.Errorf("Failed to query wechat account: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Internal server error",
+		})
+		return
+	}
+	if len(openids) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Wechat account not found",
+		})
+		return
+	}
+
+	// 账号表不记录 openid 属于哪个应用（多个微信应用或绑定后一个 userid 可以有多个 openid），
+	// 使用在本应用下缓存了会话密钥的 openid，会话密钥由该应用登录时按 (appid, openid) 保存
+	var openid, sessionKey string
+	for _, candidate := range openids {
+		if key, err := wechat.GetSessionKey(app.AppID, candidate); err == nil {
+			openid, sessionKey = candidate, key
+			break
+		}
+	}
+	if sessionKey == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "Wechat session expired, please login again",
+		})
+		return
+	}
+
+	info, err := wechat.DecryptUserInfo(app.AppID, sessionKey, req.EncryptedData, req.Iv)
+	if err != nil {
+		log.Warnf("Failed to decrypt wechat user info, userid=%d: %v", userid, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid encrypted data",
+		})
+		return
+	}
+	// 新版基础库返回的用户信息不含 openId，有值时必须与当前账号一致
+	if info.OpenID != "" && info.OpenID != openid {
+		log.Warnf("Wechat user info openid mismatch, userid=%d", userid)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid encrypted data",
+		})
+		return
+	}
+
+	sex := info.Gender
+	if sex < 0 || sex > 2 {
+		sex = 0
+	}
+	profile := gin.H{
+		"nickname": truncateRunes(info.NickName, userDataNicknameMaxLen),
+		"headurl":  truncateRunes(info.AvatarURL, userDataHeadurlMaxLen),
+		"sex":      sex,
+		"province": truncateRunes(info.Province, userDataRegionMaxLen),
+		"city":     truncateRunes(info.City, userDataRegionMaxLen),
+	}
+	if err := upsertUserProfile(userid, profile); err != nil {
+		log.Errorf("Failed to update user profile: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to update user info",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "Success",
+		"data":    profile,
+	})
+}
+
+// getWechatOpenIDs 查询用户的全部微信小游戏 openid
+func getWechatOpenIDs(userid int64) ([]string, error) {
+	rows, err := db.MySQLDB.Query("SELECT username FROM wechatMiniGame WHERE userid = ?", userid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var openids []string
+	for rows.Next() {
+		var openid string
+		if err := rows.Scan(&openid); err != nil {
+			return nil, err
+		}
+		openids = append(openids, openid)
+	}
+	return openids, rows.Err()
+}
+
+// upsertUserProfile 写入用户资料，userData 中没有记录时插入
+func upsertUserProfile(userid int64, profile gin.H) error {
+	_, err := db.MySQLDB.Exec(`
+		INSERT INTO userData (userid, nickname, headurl, sex, province, city)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE nickname = VALUES(nickname), headurl = VALUES(headurl), sex = VALUES(sex),
+			province = VALUES(province), city = VALUES(city)`,
+		userid, profile["nickname"], profile["headurl"], profile["sex"], profile["province"], profile["city"])
+	return err
+}
@@ -0,0 +1,178 @@
+// Package clientversion 客户端版本策略：按登录方式和应用配置最低支持版本和推荐版本，
+// 低于最低版本的客户端必须更新，低于推荐版本的客户端提示更新。策略保存在 gameWeb 库，
+// 各实例在内存中缓存，通过变更通知频道和定时刷新更新。
+package clientversion
+
+import (
+	"errors"
+	"gameWeb/config"
+	"gameWeb/db"
+	"gameWeb/log"
+	"gameWeb/models"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 客户端请求头
+const (
+	HeaderVersion = "X-Client-Version" // 客户端版本号，如 1.2.10
+	HeaderAppid   = "X-Client-Appid"   // 客户端应用ID，登录接口可以使用请求体中的 appid
+)
+
+// ErrInvalidVersion 版本号格式错误
+var ErrInvalidVersion = errors.New("invalid version")
+
+// policyKey 策略的查找键，appid 为0表示该登录方式下的全部应用
+type policyKey struct {
+	channel string
+	appid   int
+}
+
+var (
+	current atomic.Value // map[policyKey]*models.ClientVersionPolicy，创建后不再修改
+	loadMu  sync.Mutex
+)
+
+// Result 版本检查结果
+type Result struct {
+	Policy *models.ClientVersionPolicy
+	Force  bool // 低于最低支持版本，必须更新
+}
+
+// Start 加载版本策略并在后台监听变更；加载失败不影响启动，此时不限制版本
+func Start() {
+	if err := Reload(); err != nil {
+		log.Errorf("加载客户端版本策略失败: %v", err)
+	}
+
+	interval := time.Duration(config.AppConfig.ClientVersion.RefreshInterval) * time.Second
+	go db.WatchChannels("客户端版本策略", []string{config.AppConfig.ClientVersion.NotifyChannel}, interval, Reload)
+}
+
+// Reload 从数据库重新加载全部版本策略，失败时保留原数据
+func Reload() error {
+	loadMu.Lock()
+	defer loadMu.Unlock()
+
+	policies, err := LoadPolicies()
+	if err != nil {
+		return err
+	}
+	next := make(map[policyKey]*models.ClientVersionPolicy, len(policies))
+	for i := range policies {
+		p := &policies[i]
+		next[policyKey{p.Channel, p.Appid}] = p
+	}
+	current.Store(next)
+	return nil
+}
+
+// NotifyChanged 修改版本策略后调用，通知所有实例重新加载
+func NotifyChanged() error {
+	return db.PublishRedis(config.AppConfig.ClientVersion.NotifyChannel, "changed")
+}
+
+// Lookup 查找适用的策略：优先使用该应用的策略，没有时使用该登录方式的通用策略（appid 为0）
+func Lookup(channel string, appid int) *models.ClientVersionPolicy {
+	policies, _ := current.Load().(map[policyKey]*models.ClientVersionPolicy)
+	if p := policies[policyKey{channel, appid}]; p != nil {
+		return p
+	}
+	return policies[policyKey{channel, 0}]
+}
+
+// Check 检查客户端版本，不需要更新时返回 nil
+// 没有带版本号或版本号格式错误的客户端视为最低版本（早于版本检查上线的客户端不会带版本号）
+func Check(channel string, appid int, version string) *Result {
+	p := Lookup(channel, appid)
+	if p == nil {
+		return nil
+	}
+	if p.MinVersion != "" && Compare(version, p.MinVersion) < 0 {
+		return &Result{Policy: p, Force: true}
+	}
+	if p.RecommendedVersion != "" && Compare(version, p.RecommendedVersion) < 0 {
+		return &Result{Policy: p}
+	}
+	return nil
+}
+
+// Parse 解析版本号：以点分隔的非负整数，允许 v 前缀，忽略 - 或 + 之后的预发布和构建信息
+func Parse(version string) ([]int, error) {
+	v := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	if v == "" {
+		return nil, ErrInvalidVersion
+	}
+	parts := strings.Split(v, ".")
+	nums := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, ErrInvalidVersion
+		}
+		nums[i] = n
+	}
+	return nums, nil
+}
+
+// Compare 比较两个版本号，a 小于、等于、大于 b 时分别返回 -1、0、1
+// 缺少的段按0处理（1.2 等于 1.2.0），无法解析的版本号小于任何合法版本号
+func Compare(a, b string) int {
+	va, errA := Parse(a)
+	vb, errB := Parse(b)
+	switch {
+	case errA != nil && errB != nil:
+		return 0
+	case errA != nil:
+		return -1
+	case errB != nil:
+		return 1
+	}
+	for i := 0; i < len(va) || i < len(vb); i++ {
+		var x, y int
+		if i < len(va) {
+			x = va[i]
+		}
+		if i < len(vb) {
+			y = vb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// 数据库操作函数
+
+// LoadPolicies 查询全部版本策略，按登录方式和应用排序
+func LoadPolicies() ([]models.ClientVersionPolicy, error) {
+	rows, err := db.MySQLDBGameWeb.Query(`
+		SELECT id, channel, appid, COALESCE(minVersion, ''), COALESCE(recommendedVersion, ''),
+			COALESCE(updateUrl, ''), COALESCE(releaseNotes, ''), updatedBy, updatedByName, createdTime, updatedTime
+		FROM clientVersionPolicy ORDER BY channel ASC, appid ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := []models.ClientVersionPolicy{}
+	for rows.Next() {
+		var p models.ClientVersionPolicy
+		if err := rows.Scan(&p.ID, &p.Channel, &p.Appid, &p.MinVersion, &p.RecommendedVersion,
+			&p.UpdateURL, &p.ReleaseNotes, &p.UpdatedBy, &p.UpdatedByName, &p.CreatedTime, &p.UpdatedTime); err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+	return policies, rows.Err()
+}
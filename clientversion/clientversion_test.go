@@ -0,0 +1,108 @@
+package clientversion
+
+import (
+	"gameWeb/models"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		version string
+		want    []int
+		wantErr bool
+	}{
+		{"1.2.10", []int{1, 2, 10}, false},
+		{"v1.10", []int{1, 10}, false},
+		{" 2 ", []int{2}, false},
+		{"1.2.0-beta.1", []int{1, 2, 0}, false},
+		{"1.2+build.7", []int{1, 2}, false},
+		{"", nil, true},
+		{"v", nil, true},
+		{"-beta", nil, true},
+		{"1..2", nil, true},
+		{"1.x", nil, true},
+		{"1.-2", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := Parse(tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) err = %v, wantErr %v", tt.version, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2", "1.2.0", 0},
+		{"1.2.0.0", "1.2", 0},
+		{"v1.10", "1.9", 1},
+		{"1.9", "1.10", -1},
+		{"1.2.10", "1.2.9", 1},
+		{"2", "1.99.99", 1},
+		{"1.2.0-beta", "1.2.0", 0}, // 忽略预发布信息
+		{"1.2.1-rc.1", "1.2.0", 1},
+		{"", "0.0.1", -1}, // 无法解析的版本号小于任何合法版本号
+		{"1.0", "garbage", 1},
+		{"garbage", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"_vs_"+tt.b, func(t *testing.T) {
+			if got := Compare(tt.a, tt.b); got != tt.want {
+				t.Errorf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := Compare(tt.b, tt.a); got != -tt.want {
+				t.Errorf("Compare(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	general := &models.ClientVersionPolicy{Channel: "wechatMiniGame", MinVersion: "1.2.0", RecommendedVersion: "1.3.0"}
+	app2 := &models.ClientVersionPolicy{Channel: "wechatMiniGame", Appid: 2, MinVersion: "2.0"}
+	current.Store(map[policyKey]*models.ClientVersionPolicy{
+		{"wechatMiniGame", 0}: general,
+		{"wechatMiniGame", 2}: app2,
+	})
+	defer current.Store(map[policyKey]*models.ClientVersionPolicy{})
+
+	tests := []struct {
+		name       string
+		channel    string
+		appid      int
+		version    string
+		wantPolicy *models.ClientVersionPolicy
+		wantForce  bool
+	}{
+		{"没有策略的登录方式", "guest", 1, "0.1", nil, false},
+		{"最新版本", "wechatMiniGame", 1, "1.3", nil, false},
+		{"低于推荐版本", "wechatMiniGame", 1, "1.2.5", general, false},
+		{"低于最低版本", "wechatMiniGame", 1, "1.1.9", general, true},
+		{"没有带版本号视为最低版本", "wechatMiniGame", 1, "", general, true},
+		{"应用策略优先", "wechatMiniGame", 2, "1.9", app2, true},
+		{"应用策略没有推荐版本", "wechatMiniGame", 2, "2.0.0", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Check(tt.channel, tt.appid, tt.version)
+			if tt.wantPolicy == nil {
+				if got != nil {
+					t.Fatalf("Check() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil || got.Policy != tt.wantPolicy || got.Force != tt.wantForce {
+				t.Errorf("Check() = %+v, want policy %+v force %v", got, tt.wantPolicy, tt.wantForce)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+// Package cluster 集群配置（Redis 中的 clusterConfig）的内存缓存。
+// 启动时加载一次，之后通过变更通知频道、Redis 键空间通知和定时刷新更新快照，
+// 请求处理只读取内存中的快照，不再每次访问 Redis 和解析 JSON。
+package cluster
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gameWeb/config"
+	"gameWeb/db"
+	"gameWeb/log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ServiceNode 服务节点
+type ServiceNode struct {
+	Addr       string `json:"addr"`
+	Name       string `json:"name"`
+	Cnt        int    `json:"cnt"` // 当前连接数（负载）
+	ClientAddr string `json:"clientAddr,omitempty"`
+	Hide       bool   `json:"hide,omitempty"`
+	Down       bool   `json:"down,omitempty"`   // 节点不可用（故障或维护中），不再分配新玩家
+	MaxCnt     int    `json:"maxCnt,omitempty"` // 连接数上限，0 表示不限制；达到上限后不再分配新玩家
+}
+
+// Config 集群配置，Ver 由写入方在每次修改时递增
+type Config struct {
+	List struct {
+		Match    []ServiceNode `json:"match"`
+		Robot    []ServiceNode `json:"robot"`
+		Game     []ServiceNode `json:"game"`
+		Login    []ServiceNode `json:"login"`
+		User     []ServiceNode `json:"user"`
+		Gate     []ServiceNode `json:"gate"`
+		Activity []ServiceNode `json:"activity"`
+		Auth     []ServiceNode `json:"auth"`
+	} `json:"list"`
+	Ver int `json:"ver"`
+}
+
+// Snapshot 某一版本的集群配置快照，创建后不再修改，可以并发读取
+type Snapshot struct {
+	Config   *Config
+	Raw      string    // Redis 中的原始JSON
+	ETag     string    // 由版本号和内容哈希组成，内容不变时保持不变
+	LoadedAt time.Time // 加载时间
+}
+
+// ErrNotFound Redis 中没有集群配置
+var ErrNotFound = errors.New("cluster configuration not found")
+
+var (
+	current atomic.Value // *Snapshot
+	loadMu  sync.Mutex
+)
+
+// Current 返回当前快照；还没有加载过时同步加载一次
+func Current() (*Snapshot, error) {
+	if snap, ok := current.Load().(*Snapshot); ok && snap != nil {
+		return snap, nil
+	}
+	return Reload()
+}
+
+// Reload 从 Redis 重新加载集群配置，内容未变化时沿用原快照
+// 加载失败时保留原快照继续提供服务，只返回错误
+func Reload() (*Snapshot, error) {
+	loadMu.Lock()
+	defer loadMu.Unlock()
+
+	old, _ := current.Load().(*Snapshot)
+	raw, err := ReadRaw()
+	if err == ErrNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return old, err
+	}
+	if old != nil && old.Raw == raw {
+		return old, nil
+	}
+
+	var cfg Config
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		return old, fmt.Errorf("parse cluster configuration: %v", err)
+	}
+	snap := &Snapshot{Config: &cfg, Raw: raw, ETag: makeETag(cfg.Ver, raw), LoadedAt: time.Now()}
+	current.Store(snap)
+
+	if old != nil {
+		log.Infof("集群配置已更新: ver %d -> %d", old.Config.Ver, cfg.Ver)
+	} else {
+		log.Infof("集群配置已加载: ver %d", cfg.Ver)
+	}
+	return snap, nil
+}
+
+// makeETag 版本号加内容哈希，写入方忘记递增版本号时内容变化也会改变 ETag
+func makeETag(ver int, raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return fmt.Sprintf(`"v%d-%s"`, ver, hex.EncodeToString(sum[:8]))
+}
+
+// MatchETag 判断 If-None-Match 请求头是否命中 ETag（支持多个值、弱校验和 *）
+func MatchETag(ifNoneMatch, etag string) bool {
+	for _, v := range strings.Split(ifNoneMatch, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == "*" || v == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// NotifyChanged 写入集群配置后调用，通知所有实例重新加载
+func NotifyChanged() error {
+	return db.PublishRedis(config.AppConfig.Cluster.NotifyChannel, "changed")
+}
+
+// Start 加载集群配置并在后台监听变更；Redis 中暂时没有配置不影响启动
+func Start() {
+	if _, err := Reload(); err != nil {
+		log.Warnf("加载集群配置失败: %v", err)
+	}
+
+	// 键空间通知需要 Redis 开启 notify-keyspace-events（如 K$），未开启时只依赖变更通知频道和定时刷新
+	channels := []string{
+		config.AppConfig.Cluster.NotifyChannel,
+		fmt.Sprintf("__keyspace@%d__:%s", config.AppConfig.Redis.Database, configKey()),
+	}
+	interval := time.Duration(config.AppConfig.Cluster.RefreshInterval) * time.Second
+	go db.WatchChannels("集群配置", channels, interval, func() error {
+		if _, err := Reload(); err != nil && err != ErrNotFound {
+			return err
+		}
+		return nil
+	})
+}
+
+// configKey 集群配置在 Redis 中的键
+func configKey() string {
+	if key := config.AppConfig.Cluster.ConfigKey; key != "" {
+		return key
+	}
+	return "clusterConfig"
+}
@@ -0,0 +1,80 @@
+package cluster
+
+// 节点变更类型
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// FieldChange 字段的旧值和新值
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// NodeChange 一个节点的变更
+type NodeChange struct {
+	List   string                 `json:"list"`
+	Name   string                 `json:"name"`
+	Type   string                 `json:"type"`
+	Fields map[string]FieldChange `json:"fields,omitempty"` // 仅 changed 时有值
+}
+
+// Diff 按节点名称比较两份配置，old 为 nil 时全部视为新增
+func Diff(old, new *Config) []NodeChange {
+	if old == nil {
+		old = &Config{}
+	}
+	oldLists := old.Lists()
+	changes := []NodeChange{}
+	for i, list := range new.Lists() {
+		before := map[string]ServiceNode{}
+		for _, node := range oldLists[i].Nodes {
+			before[node.Name] = node
+		}
+
+		seen := map[string]bool{}
+		for _, node := range list.Nodes {
+			seen[node.Name] = true
+			prev, ok := before[node.Name]
+			if !ok {
+				changes = append(changes, NodeChange{List: list.Name, Name: node.Name, Type: ChangeAdded})
+				continue
+			}
+			if fields := diffNode(prev, node); len(fields) > 0 {
+				changes = append(changes, NodeChange{List: list.Name, Name: node.Name, Type: ChangeChanged, Fields: fields})
+			}
+		}
+		for _, node := range oldLists[i].Nodes {
+			if !seen[node.Name] {
+				changes = append(changes, NodeChange{List: list.Name, Name: node.Name, Type: ChangeRemoved})
+			}
+		}
+	}
+	return changes
+}
+
+// diffNode 比较节点字段
+func diffNode(a, b ServiceNode) map[string]FieldChange {
+	fields := map[string]FieldChange{}
+	if a.Addr != b.Addr {
+		fields["addr"] = FieldChange{a.Addr, b.Addr}
+	}
+	if a.Cnt != b.Cnt {
+		fields["cnt"] = FieldChange{a.Cnt, b.Cnt}
+	}
+	if a.ClientAddr != b.ClientAddr {
+		fields["clientAddr"] = FieldChange{a.ClientAddr, b.ClientAddr}
+	}
+	if a.Hide != b.Hide {
+		fields["hide"] = FieldChange{a.Hide, b.Hide}
+	}
+	if a.Down != b.Down {
+		fields["down"] = FieldChange{a.Down, b.Down}
+	}
+	if a.MaxCnt != b.MaxCnt {
+		fields["maxCnt"] = FieldChange{a.MaxCnt, b.MaxCnt}
+	}
+	return fields
+}
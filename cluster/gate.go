@@ -0,0 +1,80 @@
+package cluster
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// minLoadSlack 负载差的下限，低负载时按百分比计算的差值太小，玩家会在网关之间频繁切换
+const minLoadSlack = 10
+
+// Healthy 节点是否可以分配新玩家：未隐藏、未标记不可用、有客户端地址且未达到连接数上限
+func (n *ServiceNode) Healthy() bool {
+	return !n.Hide && !n.Down && n.ClientAddr != "" && (n.MaxCnt <= 0 || n.Cnt < n.MaxCnt)
+}
+
+// RecommendGates 为用户推荐网关，返回按推荐顺序排列的可用网关，第一个为推荐网关
+// 负载不超过最低负载 slackPercent% 的网关作为候选，候选中按 userid 做一致性哈希（最高随机权重），
+// 同一用户在负载变化不大时固定分配到同一个网关；其余可用网关按负载从低到高排在后面
+func (s *Snapshot) RecommendGates(userid int64, slackPercent int) []ServiceNode {
+	return RecommendGates(s.Config.List.Gate, userid, slackPercent)
+}
+
+// RecommendGates 在指定的网关中为用户推荐网关，规则同 Snapshot.RecommendGates（灰度发布时只在玩家可见的网关中选择）
+func RecommendGates(gates []ServiceNode, userid int64, slackPercent int) []ServiceNode {
+	var healthy []ServiceNode
+	for _, gate := range gates {
+		if gate.Healthy() {
+			healthy = append(healthy, gate)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(healthy, func(i, j int) bool {
+		if healthy[i].Cnt != healthy[j].Cnt {
+			return healthy[i].Cnt < healthy[j].Cnt
+		}
+		return healthy[i].Name < healthy[j].Name
+	})
+
+	slack := healthy[0].Cnt * slackPercent / 100
+	if slack < minLoadSlack {
+		slack = minLoadSlack
+	}
+	limit := healthy[0].Cnt + slack
+
+	best, bestWeight := 0, uint64(0)
+	for i, gate := range healthy {
+		if gate.Cnt > limit {
+			break
+		}
+		if w := UserHash(gate.Name, userid); i == 0 || w > bestWeight {
+			best, bestWeight = i, w
+		}
+	}
+
+	ordered := make([]ServiceNode, 0, len(healthy))
+	ordered = append(ordered, healthy[best])
+	ordered = append(ordered, healthy[:best]...)
+	return append(ordered, healthy[best+1:]...)
+}
+
+// UserHash 用户在指定种子下的稳定哈希，种子不同时互不相关
+// 网关推荐以网关名为种子计算最高随机权重（网关增减时只有分配到该网关的用户会变化），灰度分桶以规则ID为种子
+func UserHash(seed string, userid int64) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(seed))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatInt(userid, 10)))
+	// FNV 对末尾字节的差异扩散不充分，再做一次 splitmix64 混合使权重分布均匀
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,156 @@
+package cluster
+
+import "testing"
+
+func gate(name string, cnt int) ServiceNode {
+	return ServiceNode{Name: name, Addr: name + ":9000", ClientAddr: name + ".example.com:9000", Cnt: cnt}
+}
+
+func names(nodes []ServiceNode) []string {
+	result := make([]string, len(nodes))
+	for i, node := range nodes {
+		result[i] = node.Name
+	}
+	return result
+}
+
+func TestHealthy(t *testing.T) {
+	tests := []struct {
+		name string
+		node ServiceNode
+		want bool
+	}{
+		{"正常", gate("g1", 10), true},
+		{"隐藏", ServiceNode{Name: "g1", ClientAddr: "a", Hide: true}, false},
+		{"不可用", ServiceNode{Name: "g1", ClientAddr: "a", Down: true}, false},
+		{"没有客户端地址", ServiceNode{Name: "g1"}, false},
+		{"未达到上限", ServiceNode{Name: "g1", ClientAddr: "a", Cnt: 99, MaxCnt: 100}, true},
+		{"达到上限", ServiceNode{Name: "g1", ClientAddr: "a", Cnt: 100, MaxCnt: 100}, false},
+		{"上限为0不限制", ServiceNode{Name: "g1", ClientAddr: "a", Cnt: 100000}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.node.Healthy(); got != tt.want {
+				t.Errorf("Healthy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecommendGatesCandidates(t *testing.T) {
+	tests := []struct {
+		name       string
+		gates      []ServiceNode
+		slack      int
+		candidates []string // 可能被推荐的网关
+		rest       []string // 候选之外的网关，按负载排在后面
+	}{
+		{
+			name:  "没有可用网关",
+			gates: []ServiceNode{{Name: "g1", Hide: true}, {Name: "g2", Down: true, ClientAddr: "a"}},
+			slack: 20,
+		},
+		{
+			name:       "低负载时使用最小负载差",
+			gates:      []ServiceNode{gate("g1", 0), gate("g2", 10), gate("g3", 11)},
+			slack:      20,
+			candidates: []string{"g1", "g2"},
+			rest:       []string{"g3"},
+		},
+		{
+			name:       "按最低负载的百分比计算负载差",
+			gates:      []ServiceNode{gate("g3", 1250), gate("g2", 1200), gate("g1", 1000)},
+			slack:      20,
+			candidates: []string{"g1", "g2"},
+			rest:       []string{"g3"},
+		},
+		{
+			name:       "不可用的网关不参与推荐",
+			gates:      []ServiceNode{gate("g1", 0), {Name: "g2", ClientAddr: "a", Down: true}, gate("g3", 500), gate("g4", 100)},
+			slack:      20,
+			candidates: []string{"g1"},
+			rest:       []string{"g4", "g3"},
+		},
+		{
+			name:       "负载相同的网关都是候选",
+			gates:      []ServiceNode{gate("g1", 300), gate("g2", 300), gate("g3", 300)},
+			slack:      0,
+			candidates: []string{"g1", "g2", "g3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chosen := map[string]bool{}
+			for userid := int64(1); userid <= 2000; userid++ {
+				got := RecommendGates(tt.gates, userid, tt.slack)
+				if len(tt.candidates) == 0 {
+					if got != nil {
+						t.Fatalf("RecommendGates() = %v, want nil", names(got))
+					}
+					return
+				}
+				if len(got) != len(tt.candidates)+len(tt.rest) {
+					t.Fatalf("userid=%d: RecommendGates() = %v, 数量错误", userid, names(got))
+				}
+				chosen[got[0].Name] = true
+				tail := names(got[len(tt.candidates):])
+				for i := range tt.rest {
+					if tail[i] != tt.rest[i] {
+						t.Fatalf("userid=%d: 候选之外的网关 = %v, want %v", userid, tail, tt.rest)
+					}
+				}
+			}
+			// 候选中的每个网关都应分到用户，候选之外的网关不会被推荐
+			for _, name := range tt.candidates {
+				if !chosen[name] {
+					t.Errorf("候选网关 %s 没有被推荐给任何用户", name)
+				}
+			}
+			if len(chosen) != len(tt.candidates) {
+				t.Errorf("被推荐的网关 = %v, want %v", chosen, tt.candidates)
+			}
+		})
+	}
+}
+
+func TestRecommendGatesSticky(t *testing.T) {
+	before := []ServiceNode{gate("g1", 100), gate("g2", 105), gate("g3", 108), gate("g4", 500)}
+	// 负载小幅变化、顺序变化：候选不变，推荐结果不变
+	shifted := []ServiceNode{gate("g4", 480), gate("g3", 100), gate("g1", 107), gate("g2", 101)}
+	// 去掉一个候选网关：原来分到其他网关的用户不受影响
+	removed := []ServiceNode{gate("g1", 100), gate("g3", 108), gate("g4", 500)}
+
+	moved := 0
+	for userid := int64(1); userid <= 1000; userid++ {
+		first := RecommendGates(before, userid, 20)[0].Name
+		if got := RecommendGates(before, userid, 20)[0].Name; got != first {
+			t.Fatalf("userid=%d: 同一输入推荐结果不同: %s, %s", userid, first, got)
+		}
+		if got := RecommendGates(shifted, userid, 20)[0].Name; got != first {
+			t.Errorf("userid=%d: 负载小幅变化后推荐从 %s 变为 %s", userid, first, got)
+		}
+		got := RecommendGates(removed, userid, 20)[0].Name
+		if first != "g2" && got != first {
+			t.Errorf("userid=%d: 去掉 g2 后推荐从 %s 变为 %s", userid, first, got)
+		}
+		if first == "g2" {
+			moved++
+		}
+	}
+	if moved == 0 {
+		t.Errorf("没有用户分配到 g2，测试数据无效")
+	}
+}
+
+func TestUserHash(t *testing.T) {
+	if UserHash("g1", 42) != UserHash("g1", 42) {
+		t.Fatal("相同输入的哈希应相同")
+	}
+	if UserHash("g1", 42) == UserHash("g2", 42) {
+		t.Error("不同种子的哈希不应相同")
+	}
+	// 种子和 userid 之间有分隔符，"1"+"23" 与 "12"+"3" 不冲突
+	if UserHash("1", 23) == UserHash("12", 3) {
+		t.Error("种子与 userid 拼接冲突")
+	}
+}
@@ -0,0 +1,148 @@
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gameWeb/db"
+	"gameWeb/log"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrVersionConflict 发布时 Redis 中的版本已经不是编辑时的版本（其他人已发布或手工修改）
+var ErrVersionConflict = errors.New("cluster configuration version conflict")
+
+// publishScript 版本号一致时才写入，避免覆盖其他人的修改；当前值无法解析时版本号视为0
+const publishScript = `
+local cur = redis.call("GET", KEYS[1])
+local curVer = 0
+if cur then
+	local ok, decoded = pcall(cjson.decode, cur)
+	if ok and type(decoded) == "table" and tonumber(decoded.ver) then
+		curVer = tonumber(decoded.ver)
+	end
+end
+if curVer ~= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[2])
+return 1`
+
+// Parse 严格解析集群配置：不允许未知字段，字段类型必须正确
+func Parse(raw []byte) (*Config, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after JSON object")
+	}
+	return &cfg, nil
+}
+
+// Validate 校验集群配置，返回全部问题，为空表示通过
+func Validate(cfg *Config) []string {
+	var problems []string
+	for _, list := range cfg.Lists() {
+		names := map[string]bool{}
+		for i, node := range list.Nodes {
+			where := fmt.Sprintf("list.%s[%d]", list.Name, i)
+			if node.Name == "" {
+				problems = append(problems, where+": name 不能为空")
+			} else if names[node.Name] {
+				problems = append(problems, fmt.Sprintf("%s: name %q 重复", where, node.Name))
+			}
+			names[node.Name] = true
+			if node.Addr == "" {
+				problems = append(problems, where+": addr 不能为空")
+			}
+			if node.Cnt < 0 {
+				problems = append(problems, where+": cnt 不能为负数")
+			}
+			if node.MaxCnt < 0 {
+				problems = append(problems, where+": maxCnt 不能为负数")
+			}
+			// 返回给客户端的节点必须有客户端地址
+			if list.ClientFacing && !node.Hide && node.ClientAddr == "" {
+				problems = append(problems, where+": 未隐藏的节点必须填写 clientAddr")
+			}
+		}
+	}
+	return problems
+}
+
+// NodeList 一类服务节点
+type NodeList struct {
+	Name         string
+	Nodes        []ServiceNode
+	ClientFacing bool // 是否返回给客户端（gate、game、login）
+}
+
+// Lists 按固定顺序返回全部节点列表
+func (c *Config) Lists() []NodeList {
+	return []NodeList{
+		{Name: "match", Nodes: c.List.Match},
+		{Name: "robot", Nodes: c.List.Robot},
+		{Name: "game", Nodes: c.List.Game, ClientFacing: true},
+		{Name: "login", Nodes: c.List.Login, ClientFacing: true},
+		{Name: "user", Nodes: c.List.User},
+		{Name: "gate", Nodes: c.List.Gate, ClientFacing: true},
+		{Name: "activity", Nodes: c.List.Activity},
+		{Name: "auth", Nodes: c.List.Auth},
+	}
+}
+
+// ReadRaw 直接读取 Redis 中的集群配置原文（不经过缓存），不存在时返回 ErrNotFound
+func ReadRaw() (string, error) {
+	raw, err := db.GetRedis(configKey())
+	if err == redis.Nil || (err == nil && raw == "") {
+		return "", ErrNotFound
+	}
+	return raw, err
+}
+
+// RawVersion 从配置原文中读取版本号，无法解析时为0（与发布脚本一致）
+func RawVersion(raw string) int {
+	var v struct {
+		Ver int `json:"ver"`
+	}
+	if err := json.Unmarshal([]byte(raw), &v); err != nil {
+		return 0
+	}
+	return v.Ver
+}
+
+// Encode 以指定版本号序列化集群配置
+func Encode(cfg *Config, ver int) (string, error) {
+	next := *cfg
+	next.Ver = ver
+	data, err := json.Marshal(&next)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Publish 写入集群配置原文，Redis 中的版本不是 baseVer 时返回 ErrVersionConflict
+// 写入成功后通知所有实例重新加载
+func Publish(raw string, baseVer int) error {
+	result, err := db.EvalRedis(publishScript, []string{configKey()}, baseVer, raw)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.(int64); n != 1 {
+		return ErrVersionConflict
+	}
+
+	if err := NotifyChanged(); err != nil {
+		log.Warnf("发送集群配置变更通知失败: %v", err)
+	}
+	if _, err := Reload(); err != nil {
+		log.Warnf("重新加载集群配置失败: %v", err)
+	}
+	return nil
+}
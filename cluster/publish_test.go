@@ -0,0 +1,162 @@
+package cluster
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr bool
+	}{
+		{"合法配置", `{"ver":3,"list":{"gate":[{"name":"g1","addr":"10.0.0.1:9000","clientAddr":"g1.example.com:9000"}]}}`, false},
+		{"未知字段", `{"ver":3,"list":{"gate":[{"name":"g1","addr":"a","port":9000}]}}`, true},
+		{"字段类型错误", `{"ver":"3","list":{}}`, true},
+		{"多余数据", `{"ver":3,"list":{}} {}`, true},
+		{"不是JSON", `ver=3`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.raw))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(cfg *Config)
+		want  []string // 每条问题应包含的内容，为空表示通过
+	}{
+		{
+			name: "合法配置",
+			build: func(cfg *Config) {
+				cfg.List.Gate = []ServiceNode{gate("g1", 0), gate("g2", 0)}
+				cfg.List.Match = []ServiceNode{{Name: "m1", Addr: "10.0.0.1:7000"}}
+				cfg.List.Game = []ServiceNode{{Name: "game1", Addr: "10.0.0.2:8000", Hide: true}}
+			},
+		},
+		{
+			name: "名称为空和重复",
+			build: func(cfg *Config) {
+				cfg.List.Gate = []ServiceNode{gate("g1", 0), gate("g1", 0), {Addr: "a", ClientAddr: "b"}}
+			},
+			want: []string{`list.gate[1]: name "g1" 重复`, "list.gate[2]: name 不能为空"},
+		},
+		{
+			name: "不同列表可以同名",
+			build: func(cfg *Config) {
+				cfg.List.Match = []ServiceNode{{Name: "n1", Addr: "a"}}
+				cfg.List.Robot = []ServiceNode{{Name: "n1", Addr: "b"}}
+			},
+		},
+		{
+			name: "地址为空、负数",
+			build: func(cfg *Config) {
+				cfg.List.User = []ServiceNode{{Name: "u1", Cnt: -1, MaxCnt: -1}}
+			},
+			want: []string{"list.user[0]: addr 不能为空", "list.user[0]: cnt 不能为负数", "list.user[0]: maxCnt 不能为负数"},
+		},
+		{
+			name: "返回给客户端的节点必须有客户端地址",
+			build: func(cfg *Config) {
+				cfg.List.Login = []ServiceNode{{Name: "l1", Addr: "a"}}
+				cfg.List.Auth = []ServiceNode{{Name: "a1", Addr: "a"}}
+			},
+			want: []string{"list.login[0]: 未隐藏的节点必须填写 clientAddr"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{}
+			tt.build(cfg)
+			got := Validate(cfg)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Validate() = %q, want %q", got, tt.want)
+			}
+			for i := range tt.want {
+				if !strings.Contains(got[i], tt.want[i]) {
+					t.Errorf("Validate()[%d] = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	base := func() *Config {
+		cfg := &Config{}
+		cfg.List.Gate = []ServiceNode{gate("g1", 10), gate("g2", 20)}
+		cfg.List.Game = []ServiceNode{{Name: "game1", Addr: "10.0.0.2:8000", ClientAddr: "game1:8000"}}
+		return cfg
+	}
+
+	tests := []struct {
+		name   string
+		old    *Config
+		change func(cfg *Config)
+		want   []NodeChange
+	}{
+		{
+			name:   "没有变化",
+			old:    base(),
+			change: func(cfg *Config) {},
+			want:   []NodeChange{},
+		},
+		{
+			name:   "旧配置为空时全部视为新增",
+			old:    nil,
+			change: func(cfg *Config) {},
+			want: []NodeChange{
+				{List: "game", Name: "game1", Type: ChangeAdded},
+				{List: "gate", Name: "g1", Type: ChangeAdded},
+				{List: "gate", Name: "g2", Type: ChangeAdded},
+			},
+		},
+		{
+			name: "新增、删除和修改",
+			old:  base(),
+			change: func(cfg *Config) {
+				cfg.List.Gate = []ServiceNode{gate("g3", 0), gate("g1", 10)}
+				cfg.List.Gate[1].Down = true
+				cfg.List.Gate[1].MaxCnt = 500
+				cfg.List.Game[0].Addr = "10.0.0.3:8000"
+			},
+			want: []NodeChange{
+				{List: "game", Name: "game1", Type: ChangeChanged, Fields: map[string]FieldChange{
+					"addr": {"10.0.0.2:8000", "10.0.0.3:8000"},
+				}},
+				{List: "gate", Name: "g3", Type: ChangeAdded},
+				{List: "gate", Name: "g1", Type: ChangeChanged, Fields: map[string]FieldChange{
+					"down":   {false, true},
+					"maxCnt": {0, 500},
+				}},
+				{List: "gate", Name: "g2", Type: ChangeRemoved},
+			},
+		},
+		{
+			name: "只调整顺序不算变化",
+			old:  base(),
+			change: func(cfg *Config) {
+				cfg.List.Gate[0], cfg.List.Gate[1] = cfg.List.Gate[1], cfg.List.Gate[0]
+			},
+			want: []NodeChange{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := base()
+			tt.change(next)
+			got := Diff(tt.old, next)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/viper"
 )
@@ -25,6 +26,21 @@ func getEnvIntOrDefault(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvListOrDefault 获取以逗号分隔的环境变量列表，忽略空项
+func getEnvListOrDefault(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 // WechatInfo 微信配置信息
 type WechatInfo struct {
 	ID     int    `mapstructure:"id"`
@@ -32,10 +48,34 @@ type WechatInfo struct {
 	Secret string `mapstructure:"secret"`
 }
 
+// LoginApp 客户端登录应用配置，同一个应用ID可以按登录方式配置多条
+type LoginApp struct {
+	ID       int    `mapstructure:"id"`       // 客户端登录时提交的 appid
+	Provider string `mapstructure:"provider"` // 登录方式：wechatMiniGame、qqMiniGame、douyinMiniGame、guest
+	AppID    string `mapstructure:"appid"`    // 平台应用ID，游客登录不需要
+	Secret   string `mapstructure:"secret"`   // 平台应用密钥，游客登录不需要
+}
+
+// JWTKey JWT签名密钥配置，按 kid 区分，支持 HS256、RS256、EdDSA
+type JWTKey struct {
+	ID             string `mapstructure:"kid"`
+	Algorithm      string `mapstructure:"alg"`
+	Secret         string `mapstructure:"secret"`         // HS256 密钥
+	PrivateKeyFile string `mapstructure:"privateKeyFile"` // RS256/EdDSA 私钥（PEM），只用于验证的旧密钥可以不配置
+	PublicKeyFile  string `mapstructure:"publicKeyFile"`  // RS256/EdDSA 公钥（PEM），未配置时由私钥推导
+}
+
+// OIDCRoleMapping 身份提供方声明值到管理后台角色的映射
+type OIDCRoleMapping struct {
+	Value string   `mapstructure:"value"` // 角色声明中的值，如IdP中的用户组名
+	Roles []string `mapstructure:"roles"` // 对应的 adminRole 名称
+}
+
 // AppConfig 应用配置结构体
 var AppConfig struct {
 	Server struct {
-		Port string
+		Port           string
+		TrustedProxies []string // 可信反向代理的IP或CIDR，只采信来自这些地址的 X-Forwarded-For，为空时不信任任何代理
 	}
 	MySQL struct {
 		Host     string
@@ -77,16 +117,103 @@ var AppConfig struct {
 	}
 	// 客户端JWT配置
 	JWT struct {
-		SecretKey  string
-		ExpireTime int64 // 过期时间，单位：秒
+		SecretKey           string
+		ExpireTime          int64    // 过期时间，单位：秒
+		RefreshExpireTime   int64    // 刷新令牌有效期，单位：秒，每次刷新重新计算
+		Keys                []JWTKey // 密钥环，为空时只使用 SecretKey
+		SigningKeyID        string   // 签发新令牌使用的 kid，为空时使用 SecretKey
+		DisableLegacySecret bool     // 不再接受未携带 kid 的令牌（SecretKey 退役后开启）
+	}
+	// 客户端认证方案配置，方案名称：jwt、des
+	Auth struct {
+		ClientSchemes []string            // 默认认证方案及尝试顺序
+		Groups        map[string][]string // 按路由组覆盖认证方案，如 mail: [jwt, des]
 	}
 	// 管理后台JWT配置
 	Admin struct {
-		JWTSecretKey     string
-		TokenExpireHours int
-		SessionTimeout   int
-		MaxLoginAttempts int
-		LockoutDuration  int
+		JWTSecretKey               string
+		TokenExpireHours           int
+		SessionTimeout             int
+		MaxLoginAttempts           int
+		MaxIPLoginAttempts         int // 同一IP最大登录失败次数
+		LockoutDuration            int
+		TwoFactorIssuer            string   // 两步验证显示的发行方名称
+		TwoFactorSecretKey         string   // 加密保存TOTP密钥使用的服务端密钥，修改后已绑定的两步验证需要重置
+		PasswordResetExpireMinutes int      // 密码重置令牌有效期（分钟）
+		PasswordResetURL           string   // 密码重置页面地址，令牌以token参数附加
+		IPBindingMode              string   // 登录IP变更时的处理方式：warn、reject、reauth
+		JWTKeys                    []JWTKey // 管理后台JWT密钥环，为空时只使用 JWTSecretKey
+		JWTSigningKeyID            string   // 签发管理后台令牌使用的 kid
+		JWTDisableLegacySecret     bool     // 不再接受未携带 kid 的管理后台令牌
+	}
+	// 管理后台单点登录（OIDC 授权码 + PKCE）
+	OIDC struct {
+		Enabled              bool
+		Issuer               string
+		ClientID             string
+		ClientSecret         string
+		RedirectURL          string   // 管理后台前端的回调页面，需要在身份提供方登记
+		Scopes               []string // 默认 openid profile email
+		UsernameClaim        string   // 用户名声明，默认 preferred_username
+		RolesClaim           string   // 角色声明，默认 groups
+		RoleMappings         []OIDCRoleMapping
+		SuperAdminValues     []string // 角色声明包含其中任意值时为超级管理员
+		AutoProvision        bool     // 首次登录时自动创建管理员账户
+		LinkByEmail          bool     // 首次登录时按已验证邮箱关联已有管理员账户
+		DisablePasswordLogin bool     // 禁止非超级管理员使用本地密码登录
+	}
+	// 通知通道配置（密码重置邮件等）
+	Notifier struct {
+		Driver string // smtp 或 log（默认，只写日志）
+		SMTP   struct {
+			Host     string
+			Port     string
+			Username string
+			Password string
+			From     string
+		}
+	}
+	// 微信服务端接口配置
+	Wechat struct {
+		BaseURL string // 接口地址，默认 https://api.weixin.qq.com，测试时可以指向本地桩服务
+		Timeout int    // 单次请求超时（秒）
+		Retries int    // 网络错误、5xx 和系统繁忙时的重试次数
+	}
+	// QQ小游戏服务端接口
+	QQ struct {
+		BaseURL string // 接口地址，默认 https://api.q.qq.com，测试时可以指向本地桩服务
+	}
+	// 抖音小游戏服务端接口
+	Douyin struct {
+		BaseURL string // 接口地址，默认 https://developer.toutiao.com，测试时可以指向本地桩服务
+	}
+	// 集群配置（clusterConfig）缓存
+	Cluster struct {
+		ConfigKey       string // 集群配置在Redis中的键
+		NotifyChannel   string // 集群配置变更通知频道，写入配置后向该频道发布消息
+		RefreshInterval int    // 定时刷新间隔（秒），通知丢失时的兜底
+		GateLoadSlack   int    // 推荐网关时允许的负载差（百分比），负载不超过最低负载这么多的网关都可以按用户固定分配
+	}
+	// 玩家账号注销配置
+	AccountDeletion struct {
+		CoolingOffDays   int // 冷静期（天），申请后至少经过这么久才会执行，期间玩家可以撤销
+		ExecutorInterval int // 执行器扫描间隔（秒），0 表示本实例不运行执行器
+		BatchSize        int // 日志表分批处理的行数
+	}
+	// 停服维护
+	Maintenance struct {
+		NotifyChannel   string // 维护窗口和放行名单变更通知频道
+		RefreshInterval int    // 定时刷新间隔（秒），通知丢失时的兜底
+	}
+	// 客户端版本策略
+	ClientVersion struct {
+		NotifyChannel   string // 版本策略变更通知频道
+		RefreshInterval int    // 定时刷新间隔（秒），通知丢失时的兜底
+	}
+	// 灰度发布规则
+	GrayRelease struct {
+		NotifyChannel   string // 灰度规则变更通知频道
+		RefreshInterval int    // 定时刷新间隔（秒），通知丢失时的兜底
 	}
 	// 添加GameServer配置
 	GameServer struct {
@@ -95,6 +222,8 @@ var AppConfig struct {
 	}
 	// 添加WechatInfo配置
 	WechatInfos []WechatInfo `mapstructure:"wechatInfo"`
+	// 客户端登录应用，按登录方式开启；wechatInfo 中的应用视为开启了微信小游戏登录
+	LoginApps []LoginApp `mapstructure:"loginApps"`
 }
 
 // InitConfig 初始化配置
@@ -106,6 +235,7 @@ func InitConfig() {
 
 	// 设置默认值（优先使用环境变量）
 	viper.SetDefault("Server.Port", getEnvOrDefault("SERVER_PORT", "8080"))
+	viper.SetDefault("Server.TrustedProxies", getEnvListOrDefault("SERVER_TRUSTED_PROXIES", []string{})) // 默认直接使用连接地址
 	viper.SetDefault("MySQL.Host", getEnvOrDefault("MYSQL_HOST", "localhost"))
 	viper.SetDefault("MySQL.Port", getEnvOrDefault("MYSQL_PORT", "3306"))
 	viper.SetDefault("MySQL.Username", getEnvOrDefault("MYSQL_USER", "root"))
@@ -137,13 +267,61 @@ func InitConfig() {
 	viper.SetDefault("Log.DateFormat", "2006-01-02") // 添加默认日期格式
 	// 添加客户端JWT默认值
 	viper.SetDefault("JWT.SecretKey", getEnvOrDefault("JWT_SECRET", "GameWebJWTSecretKey1234567890ABCDEF"))
-	viper.SetDefault("JWT.ExpireTime", getEnvIntOrDefault("JWT_EXPIRE_TIME", 3600)) // 默认1小时过期
+	viper.SetDefault("JWT.ExpireTime", getEnvIntOrDefault("JWT_EXPIRE_TIME", 3600))                   // 默认1小时过期
+	viper.SetDefault("JWT.RefreshExpireTime", getEnvIntOrDefault("JWT_REFRESH_EXPIRE_TIME", 2592000)) // 刷新令牌默认30天
+	// 客户端认证方案默认值，多个方案用逗号分隔
+	viper.SetDefault("Auth.ClientSchemes", strings.Split(getEnvOrDefault("AUTH_CLIENT_SCHEMES", "jwt"), ","))
 	// 添加管理后台JWT默认值
 	viper.SetDefault("Admin.JWTSecretKey", getEnvOrDefault("ADMIN_JWT_SECRET", "GameWebAdminJWTSecretKey987654321FEDCBA"))
-	viper.SetDefault("Admin.TokenExpireHours", getEnvIntOrDefault("ADMIN_TOKEN_EXPIRE_HOURS", 8)) // 8小时过期
-	viper.SetDefault("Admin.SessionTimeout", getEnvIntOrDefault("ADMIN_SESSION_TIMEOUT", 24))     // Redis会话24小时过期
-	viper.SetDefault("Admin.MaxLoginAttempts", getEnvIntOrDefault("ADMIN_MAX_LOGIN_ATTEMPTS", 5)) // 最大登录尝试次数
-	viper.SetDefault("Admin.LockoutDuration", getEnvIntOrDefault("ADMIN_LOCKOUT_DURATION", 30))   // 锁定时间（分钟）
+	viper.SetDefault("Admin.TokenExpireHours", getEnvIntOrDefault("ADMIN_TOKEN_EXPIRE_HOURS", 8))       // 8小时过期
+	viper.SetDefault("Admin.SessionTimeout", getEnvIntOrDefault("ADMIN_SESSION_TIMEOUT", 24))           // Redis会话24小时过期
+	viper.SetDefault("Admin.MaxLoginAttempts", getEnvIntOrDefault("ADMIN_MAX_LOGIN_ATTEMPTS", 5))       // 最大登录尝试次数
+	viper.SetDefault("Admin.MaxIPLoginAttempts", getEnvIntOrDefault("ADMIN_MAX_IP_LOGIN_ATTEMPTS", 20)) // 同一IP最大登录失败次数
+	viper.SetDefault("Admin.LockoutDuration", getEnvIntOrDefault("ADMIN_LOCKOUT_DURATION", 30))         // 锁定时间（分钟）
+	viper.SetDefault("Admin.TwoFactorIssuer", getEnvOrDefault("ADMIN_TWO_FACTOR_ISSUER", "gameWeb"))
+	viper.SetDefault("Admin.TwoFactorSecretKey", getEnvOrDefault("ADMIN_TWO_FACTOR_SECRET_KEY", "GameWebAdminTwoFactorKey13579BDF"))
+	viper.SetDefault("Admin.PasswordResetExpireMinutes", getEnvIntOrDefault("ADMIN_PASSWORD_RESET_EXPIRE_MINUTES", 30))
+	viper.SetDefault("Admin.PasswordResetURL", getEnvOrDefault("ADMIN_PASSWORD_RESET_URL", ""))
+	viper.SetDefault("Admin.IPBindingMode", getEnvOrDefault("ADMIN_IP_BINDING_MODE", "warn"))
+	// 添加单点登录默认值
+	viper.SetDefault("OIDC.Enabled", getEnvOrDefault("OIDC_ENABLED", "false") == "true")
+	viper.SetDefault("OIDC.Issuer", getEnvOrDefault("OIDC_ISSUER", ""))
+	viper.SetDefault("OIDC.ClientID", getEnvOrDefault("OIDC_CLIENT_ID", ""))
+	viper.SetDefault("OIDC.ClientSecret", getEnvOrDefault("OIDC_CLIENT_SECRET", ""))
+	viper.SetDefault("OIDC.RedirectURL", getEnvOrDefault("OIDC_REDIRECT_URL", ""))
+	viper.SetDefault("OIDC.UsernameClaim", "preferred_username")
+	viper.SetDefault("OIDC.RolesClaim", "groups")
+	// 添加通知通道默认值
+	viper.SetDefault("Notifier.Driver", getEnvOrDefault("NOTIFIER_DRIVER", "log"))
+	viper.SetDefault("Notifier.SMTP.Host", getEnvOrDefault("SMTP_HOST", ""))
+	viper.SetDefault("Notifier.SMTP.Port", getEnvOrDefault("SMTP_PORT", "587"))
+	viper.SetDefault("Notifier.SMTP.Username", getEnvOrDefault("SMTP_USERNAME", ""))
+	viper.SetDefault("Notifier.SMTP.Password", getEnvOrDefault("SMTP_PASSWORD", ""))
+	viper.SetDefault("Notifier.SMTP.From", getEnvOrDefault("SMTP_FROM", ""))
+	// 添加微信接口默认值
+	viper.SetDefault("Wechat.BaseURL", getEnvOrDefault("WECHAT_BASE_URL", "https://api.weixin.qq.com"))
+	viper.SetDefault("Wechat.Timeout", getEnvIntOrDefault("WECHAT_TIMEOUT", 5))
+	viper.SetDefault("Wechat.Retries", getEnvIntOrDefault("WECHAT_RETRIES", 2))
+	viper.SetDefault("QQ.BaseURL", getEnvOrDefault("QQ_BASE_URL", "https://api.q.qq.com"))
+	viper.SetDefault("Douyin.BaseURL", getEnvOrDefault("DOUYIN_BASE_URL", "https://developer.toutiao.com"))
+	// 添加集群配置缓存默认值
+	viper.SetDefault("Cluster.ConfigKey", getEnvOrDefault("CLUSTER_CONFIG_KEY", "clusterConfig"))
+	viper.SetDefault("Cluster.NotifyChannel", getEnvOrDefault("CLUSTER_NOTIFY_CHANNEL", "clusterConfig:changed"))
+	viper.SetDefault("Cluster.RefreshInterval", getEnvIntOrDefault("CLUSTER_REFRESH_INTERVAL", 30))
+	viper.SetDefault("Cluster.GateLoadSlack", getEnvIntOrDefault("CLUSTER_GATE_LOAD_SLACK", 20))
+	// 添加账号注销默认值
+	viper.SetDefault("AccountDeletion.CoolingOffDays", getEnvIntOrDefault("ACCOUNT_DELETION_COOLING_OFF_DAYS", 7))
+	viper.SetDefault("AccountDeletion.ExecutorInterval", getEnvIntOrDefault("ACCOUNT_DELETION_EXECUTOR_INTERVAL", 60))
+	viper.SetDefault("AccountDeletion.BatchSize", getEnvIntOrDefault("ACCOUNT_DELETION_BATCH_SIZE", 1000))
+	// 添加停服维护默认值
+	viper.SetDefault("Maintenance.NotifyChannel", getEnvOrDefault("MAINTENANCE_NOTIFY_CHANNEL", "maintenance:changed"))
+	viper.SetDefault("Maintenance.RefreshInterval", getEnvIntOrDefault("MAINTENANCE_REFRESH_INTERVAL", 30))
+	// 添加客户端版本策略默认值
+	viper.SetDefault("ClientVersion.NotifyChannel", getEnvOrDefault("CLIENT_VERSION_NOTIFY_CHANNEL", "clientVersion:changed"))
+	viper.SetDefault("ClientVersion.RefreshInterval", getEnvIntOrDefault("CLIENT_VERSION_REFRESH_INTERVAL", 30))
+	// 添加灰度发布默认值
+	viper.SetDefault("GrayRelease.NotifyChannel", getEnvOrDefault("GRAY_RELEASE_NOTIFY_CHANNEL", "grayRelease:changed"))
+	viper.SetDefault("GrayRelease.RefreshInterval", getEnvIntOrDefault("GRAY_RELEASE_REFRESH_INTERVAL", 30))
 	// 添加GameServer默认值
 	viper.SetDefault("GameServer.Host", getEnvOrDefault("GAMESERVER_HOST", "localhost"))
 	viper.SetDefault("GameServer.Port", getEnvOrDefault("GAMESERVER_PORT", "9000"))
@@ -14,6 +14,11 @@ var MySQLDB *sql.DB
 var MySQLDBGameWeb *sql.DB // gameWeb数据库连接（管理员数据）
 var MySQLDBGameLog *sql.DB // gamelog数据库连接（日志数据）
 
+// Scanner 兼容 *sql.Row 和 *sql.Rows，用于编写同时读取单行和多行的扫描函数
+type Scanner interface {
+	Scan(dest ...interface{}) error
+}
+
 // InitMySQL 初始化MySQL连接（game库 - 用户游戏数据）
 func InitMySQL() {
 	cfg := config.AppConfig.MySQL
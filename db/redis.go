@@ -68,3 +68,110 @@ func ExistsRedis(key string) (bool, error) {
 func ExpireRedis(key string, expiration time.Duration) error {
 	return RedisClient.Expire(ctx, key, expiration).Err()
 }
+
+// IncrRedis 对Redis键进行自增
+func IncrRedis(key string) (int64, error) {
+	return RedisClient.Incr(ctx, key).Result()
+}
+
+// TTLRedis 获取Redis键的剩余过期时间
+func TTLRedis(key string) (time.Duration, error) {
+	return RedisClient.TTL(ctx, key).Result()
+}
+
+// ScanRedisKeys 按模式扫描Redis键（使用SCAN，避免KEYS阻塞）
+func ScanRedisKeys(pattern string) ([]string, error) {
+	var keys []string
+	var cursor uint64
+	for {
+		batch, next, err := RedisClient.Scan(ctx, cursor, pattern, 100).Result()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, batch...)
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+	return keys, nil
+}
+
+// HSetRedis 设置哈希表字段（values为字段、值交替或map）
+func HSetRedis(key string, values ...interface{}) error {
+	return RedisClient.HSet(ctx, key, values...).Err()
+}
+
+// SAddRedis 向集合添加成员
+func SAddRedis(key string, members ...interface{}) error {
+	return RedisClient.SAdd(ctx, key, members...).Err()
+}
+
+// SRemRedis 从集合删除成员
+func SRemRedis(key string, members ...interface{}) error {
+	return RedisClient.SRem(ctx, key, members...).Err()
+}
+
+// SMembersRedis 获取集合全部成员
+func SMembersRedis(key string) ([]string, error) {
+	return RedisClient.SMembers(ctx, key).Result()
+}
+
+// EvalRedis 执行Lua脚本，用于需要原子完成的读-改-写操作
+func EvalRedis(script string, keys []string, args ...interface{}) (interface{}, error) {
+	return RedisClient.Eval(ctx, script, keys, args...).Result()
+}
+
+// SetNXRedis 键不存在时设置并返回true，用于简单的分布式锁
+func SetNXRedis(key string, value interface{}, expiration time.Duration) (bool, error) {
+	return RedisClient.SetNX(ctx, key, value, expiration).Result()
+}
+
+// PublishRedis 向频道发布消息
+func PublishRedis(channel string, message interface{}) error {
+	return RedisClient.Publish(ctx, channel, message).Err()
+}
+
+// SubscribeRedis 订阅频道，调用方负责关闭返回的订阅
+func SubscribeRedis(channels ...string) *redis.PubSub {
+	return RedisClient.Subscribe(ctx, channels...)
+}
+
+// defaultWatchInterval 未配置刷新间隔时的默认值
+const defaultWatchInterval = 30 * time.Second
+
+// WatchChannels 订阅变更通知频道，收到通知时调用 reload，并按 interval 定时调用，通知丢失时兜底。
+// 连接断开后客户端会自动重连并重新订阅，每次订阅成功时同样调用 reload，补上未订阅期间的变更。
+// name 用于日志；函数不会返回，调用方在单独的 goroutine 中运行
+func WatchChannels(name string, channels []string, interval time.Duration, reload func() error) {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	pubsub := SubscribeRedis(channels...)
+	defer pubsub.Close()
+	messages := pubsub.ChannelWithSubscriptions(ctx, 100)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case msg := <-messages:
+			switch m := msg.(type) {
+			case *redis.Subscription:
+				if m.Kind != "subscribe" {
+					continue
+				}
+				log.Infof("已订阅%s变更通知: channel=%s", name, m.Channel)
+			case *redis.Message:
+				log.Debugf("收到%s变更通知: channel=%s, payload=%s", name, m.Channel, m.Payload)
+			}
+			if err := reload(); err != nil {
+				log.Errorf("重新加载%s失败: %v", name, err)
+			}
+		case <-ticker.C:
+			if err := reload(); err != nil {
+				log.Errorf("定时刷新%s失败: %v", name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,269 @@
+// Package grayrelease 灰度发布：按规则把部分玩家（指定 userid，或按登录方式、客户端版本和比例）
+// 分配到集群配置中的灰度网关和游戏节点。规则保存在 gameWeb 库，各实例在内存中缓存，
+// 通过变更通知频道和定时刷新更新。被任意启用规则引用的节点视为灰度节点，未命中规则的玩家看不到。
+package grayrelease
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"gameWeb/clientversion"
+	"gameWeb/cluster"
+	"gameWeb/config"
+	"gameWeb/db"
+	"gameWeb/log"
+	"gameWeb/models"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// state 某一时刻加载的启用规则，创建后不再修改
+type state struct {
+	rules       []models.GrayReleaseRule // 按优先级排序
+	canaryGates map[string]bool
+	canaryGames map[string]bool
+	version     string // 规则内容的哈希，规则变化时改变，用于 ETag
+}
+
+var (
+	current atomic.Value // *state
+	loadMu  sync.Mutex
+)
+
+// Routing 玩家的灰度分配结果
+type Routing struct {
+	Rule  *models.GrayReleaseRule // 命中的规则，未命中时为 nil
+	Gates []cluster.ServiceNode   // 玩家可见的网关
+	Games []cluster.ServiceNode   // 玩家可见的游戏节点
+}
+
+// Start 加载灰度规则并在后台监听变更；加载失败不影响启动，此时不做灰度分流
+func Start() {
+	if err := Reload(); err != nil {
+		log.Errorf("加载灰度发布规则失败: %v", err)
+	}
+
+	interval := time.Duration(config.AppConfig.GrayRelease.RefreshInterval) * time.Second
+	go db.WatchChannels("灰度发布规则", []string{config.AppConfig.GrayRelease.NotifyChannel}, interval, Reload)
+}
+
+// Reload 从数据库重新加载启用的灰度规则，失败时保留原数据
+func Reload() error {
+	loadMu.Lock()
+	defer loadMu.Unlock()
+
+	rules, err := LoadRules(true)
+	if err != nil {
+		return err
+	}
+
+	next := &state{rules: rules, canaryGates: map[string]bool{}, canaryGames: map[string]bool{}}
+	h := sha256.New()
+	for _, rule := range rules {
+		for _, name := range rule.GateNodes {
+			next.canaryGates[name] = true
+		}
+		for _, name := range rule.GameNodes {
+			next.canaryGames[name] = true
+		}
+		// 按内容计算，同一秒内的多次修改也会改变版本
+		data, _ := json.Marshal(rule)
+		h.Write(data)
+	}
+	if len(rules) > 0 {
+		next.version = hex.EncodeToString(h.Sum(nil)[:4])
+	}
+	current.Store(next)
+	return nil
+}
+
+// NotifyChanged 修改灰度规则后调用，通知所有实例重新加载
+func NotifyChanged() error {
+	return db.PublishRedis(config.AppConfig.GrayRelease.NotifyChannel, "changed")
+}
+
+// Version 当前规则的版本，没有启用的规则时为空
+func Version() string {
+	s, _ := current.Load().(*state)
+	if s == nil {
+		return ""
+	}
+	return s.version
+}
+
+// Route 按规则计算玩家可见的网关和游戏节点
+// 未命中规则的玩家只能看到正式节点；命中规则的玩家看到规则分配的节点，
+// 规则没有指定某类节点，或指定的节点都已不在集群配置中（网关为都不可用）时，使用正式节点
+func Route(cfg *cluster.Config, userid int64, channel, version string) *Routing {
+	s, _ := current.Load().(*state)
+	if s == nil {
+		s = &state{}
+	}
+
+	r := &Routing{
+		Gates: exclude(cfg.List.Gate, s.canaryGates),
+		Games: exclude(cfg.List.Game, s.canaryGames),
+	}
+	for i := range s.rules {
+		if Match(&s.rules[i], userid, channel, version) {
+			r.Rule = &s.rules[i]
+			break
+		}
+	}
+	if r.Rule == nil {
+		return r
+	}
+
+	if gates := pick(cfg.List.Gate, r.Rule.GateNodes); anyHealthy(gates) {
+		r.Gates = gates
+	}
+	if games := pick(cfg.List.Game, r.Rule.GameNodes); len(games) > 0 {
+		r.Games = games
+	}
+	return r
+}
+
+// Match 判断玩家是否命中规则
+// 指定用户命中时不再判断其他条件；否则登录方式、版本范围、分流比例中已配置的条件都要满足，
+// 三者都没有配置的规则只对指定用户生效
+func Match(rule *models.GrayReleaseRule, userid int64, channel, version string) bool {
+	for _, id := range rule.Userids {
+		if id == userid {
+			return true
+		}
+	}
+	if len(rule.Channels) == 0 && rule.MinVersion == "" && rule.MaxVersion == "" && rule.Percentage == nil {
+		return false
+	}
+
+	if len(rule.Channels) > 0 {
+		found := false
+		for _, c := range rule.Channels {
+			if c == channel {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if rule.MinVersion != "" || rule.MaxVersion != "" {
+		// 没有带版本号或版本号无法解析时不命中版本条件
+		if _, err := clientversion.Parse(version); err != nil {
+			return false
+		}
+		if rule.MinVersion != "" && clientversion.Compare(version, rule.MinVersion) < 0 {
+			return false
+		}
+		if rule.MaxVersion != "" && clientversion.Compare(version, rule.MaxVersion) > 0 {
+			return false
+		}
+	}
+	if rule.Percentage != nil && Bucket(rule.ID, userid) >= *rule.Percentage {
+		return false
+	}
+	return true
+}
+
+// Bucket 玩家在规则中的分桶（0-99），以规则ID为种子，同一玩家在同一规则中固定不变；
+// 调大比例时原来命中的玩家仍然命中，不同规则之间的分桶互不相关
+func Bucket(ruleID uint64, userid int64) int {
+	return int(cluster.UserHash(strconv.FormatUint(ruleID, 10), userid) % 100)
+}
+
+// exclude 去掉灰度节点
+func exclude(nodes []cluster.ServiceNode, canary map[string]bool) []cluster.ServiceNode {
+	result := make([]cluster.ServiceNode, 0, len(nodes))
+	for _, node := range nodes {
+		if !canary[node.Name] {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+// pick 按名称选出节点，保持集群配置中的顺序
+func pick(nodes []cluster.ServiceNode, names []string) []cluster.ServiceNode {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+	var result []cluster.ServiceNode
+	for _, node := range nodes {
+		if wanted[node.Name] {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+// anyHealthy 是否有可以分配新玩家的节点
+func anyHealthy(nodes []cluster.ServiceNode) bool {
+	for i := range nodes {
+		if nodes[i].Healthy() {
+			return true
+		}
+	}
+	return false
+}
+
+// 数据库操作函数
+
+// RuleColumns 灰度规则查询字段，与 ScanRule 的顺序一致
+const RuleColumns = `id, name, priority, enabled, COALESCE(userids, ''), COALESCE(channels, ''),
+	COALESCE(minVersion, ''), COALESCE(maxVersion, ''), percentage, COALESCE(gateNodes, ''), COALESCE(gameNodes, ''),
+	COALESCE(comment, ''), updatedBy, updatedByName, createdTime, updatedTime`
+
+// ScanRule 读取一行灰度规则（字段见 RuleColumns）
+func ScanRule(row db.Scanner) (models.GrayReleaseRule, error) {
+	var r models.GrayReleaseRule
+	var userids, channels, gateNodes, gameNodes string
+	err := row.Scan(&r.ID, &r.Name, &r.Priority, &r.Enabled, &userids, &channels,
+		&r.MinVersion, &r.MaxVersion, &r.Percentage, &gateNodes, &gameNodes,
+		&r.Comment, &r.UpdatedBy, &r.UpdatedByName, &r.CreatedTime, &r.UpdatedTime)
+	if err != nil {
+		return r, err
+	}
+	r.Userids = []int64{}
+	r.Channels = []string{}
+	r.GateNodes = []string{}
+	r.GameNodes = []string{}
+	for _, field := range []struct {
+		raw  string
+		dest interface{}
+	}{{userids, &r.Userids}, {channels, &r.Channels}, {gateNodes, &r.GateNodes}, {gameNodes, &r.GameNodes}} {
+		if field.raw == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(field.raw), field.dest); err != nil {
+			log.Errorf("灰度规则字段格式错误: id=%d, err=%v", r.ID, err)
+		}
+	}
+	return r, nil
+}
+
+// LoadRules 按优先级查询灰度规则，enabledOnly 为 true 时只查询启用的规则
+func LoadRules(enabledOnly bool) ([]models.GrayReleaseRule, error) {
+	query := "SELECT " + RuleColumns + " FROM grayReleaseRule"
+	if enabledOnly {
+		query += " WHERE enabled = 1"
+	}
+	rows, err := db.MySQLDBGameWeb.Query(query + " ORDER BY priority ASC, id ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []models.GrayReleaseRule{}
+	for rows.Next() {
+		r, err := ScanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
@@ -0,0 +1,159 @@
+package grayrelease
+
+import (
+	"gameWeb/cluster"
+	"gameWeb/models"
+	"reflect"
+	"testing"
+)
+
+func percent(p int) *int {
+	return &p
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    models.GrayReleaseRule
+		userid  int64
+		channel string
+		version string
+		want    bool
+	}{
+		{"指定用户", models.GrayReleaseRule{Userids: []int64{7}}, 7, "guest", "", true},
+		{"只有指定用户的规则不命中其他用户", models.GrayReleaseRule{Userids: []int64{7}}, 8, "guest", "1.0", false},
+		{"没有任何条件", models.GrayReleaseRule{}, 8, "guest", "1.0", false},
+		{"指定用户不受其他条件限制", models.GrayReleaseRule{Userids: []int64{7}, Channels: []string{"wechatMiniGame"}, MinVersion: "9.0", Percentage: percent(0)}, 7, "guest", "", true},
+		{"登录方式命中", models.GrayReleaseRule{Channels: []string{"guest", "wechatMiniGame"}}, 8, "wechatMiniGame", "", true},
+		{"登录方式不命中", models.GrayReleaseRule{Channels: []string{"guest"}}, 8, "wechatMiniGame", "", false},
+		{"版本范围内", models.GrayReleaseRule{MinVersion: "1.2", MaxVersion: "1.10"}, 8, "guest", "1.9.5", true},
+		{"版本边界", models.GrayReleaseRule{MinVersion: "1.2", MaxVersion: "1.10"}, 8, "guest", "v1.10.0", true},
+		{"低于最低版本", models.GrayReleaseRule{MinVersion: "1.2"}, 8, "guest", "1.1.9", false},
+		{"高于最高版本", models.GrayReleaseRule{MaxVersion: "1.10"}, 8, "guest", "1.10.1", false},
+		{"没有带版本号", models.GrayReleaseRule{MaxVersion: "1.10"}, 8, "guest", "", false},
+		{"版本号无法解析", models.GrayReleaseRule{MaxVersion: "1.10"}, 8, "guest", "dev", false},
+		{"比例为100", models.GrayReleaseRule{Percentage: percent(100)}, 8, "guest", "", true},
+		{"比例为0", models.GrayReleaseRule{Percentage: percent(0)}, 8, "guest", "", false},
+		{"条件都满足", models.GrayReleaseRule{Channels: []string{"guest"}, MinVersion: "1.0", Percentage: percent(100)}, 8, "guest", "1.0", true},
+		{"任一条件不满足", models.GrayReleaseRule{Channels: []string{"guest"}, MinVersion: "1.0", Percentage: percent(100)}, 8, "wechatMiniGame", "1.0", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Match(&tt.rule, tt.userid, tt.channel, tt.version); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBucket(t *testing.T) {
+	counts := make([]int, 100)
+	for userid := int64(1); userid <= 10000; userid++ {
+		b := Bucket(1, userid)
+		if b < 0 || b >= 100 {
+			t.Fatalf("Bucket(1, %d) = %d, 超出范围", userid, b)
+		}
+		if Bucket(1, userid) != b {
+			t.Fatalf("Bucket(1, %d) 结果不固定", userid)
+		}
+		counts[b]++
+	}
+	// 平均每个分桶 100 人，允许较大偏差
+	for b, n := range counts {
+		if n < 50 || n > 150 {
+			t.Errorf("分桶 %d 有 %d 人，分布不均匀", b, n)
+		}
+	}
+
+	same := 0
+	for userid := int64(1); userid <= 1000; userid++ {
+		if Bucket(1, userid) == Bucket(2, userid) {
+			same++
+		}
+	}
+	if same > 50 {
+		t.Errorf("不同规则中 %d/1000 个玩家分桶相同，规则之间不应相关", same)
+	}
+}
+
+func TestMatchPercentageMonotonic(t *testing.T) {
+	rule := models.GrayReleaseRule{ID: 42}
+	matched := map[int64]bool{}
+	for _, p := range []int{0, 1, 5, 10, 30, 50, 99, 100} {
+		rule.Percentage = percent(p)
+		n := 0
+		for userid := int64(1); userid <= 5000; userid++ {
+			hit := Match(&rule, userid, "guest", "")
+			if matched[userid] && !hit {
+				t.Fatalf("比例调到 %d%% 后 userid=%d 不再命中", p, userid)
+			}
+			if hit {
+				matched[userid] = true
+				n++
+			}
+		}
+		if p == 0 && n != 0 || p == 100 && n != 5000 {
+			t.Errorf("比例 %d%% 命中 %d 人", p, n)
+		}
+	}
+}
+
+func TestRoute(t *testing.T) {
+	cfg := &cluster.Config{}
+	cfg.List.Gate = []cluster.ServiceNode{
+		{Name: "g1", ClientAddr: "g1:9000"},
+		{Name: "canary1", ClientAddr: "c1:9000"},
+		{Name: "canary2", ClientAddr: "c2:9000", Down: true},
+	}
+	cfg.List.Game = []cluster.ServiceNode{{Name: "game1"}, {Name: "canaryGame"}}
+
+	current.Store(&state{
+		rules: []models.GrayReleaseRule{
+			{ID: 1, Userids: []int64{1}, GateNodes: []string{"canary1"}, GameNodes: []string{"canaryGame"}},
+			{ID: 2, Userids: []int64{1, 2}, GateNodes: []string{"canary2"}},
+			{ID: 3, Userids: []int64{3}, GateNodes: []string{"removed"}, GameNodes: []string{"removed"}},
+		},
+		canaryGates: map[string]bool{"canary1": true, "canary2": true, "removed": true},
+		canaryGames: map[string]bool{"canaryGame": true, "removed": true},
+	})
+	defer current.Store(&state{})
+
+	tests := []struct {
+		name      string
+		userid    int64
+		wantRule  uint64
+		wantGates []string
+		wantGames []string
+	}{
+		{"未命中规则只看到正式节点", 9, 0, []string{"g1"}, []string{"game1"}},
+		{"按优先级命中第一条规则", 1, 1, []string{"canary1"}, []string{"canaryGame"}},
+		{"灰度网关都不可用时使用正式网关", 2, 2, []string{"g1"}, []string{"game1"}},
+		{"灰度节点已不在集群配置中", 3, 3, []string{"g1"}, []string{"game1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Route(cfg, tt.userid, "guest", "1.0")
+			var ruleID uint64
+			if r.Rule != nil {
+				ruleID = r.Rule.ID
+			}
+			if ruleID != tt.wantRule {
+				t.Errorf("命中规则 = %d, want %d", ruleID, tt.wantRule)
+			}
+			if got := nodeNames(r.Gates); !reflect.DeepEqual(got, tt.wantGates) {
+				t.Errorf("Gates = %v, want %v", got, tt.wantGates)
+			}
+			if got := nodeNames(r.Games); !reflect.DeepEqual(got, tt.wantGames) {
+				t.Errorf("Games = %v, want %v", got, tt.wantGames)
+			}
+		})
+	}
+}
+
+func nodeNames(nodes []cluster.ServiceNode) []string {
+	result := make([]string, len(nodes))
+	for i, node := range nodes {
+		result[i] = node.Name
+	}
+	return result
+}
@@ -0,0 +1,301 @@
+// Package keyring 管理JWT签名密钥：按 kid 选择验证密钥，支持 HS256、RS256、EdDSA，
+// 并导出非对称密钥的公钥（JWKS），游戏服无需持有密钥即可验证令牌。
+package keyring
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"gameWeb/config"
+	"gameWeb/log"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 支持的签名算法
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Client 客户端令牌密钥环
+var Client *Keyring
+
+// Admin 管理后台令牌密钥环
+var Admin *Keyring
+
+// Key 密钥环中的一个密钥
+type Key struct {
+	ID        string
+	Algorithm string
+	method    jwt.SigningMethod
+	signKey   interface{} // 为空表示只能验证，不能签发
+	verifyKey interface{}
+}
+
+// Keyring 密钥环：一个签发密钥，多个验证密钥
+type Keyring struct {
+	keys    map[string]*Key
+	signing *Key
+	legacy  *Key // 未携带 kid 的旧令牌使用的HMAC密钥
+}
+
+// JWK 公钥的JWK表示
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet JWKS文档
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// InitJWTKeyrings 根据配置初始化客户端和管理后台密钥环，配置错误时终止启动
+func InitJWTKeyrings() {
+	clientLegacy := config.AppConfig.JWT.SecretKey
+	if config.AppConfig.JWT.DisableLegacySecret {
+		clientLegacy = ""
+	}
+	adminLegacy := config.AppConfig.Admin.JWTSecretKey
+	if config.AppConfig.Admin.JWTDisableLegacySecret {
+		adminLegacy = ""
+	}
+
+	var err error
+	Client, err = New(config.AppConfig.JWT.Keys, config.AppConfig.JWT.SigningKeyID, clientLegacy)
+	if err != nil {
+		log.Fatalf("Failed to init client JWT keyring: %v", err)
+	}
+	Admin, err = New(config.AppConfig.Admin.JWTKeys, config.AppConfig.Admin.JWTSigningKeyID, adminLegacy)
+	if err != nil {
+		log.Fatalf("Failed to init admin JWT keyring: %v", err)
+	}
+	log.Infof("JWT keyrings initialized: client signing kid=%q, admin signing kid=%q",
+		Client.SigningKeyID(), Admin.SigningKeyID())
+}
+
+// New 创建密钥环。legacySecret 用于验证未携带 kid 的令牌；signingKeyID 为空时用它签发（不带 kid）
+func New(cfgs []config.JWTKey, signingKeyID, legacySecret string) (*Keyring, error) {
+	r := &Keyring{keys: make(map[string]*Key)}
+	if legacySecret != "" {
+		r.legacy = &Key{
+			Algorithm: AlgHS256,
+			method:    jwt.SigningMethodHS256,
+			signKey:   []byte(legacySecret),
+			verifyKey: []byte(legacySecret),
+		}
+	}
+
+	for _, cfg := range cfgs {
+		key, err := loadKey(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("kid %q: %v", cfg.ID, err)
+		}
+		if _, exists := r.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate kid %q", key.ID)
+		}
+		r.keys[key.ID] = key
+	}
+
+	if signingKeyID == "" {
+		if r.legacy == nil {
+			return nil, fmt.Errorf("no signing key configured")
+		}
+		r.signing = r.legacy
+		return r, nil
+	}
+
+	key, ok := r.keys[signingKeyID]
+	if !ok {
+		return nil, fmt.Errorf("signing kid %q not found", signingKeyID)
+	}
+	if key.signKey == nil {
+		return nil, fmt.Errorf("signing kid %q has no private key", signingKeyID)
+	}
+	r.signing = key
+	return r, nil
+}
+
+// SigningKeyID 当前签发密钥的 kid，使用旧密钥签发时为空
+func (r *Keyring) SigningKeyID() string {
+	return r.signing.ID
+}
+
+// Sign 使用签发密钥签名，并在头部写入 kid
+func (r *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(r.signing.method, claims)
+	if r.signing.ID != "" {
+		token.Header["kid"] = r.signing.ID
+	}
+	return token.SignedString(r.signing.signKey)
+}
+
+// Keyfunc 按 kid 选择验证密钥，并校验令牌算法与密钥算法一致，防止算法混淆
+func (r *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	key := r.legacy
+	if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+		key, ok = r.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown kid: %s", kid)
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("token has no kid")
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.verifyKey, nil
+}
+
+// ValidMethods 密钥环中出现的全部算法，用于 jwt.WithValidMethods
+func (r *Keyring) ValidMethods() []string {
+	seen := map[string]bool{}
+	methods := []string{}
+	add := func(k *Key) {
+		if k != nil && !seen[k.method.Alg()] {
+			seen[k.method.Alg()] = true
+			methods = append(methods, k.method.Alg())
+		}
+	}
+	add(r.legacy)
+	for _, k := range r.keys {
+		add(k)
+	}
+	return methods
+}
+
+// JWKS 导出非对称密钥的公钥，HMAC密钥永远不会导出
+func (r *Keyring) JWKS() JWKSet {
+	ids := make([]string, 0, len(r.keys))
+	for id := range r.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	set := JWKSet{Keys: []JWK{}}
+	for _, id := range ids {
+		k := r.keys[id]
+		switch pub := k.verifyKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA",
+				Kid: k.ID,
+				Use: "sig",
+				Alg: AlgRS256,
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "OKP",
+				Kid: k.ID,
+				Use: "sig",
+				Alg: AlgEdDSA,
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return set
+}
+
+// loadKey 根据配置加载单个密钥
+func loadKey(cfg config.JWTKey) (*Key, error) {
+	if cfg.ID == "" {
+		return nil, fmt.Errorf("kid is required")
+	}
+	key := &Key{ID: cfg.ID}
+
+	switch strings.ToUpper(cfg.Algorithm) {
+	case AlgHS256:
+		if cfg.Secret == "" {
+			return nil, fmt.Errorf("secret is required for HS256")
+		}
+		key.Algorithm = AlgHS256
+		key.method = jwt.SigningMethodHS256
+		key.signKey = []byte(cfg.Secret)
+		key.verifyKey = []byte(cfg.Secret)
+		return key, nil
+
+	case AlgRS256:
+		key.Algorithm = AlgRS256
+		key.method = jwt.SigningMethodRS256
+		if cfg.PrivateKeyFile != "" {
+			pem, err := os.ReadFile(cfg.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			priv, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.signKey = priv
+			key.verifyKey = &priv.PublicKey
+		}
+		if cfg.PublicKeyFile != "" {
+			pem, err := os.ReadFile(cfg.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			pub, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.verifyKey = pub
+		}
+
+	case strings.ToUpper(AlgEdDSA):
+		key.Algorithm = AlgEdDSA
+		key.method = jwt.SigningMethodEdDSA
+		if cfg.PrivateKeyFile != "" {
+			pem, err := os.ReadFile(cfg.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			priv, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			signer, ok := priv.(crypto.Signer)
+			if !ok {
+				return nil, fmt.Errorf("invalid EdDSA private key")
+			}
+			key.signKey = priv
+			key.verifyKey = signer.Public()
+		}
+		if cfg.PublicKeyFile != "" {
+			pem, err := os.ReadFile(cfg.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			pub, err := jwt.ParseEdPublicKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.verifyKey = pub
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported alg %q", cfg.Algorithm)
+	}
+
+	if key.verifyKey == nil {
+		return nil, fmt.Errorf("privateKeyFile or publicKeyFile is required for %s", key.Algorithm)
+	}
+	return key, nil
+}
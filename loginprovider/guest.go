@@ -0,0 +1,34 @@
+package loginprovider
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"gameWeb/config"
+	"regexp"
+)
+
+// guestDeviceIDPattern 设备ID格式：客户端首次启动时生成并保存的随机串（如UUID），长度足够时难以猜测
+var guestDeviceIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{32,128}$`)
+
+// guestProvider 游客（设备）登录，loginData 为客户端生成的设备ID
+// 设备ID就是账号凭证，丢失后无法找回，客户端应引导游客绑定平台账号
+type guestProvider struct{}
+
+func (guestProvider) Name() string  { return Guest }
+func (guestProvider) Table() string { return "guest" }
+
+// Login 校验设备ID格式，账号表中只保存设备ID的哈希，每次登录生成新的会话密钥
+func (guestProvider) Login(ctx context.Context, app config.LoginApp, loginData string) (*Identity, error) {
+	if !guestDeviceIDPattern.MatchString(loginData) {
+		return nil, ErrInvalidLoginData
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(loginData))
+	return &Identity{OpenID: hex.EncodeToString(sum[:]), SessionKey: hex.EncodeToString(buf)}, nil
+}
@@ -0,0 +1,181 @@
+package loginprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gameWeb/config"
+	"gameWeb/log"
+	"gameWeb/wechat"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// 各平台 code2session 接口的路径，接口地址在 qq、douyin 配置中，微信的在 wechat 配置中
+const (
+	qqCode2SessionPath     = "/sns/jscode2session"
+	douyinCode2SessionPath = "/api/apps/v2/jscode2session"
+)
+
+// code2SessionResponse QQ小游戏 jscode2session 的响应
+type code2SessionResponse struct {
+	SessionKey string `json:"session_key"`
+	Unionid    string `json:"unionid"`
+	Errmsg     string `json:"errmsg"`
+	Errcode    int    `json:"errcode"`
+	Openid     string `json:"openid"`
+}
+
+// wechatMiniGameProvider 微信小游戏登录，loginData 为 wx.login 返回的 code
+type wechatMiniGameProvider struct{}
+
+func (wechatMiniGameProvider) Name() string  { return WechatMiniGame }
+func (wechatMiniGameProvider) Table() string { return "wechatMiniGame" }
+
+// Login 通过微信服务端接口客户端调用 jscode2session
+func (wechatMiniGameProvider) Login(ctx context.Context, app config.LoginApp, loginData string) (*Identity, error) {
+	if loginData == "" {
+		return nil, ErrInvalidLoginData
+	}
+
+	log.Infof("%s code2session request: appid=%s", WechatMiniGame, app.AppID)
+	session, err := wechat.NewClient(app.AppID, app.Secret).Code2Session(ctx, loginData)
+	if err != nil {
+		var apiErr *wechat.APIError
+		if errors.As(err, &apiErr) {
+			return nil, &PlatformError{Provider: WechatMiniGame, Code: apiErr.Code, Message: apiErr.Message}
+		}
+		return nil, err
+	}
+
+	log.Infof("%s response parsed: openid=%s, unionid=%s", WechatMiniGame, session.OpenID, session.UnionID)
+	return &Identity{OpenID: session.OpenID, UnionID: session.UnionID, SessionKey: session.SessionKey}, nil
+}
+
+// qqMiniGameProvider QQ小游戏登录，loginData 为 qq.login 返回的 code
+type qqMiniGameProvider struct{}
+
+func (qqMiniGameProvider) Name() string  { return QQMiniGame }
+func (qqMiniGameProvider) Table() string { return "qqMiniGame" }
+
+// Login 调用QQ jscode2session
+func (qqMiniGameProvider) Login(ctx context.Context, app config.LoginApp, loginData string) (*Identity, error) {
+	baseURL := strings.TrimRight(config.AppConfig.QQ.BaseURL, "/")
+	return jscode2session(ctx, QQMiniGame, baseURL+qqCode2SessionPath, app, loginData)
+}
+
+// jscode2session QQ小游戏接口，参数和响应格式与微信相同；js_code 只能使用一次，失败时不重试
+func jscode2session(ctx context.Context, provider, baseURL string, app config.LoginApp, code string) (*Identity, error) {
+	if code == "" {
+		return nil, ErrInvalidLoginData
+	}
+
+	values := url.Values{}
+	values.Add("appid", app.AppID)
+	values.Add("secret", app.Secret)
+	values.Add("js_code", code)
+	values.Add("grant_type", "authorization_code")
+	log.Infof("%s code2session request: appid=%s", provider, app.AppID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"?"+values.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp code2SessionResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("unmarshal %s response: %v", provider, err)
+	}
+	if resp.Errcode != 0 {
+		return nil, &PlatformError{Provider: provider, Code: resp.Errcode, Message: resp.Errmsg}
+	}
+	if resp.Openid == "" {
+		return nil, &PlatformError{Provider: provider, Code: -1, Message: "empty openid"}
+	}
+
+	log.Infof("%s response parsed: openid=%s, unionid=%s", provider, resp.Openid, resp.Unionid)
+	return &Identity{OpenID: resp.Openid, UnionID: resp.Unionid, SessionKey: resp.SessionKey}, nil
+}
+
+// douyinMiniGameProvider 抖音小游戏登录，loginData 为 tt.login 返回的 code
+type douyinMiniGameProvider struct{}
+
+func (douyinMiniGameProvider) Name() string  { return DouyinMiniGame }
+func (douyinMiniGameProvider) Table() string { return "douyinMiniGame" }
+
+// Login 调用抖音 jscode2session（v2，POST JSON）
+func (douyinMiniGameProvider) Login(ctx context.Context, app config.LoginApp, loginData string) (*Identity, error) {
+	if loginData == "" {
+		return nil, ErrInvalidLoginData
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"appid":  app.AppID,
+		"secret": app.Secret,
+		"code":   loginData,
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("%s code2session request: appid=%s", DouyinMiniGame, app.AppID)
+
+	baseURL := strings.TrimRight(config.AppConfig.Douyin.BaseURL, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+douyinCode2SessionPath, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	body, err := doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		ErrNo   int    `json:"err_no"`
+		ErrTips string `json:"err_tips"`
+		Data    struct {
+			SessionKey string `json:"session_key"`
+			Openid     string `json:"openid"`
+			Unionid    string `json:"unionid"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("unmarshal %s response: %v", DouyinMiniGame, err)
+	}
+	if resp.ErrNo != 0 {
+		return nil, &PlatformError{Provider: DouyinMiniGame, Code: resp.ErrNo, Message: resp.ErrTips}
+	}
+	// 未登录抖音的用户只有 anonymous_openid，不能用来建立账号
+	if resp.Data.Openid == "" {
+		return nil, &PlatformError{Provider: DouyinMiniGame, Code: -1, Message: "empty openid"}
+	}
+
+	log.Infof("%s response parsed: openid=%s, unionid=%s", DouyinMiniGame, resp.Data.Openid, resp.Data.Unionid)
+	return &Identity{OpenID: resp.Data.Openid, UnionID: resp.Data.Unionid, SessionKey: resp.Data.SessionKey}, nil
+}
+
+// doRequest 发送请求并读取响应
+func doRequest(req *http.Request) ([]byte, error) {
+	resp, err := wechat.HTTPClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("make HTTP request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read response body: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP status %d", resp.StatusCode)
+	}
+	return body, nil
+}
@@ -0,0 +1,135 @@
+// Package loginprovider 客户端第三方登录方式注册表：每种登录方式把客户端提交的登录数据
+// 换成平台用户标识，并对应一张固定的账号表。
+package loginprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gameWeb/config"
+	"regexp"
+	"sort"
+)
+
+// 登录方式名称，即 ThirdLogin 的 loginType，同时也是账号表名和账号表 type 字段的值
+const (
+	WechatMiniGame = "wechatMiniGame"
+	QQMiniGame     = "qqMiniGame"
+	DouyinMiniGame = "douyinMiniGame"
+	Guest          = "guest"
+)
+
+// ErrInvalidLoginData 登录数据格式错误
+var ErrInvalidLoginData = errors.New("invalid login data")
+
+// PlatformError 平台接口返回的业务错误
+type PlatformError struct {
+	Provider string
+	Code     int
+	Message  string
+}
+
+func (e *PlatformError) Error() string {
+	return fmt.Sprintf("%s error, code: %d, message: %s", e.Provider, e.Code, e.Message)
+}
+
+// Identity 登录成功后得到的平台用户信息
+type Identity struct {
+	OpenID     string // 平台用户标识，写入账号表 username
+	UnionID    string
+	SessionKey string // 平台会话密钥，游客登录为随机值
+}
+
+// Provider 登录方式
+type Provider interface {
+	// Name 登录方式名称
+	Name() string
+	// Table 账号表名，必须是固定的表名，不能来自客户端输入
+	Table() string
+	// Login 用客户端提交的登录数据（如 js_code）换取平台用户信息
+	Login(ctx context.Context, app config.LoginApp, loginData string) (*Identity, error)
+}
+
+// providers 已注册的登录方式
+var providers = map[string]Provider{}
+
+// tableNamePattern 账号表名只允许字母、数字和下划线，表名会直接拼接进SQL
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,63}$`)
+
+// Register 注册登录方式，需要在处理请求之前调用；同名登录方式会被替换
+// 表名不合法时直接panic，避免带着可注入的表名启动
+func Register(p Provider) {
+	if !tableNamePattern.MatchString(p.Table()) {
+		panic(fmt.Sprintf("loginprovider: invalid account table %q for %s", p.Table(), p.Name()))
+	}
+	providers[p.Name()] = p
+}
+
+// Get 按名称获取登录方式
+func Get(name string) (Provider, bool) {
+	p, ok := providers[name]
+	return p, ok
+}
+
+// All 返回全部登录方式，按名称排序
+func All() []Provider {
+	list := make([]Provider, 0, len(providers))
+	for _, p := range providers {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list
+}
+
+// FindApp 查找应用为该登录方式配置的参数，未配置表示该应用没有开启这种登录方式
+// 兼容旧配置：wechatInfo 中的应用视为开启了微信小游戏登录
+func FindApp(appid int, provider string) (config.LoginApp, bool) {
+	for _, app := range config.AppConfig.LoginApps {
+		if app.ID == appid && app.Provider == provider {
+			return app, true
+		}
+	}
+	if provider == WechatMiniGame {
+		for _, info := range config.AppConfig.WechatInfos {
+			if info.ID == appid {
+				return config.LoginApp{ID: info.ID, Provider: WechatMiniGame, AppID: info.AppID, Secret: info.Secret}, true
+			}
+		}
+	}
+	return config.LoginApp{}, false
+}
+
+// Apps 返回开启了该登录方式的全部应用，包括旧配置 wechatInfo 中的微信小游戏应用
+func Apps(provider string) []config.LoginApp {
+	var apps []config.LoginApp
+	for _, app := range config.AppConfig.LoginApps {
+		if app.Provider == provider {
+			apps = append(apps, app)
+		}
+	}
+	if provider == WechatMiniGame {
+		for _, info := range config.AppConfig.WechatInfos {
+			if !containsApp(apps, info.AppID) {
+				apps = append(apps, config.LoginApp{ID: info.ID, Provider: WechatMiniGame, AppID: info.AppID, Secret: info.Secret})
+			}
+		}
+	}
+	return apps
+}
+
+// containsApp 列表中是否已有该平台应用
+func containsApp(apps []config.LoginApp, appID string) bool {
+	for _, app := range apps {
+		if app.AppID == appID {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	Register(wechatMiniGameProvider{})
+	Register(qqMiniGameProvider{})
+	Register(douyinMiniGameProvider{})
+	Register(guestProvider{})
+}
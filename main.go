@@ -2,9 +2,17 @@ package main
 
 import (
 	"fmt"
+	"gameWeb/accountdeletion"
+	"gameWeb/clientversion"
+	"gameWeb/cluster"
 	"gameWeb/config"
 	"gameWeb/db"
+	"gameWeb/grayrelease"
+	"gameWeb/keyring"
 	"gameWeb/log"
+	"gameWeb/maintenance"
+	"gameWeb/notifier"
+	"gameWeb/oidc"
 	"gameWeb/routes"
 	"path/filepath"
 	"time"
@@ -23,11 +31,32 @@ func init() {
 		Format: config.AppConfig.Log.Format,
 	})
 
+	// 初始化JWT密钥环
+	keyring.InitJWTKeyrings()
+
 	// 初始化数据库连接
 	db.InitMySQL()        // game库 - 用户游戏数据
 	db.InitMySQLGameWeb() // gameWeb库 - 管理员数据
 	db.InitMySQLGameLog() // gamelog库 - 日志数据
 	db.InitRedis()
+
+	// 加载集群配置并监听变更
+	cluster.Start()
+
+	// 加载停服维护配置并监听变更
+	maintenance.Start()
+
+	// 加载客户端版本策略并监听变更
+	clientversion.Start()
+
+	// 加载灰度发布规则并监听变更
+	grayrelease.Start()
+
+	// 初始化通知通道
+	notifier.InitNotifier()
+
+	// 初始化管理后台单点登录
+	oidc.InitAdminProvider()
 }
 
 func main() {
@@ -38,6 +67,12 @@ func main() {
 	// 创建Gin引擎
 	router := gin.New()
 
+	// 设置可信代理：ClientIP 只在请求来自可信代理时采信 X-Forwarded-For，
+	// 管理后台IP白名单、会话IP绑定、登录锁定和停服白名单都依赖 ClientIP
+	if err := router.SetTrustedProxies(config.AppConfig.Server.TrustedProxies); err != nil {
+		log.Fatalf("可信代理配置错误: %v", err)
+	}
+
 	// 删除这行代码
 	// router.Use(gin.LoggerWithWriter(log.LogWriter))
 
@@ -48,7 +83,7 @@ func main() {
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-API-Key", "X-Client-Version", "X-Client-Appid"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
@@ -57,6 +92,9 @@ func main() {
 	// 注册路由
 	routes.RegisterRoutes(router)
 
+	// 启动账号注销执行器
+	accountdeletion.StartExecutor()
+
 	// 启动服务器
 	serverPort := config.AppConfig.Server.Port
 	if err := router.Run(":" + serverPort); err != nil {
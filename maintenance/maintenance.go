@@ -0,0 +1,239 @@
+// Package maintenance 停服维护：维护窗口和放行名单保存在 gameWeb 库，各实例在内存中缓存，
+// 通过变更通知频道和定时刷新更新。维护期间客户端登录和服务器列表返回维护信息，
+// 放行名单中的用户和IP不受影响。
+package maintenance
+
+import (
+	"encoding/json"
+	"gameWeb/config"
+	"gameWeb/db"
+	"gameWeb/log"
+	"gameWeb/middleware"
+	"gameWeb/models"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 维护窗口状态
+const (
+	StatusNormal  int8 = 0 // 按开始和结束时间生效
+	StatusStopped int8 = 1 // 已手动结束或取消
+)
+
+// 维护窗口按当前时间计算的阶段
+const (
+	StateScheduled = "scheduled" // 未开始
+	StateActive    = "active"    // 维护中
+	StateEnded     = "ended"     // 已到结束时间
+	StateStopped   = "stopped"   // 已手动结束或取消
+)
+
+// 放行名单类型
+const (
+	AllowUserid = "userid"
+	AllowIP     = "ip"
+)
+
+// state 某一时刻加载的未结束维护窗口和放行名单，创建后不再修改
+type state struct {
+	windows []models.MaintenanceWindow
+	users   map[int64]bool
+	cidrs   []string
+}
+
+var (
+	current atomic.Value // *state
+	loadMu  sync.Mutex
+)
+
+// Start 加载维护窗口并在后台监听变更；加载失败不影响启动，此时视为没有维护
+func Start() {
+	if err := Reload(); err != nil {
+		log.Errorf("加载停服维护配置失败: %v", err)
+	}
+
+	// 计划中的窗口按时间在内存中判断是否生效，不依赖刷新
+	interval := time.Duration(config.AppConfig.Maintenance.RefreshInterval) * time.Second
+	go db.WatchChannels("停服维护配置", []string{config.AppConfig.Maintenance.NotifyChannel}, interval, Reload)
+}
+
+// Reload 从数据库重新加载未结束的维护窗口和放行名单，失败时保留原数据
+func Reload() error {
+	loadMu.Lock()
+	defer loadMu.Unlock()
+
+	windows, err := LoadOpenWindows(time.Now())
+	if err != nil {
+		return err
+	}
+	entries, err := LoadAllowlist()
+	if err != nil {
+		return err
+	}
+
+	next := &state{windows: windows, users: map[int64]bool{}, cidrs: []string{}}
+	for _, entry := range entries {
+		switch entry.Type {
+		case AllowUserid:
+			if userid, err := strconv.ParseInt(entry.Value, 10, 64); err == nil {
+				next.users[userid] = true
+			}
+		case AllowIP:
+			next.cidrs = append(next.cidrs, entry.Value)
+		}
+	}
+	current.Store(next)
+	return nil
+}
+
+// NotifyChanged 修改维护窗口或放行名单后调用，通知所有实例重新加载
+func NotifyChanged() error {
+	return db.PublishRedis(config.AppConfig.Maintenance.NotifyChannel, "changed")
+}
+
+// Current 返回对该登录方式生效中的维护窗口，没有维护时返回 nil
+// 多个窗口同时生效时取最早开始的一个
+func Current(channel string) *models.MaintenanceWindow {
+	s, _ := current.Load().(*state)
+	if s == nil {
+		return nil
+	}
+	now := time.Now()
+	for i := range s.windows {
+		w := &s.windows[i]
+		if StateOf(w, now) == StateActive && appliesTo(w, channel) {
+			return w
+		}
+	}
+	return nil
+}
+
+// Allowed 用户或IP是否在放行名单中，维护期间仍然可以登录
+func Allowed(userid int64, ip string) bool {
+	s, _ := current.Load().(*state)
+	if s == nil {
+		return false
+	}
+	if userid > 0 && s.users[userid] {
+		return true
+	}
+	// 放行名单为空时 IPMatchesAllowlist 视为不限制，这里需要视为不放行
+	return len(s.cidrs) > 0 && middleware.IPMatchesAllowlist(ip, s.cidrs)
+}
+
+// IPAllowed IP是否在放行名单中，登录时还没有确定 userid 前先按IP判断
+func IPAllowed(ip string) bool {
+	return Allowed(0, ip)
+}
+
+// Notice 维护窗口对该登录方式的公告，没有单独配置时使用默认公告
+func Notice(w *models.MaintenanceWindow, channel string) string {
+	if msg := w.ChannelNotices[channel]; msg != "" {
+		return msg
+	}
+	return w.Notice
+}
+
+// StateOf 按当前时间计算维护窗口所处的阶段
+func StateOf(w *models.MaintenanceWindow, now time.Time) string {
+	switch {
+	case w.Status == StatusStopped:
+		return StateStopped
+	case now.Before(w.StartTime):
+		return StateScheduled
+	case w.EndTime != nil && !now.Before(*w.EndTime):
+		return StateEnded
+	default:
+		return StateActive
+	}
+}
+
+// appliesTo 维护窗口是否适用于该登录方式，未指定登录方式时适用于全部
+func appliesTo(w *models.MaintenanceWindow, channel string) bool {
+	if len(w.Channels) == 0 {
+		return true
+	}
+	for _, c := range w.Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// 数据库操作函数
+
+// WindowColumns 维护窗口查询字段，与 ScanWindow 的顺序一致
+const WindowColumns = `id, title, COALESCE(channels, ''), notice, COALESCE(channelNotices, ''), startTime, endTime,
+	status, createdBy, createdByName, stoppedBy, stoppedTime, createdTime`
+
+// ScanWindow 读取一行维护窗口（字段见 WindowColumns），并按当前时间计算阶段
+func ScanWindow(row db.Scanner) (models.MaintenanceWindow, error) {
+	var w models.MaintenanceWindow
+	var channels, notices string
+	err := row.Scan(&w.ID, &w.Title, &channels, &w.Notice, &notices, &w.StartTime, &w.EndTime,
+		&w.Status, &w.CreatedBy, &w.CreatedByName, &w.StoppedBy, &w.StoppedTime, &w.CreatedTime)
+	if err != nil {
+		return w, err
+	}
+	w.Channels = []string{}
+	if channels != "" {
+		if err := json.Unmarshal([]byte(channels), &w.Channels); err != nil {
+			log.Errorf("维护窗口登录方式格式错误: id=%d, err=%v", w.ID, err)
+		}
+	}
+	w.ChannelNotices = map[string]string{}
+	if notices != "" {
+		if err := json.Unmarshal([]byte(notices), &w.ChannelNotices); err != nil {
+			log.Errorf("维护窗口公告格式错误: id=%d, err=%v", w.ID, err)
+		}
+	}
+	w.State = StateOf(&w, time.Now())
+	return w, nil
+}
+
+// LoadOpenWindows 查询未手动结束且未到结束时间的维护窗口（含计划中的），按开始时间排序
+func LoadOpenWindows(now time.Time) ([]models.MaintenanceWindow, error) {
+	rows, err := db.MySQLDBGameWeb.Query(`
+		SELECT `+WindowColumns+`
+		FROM maintenanceWindow
+		WHERE status = ? AND (endTime IS NULL OR endTime > ?)
+		ORDER BY startTime ASC, id ASC`, StatusNormal, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	windows := []models.MaintenanceWindow{}
+	for rows.Next() {
+		w, err := ScanWindow(rows)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, rows.Err()
+}
+
+// LoadAllowlist 查询全部放行名单条目
+func LoadAllowlist() ([]models.MaintenanceAllowlistEntry, error) {
+	rows, err := db.MySQLDBGameWeb.Query(`
+		SELECT id, type, value, COALESCE(remark, ''), createdBy, createdTime
+		FROM maintenanceAllowlist ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.MaintenanceAllowlistEntry{}
+	for rows.Next() {
+		var e models.MaintenanceAllowlistEntry
+		if err := rows.Scan(&e.ID, &e.Type, &e.Value, &e.Remark, &e.CreatedBy, &e.CreatedTime); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,222 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"gameWeb/config"
+	"gameWeb/db"
+	"gameWeb/log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IP绑定模式：管理员JWT中的登录IP与当前请求IP不一致时的处理方式
+const (
+	IPBindingModeWarn   = "warn"   // 只记录警告（默认）
+	IPBindingModeReject = "reject" // 拒绝本次请求，会话保留，回到登录IP后可继续使用
+	IPBindingModeReauth = "reauth" // 注销当前会话，要求重新登录
+)
+
+// GlobalIPAllowlistID 全局IP白名单使用的管理员ID
+const GlobalIPAllowlistID uint64 = 0
+
+// adminIPAllowlistCacheTTL IP白名单缓存时间
+const adminIPAllowlistCacheTTL = 10 * time.Minute
+
+// adminIPAllowlistCacheKey IP白名单缓存键，adminId为0表示全局白名单
+func adminIPAllowlistCacheKey(adminID uint64) string {
+	return fmt.Sprintf("admin_ip_allowlist:%d", adminID)
+}
+
+// ipBindingMode 获取配置的IP绑定模式，未配置或配置错误时按warn处理
+func ipBindingMode() string {
+	switch mode := strings.ToLower(config.AppConfig.Admin.IPBindingMode); mode {
+	case IPBindingModeReject, IPBindingModeReauth:
+		return mode
+	default:
+		return IPBindingModeWarn
+	}
+}
+
+// NormalizeCIDR 校验并规范化白名单条目，单个IP转换为 /32 或 /128
+func NormalizeCIDR(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return "", fmt.Errorf("无效的IP地址: %s", value)
+		}
+		if ip.To4() != nil {
+			return ip.To4().String() + "/32", nil
+		}
+		return ip.String() + "/128", nil
+	}
+
+	_, ipNet, err := net.ParseCIDR(value)
+	if err != nil {
+		return "", fmt.Errorf("无效的CIDR: %s", value)
+	}
+	return ipNet.String(), nil
+}
+
+// GetAdminIPAllowlist 获取管理员自己的IP白名单（adminId为0时为全局白名单），优先读取Redis缓存
+func GetAdminIPAllowlist(adminID uint64) ([]string, error) {
+	cacheKey := adminIPAllowlistCacheKey(adminID)
+	if cached, err := db.GetRedis(cacheKey); err == nil && cached != "" {
+		var cidrs []string
+		if err := json.Unmarshal([]byte(cached), &cidrs); err == nil {
+			return cidrs, nil
+		}
+	}
+
+	rows, err := db.MySQLDBGameWeb.Query("SELECT cidr FROM adminIpAllowlist WHERE adminId = ?", adminID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cidrs := []string{}
+	for rows.Next() {
+		var cidr string
+		if err := rows.Scan(&cidr); err != nil {
+			return nil, err
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if data, err := json.Marshal(cidrs); err == nil {
+		if err := db.SetRedisWithExpire(cacheKey, string(data), adminIPAllowlistCacheTTL); err != nil {
+			log.Errorf("缓存IP白名单失败: %v", err)
+		}
+	}
+	return cidrs, nil
+}
+
+// ClearAdminIPAllowlistCache 清除IP白名单缓存
+func ClearAdminIPAllowlistCache(adminID uint64) {
+	if err := db.DelRedis(adminIPAllowlistCacheKey(adminID)); err != nil {
+		log.Errorf("清除IP白名单缓存失败: %v", err)
+	}
+}
+
+// IsAdminIPAllowed 判断管理员能否从指定IP访问
+// 全局白名单和管理员自己的白名单都为空时不限制，否则IP需要命中其中任意一条
+func IsAdminIPAllowed(adminID uint64, ip string) (bool, error) {
+	global, err := GetAdminIPAllowlist(GlobalIPAllowlistID)
+	if err != nil {
+		return false, err
+	}
+	own, err := GetAdminIPAllowlist(adminID)
+	if err != nil {
+		return false, err
+	}
+	return IPMatchesAllowlist(ip, append(global, own...)), nil
+}
+
+// IPMatchesAllowlist 判断IP是否命中白名单，白名单为空表示不限制
+func IPMatchesAllowlist(ip string, cidrs []string) bool {
+	if len(cidrs) == 0 {
+		return true
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			log.Errorf("IP白名单条目无效: %s", cidr)
+			continue
+		}
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordAdminLoginLog 记录一次管理员登录尝试或被拒绝的访问，写入失败只记录日志不影响请求
+func RecordAdminLoginLog(adminID *uint64, username, ip, userAgent string, status int8, reason string) {
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	query := `
+		INSERT INTO adminLoginLog (adminId, username, ip, userAgent, status, reason)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	if _, err := db.MySQLDBGameWeb.Exec(query, adminID, username, ip, userAgent, status, reason); err != nil {
+		log.Errorf("记录管理员登录历史失败: %v", err)
+	}
+}
+
+// checkAdminIPAllowlist 校验当前请求IP是否在白名单内，不在时返回403并记录到登录历史
+func checkAdminIPAllowlist(c *gin.Context, adminID uint64, username string) bool {
+	clientIP := c.ClientIP()
+	allowed, err := IsAdminIPAllowed(adminID, clientIP)
+	if err != nil {
+		log.Errorf("查询IP白名单失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "系统错误",
+		})
+		c.Abort()
+		return false
+	}
+	if !allowed {
+		log.Warnf("管理员访问被拒绝 - IP不在白名单: %s (ID: %d), IP: %s", username, adminID, clientIP)
+		RecordAdminLoginLog(&adminID, username, clientIP, c.GetHeader("User-Agent"), 0, "IP不在白名单")
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "当前IP不允许访问管理后台",
+		})
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// checkAdminIPBinding 按配置的IP绑定模式处理登录IP与当前IP不一致的请求
+func checkAdminIPBinding(c *gin.Context, adminID uint64, username, sessionID, loginIP string) bool {
+	clientIP := c.ClientIP()
+	if loginIP == "" || loginIP == clientIP {
+		return true
+	}
+
+	mode := ipBindingMode()
+	if mode == IPBindingModeWarn {
+		log.Warnf("管理员IP地址变更: %s -> %s, 用户: %s", loginIP, clientIP, username)
+		return true
+	}
+
+	userAgent := c.GetHeader("User-Agent")
+	if mode == IPBindingModeReauth {
+		if _, err := RevokeAdminSession(adminID, sessionID); err != nil {
+			log.Errorf("注销管理员会话失败: %v", err)
+		}
+		log.Warnf("管理员IP地址变更，会话已注销: %s -> %s, 用户: %s", loginIP, clientIP, username)
+		RecordAdminLoginLog(&adminID, username, clientIP, userAgent, 0, "IP变更，需重新登录")
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "登录IP已变更，请重新登录",
+			"data":    gin.H{"reauth": true},
+		})
+		c.Abort()
+		return false
+	}
+
+	log.Warnf("管理员请求被拒绝 - IP与登录IP不一致: %s -> %s, 用户: %s", loginIP, clientIP, username)
+	RecordAdminLoginLog(&adminID, username, clientIP, userAgent, 0, "IP与登录IP不一致")
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"code":    401,
+		"message": "当前IP与登录IP不一致",
+	})
+	c.Abort()
+	return false
+}
@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"gameWeb/config"
+	"gameWeb/db"
+	"gameWeb/log"
+	"gameWeb/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 管理员会话存储结构：
+//   admin_session:{adminId}:{jti}  哈希，保存单个会话的设备、IP、UA、登录时间和最后活跃时间
+//   admin_sessions:{adminId}       集合，保存该管理员全部会话的jti
+// 每次登录生成新的jti，多个设备的会话互不影响。
+
+// adminSessionTouchInterval 最后活跃时间的最小更新间隔，避免每个请求都写Redis
+const adminSessionTouchInterval = time.Minute
+
+// adminSessionKey 单个会话的键
+func adminSessionKey(adminID uint64, sessionID string) string {
+	return fmt.Sprintf("admin_session:%d:%s", adminID, sessionID)
+}
+
+// adminSessionSetKey 管理员会话集合的键
+func adminSessionSetKey(adminID uint64) string {
+	return fmt.Sprintf("admin_sessions:%d", adminID)
+}
+
+// generateSessionID 生成随机会话ID（用作JWT的jti）
+func generateSessionID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// adminSessionTimeout 会话有效期
+func adminSessionTimeout() time.Duration {
+	return time.Duration(config.AppConfig.Admin.SessionTimeout) * time.Hour
+}
+
+// CreateAdminSession 创建管理员会话
+func CreateAdminSession(adminID uint64, sessionID, ip, userAgent string) error {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	key := adminSessionKey(adminID, sessionID)
+	if err := db.HSetRedis(key,
+		"device", describeDevice(userAgent),
+		"ip", ip,
+		"userAgent", userAgent,
+		"loginTime", now,
+		"lastSeen", now,
+	); err != nil {
+		return err
+	}
+
+	expiration := adminSessionTimeout()
+	if err := db.ExpireRedis(key, expiration); err != nil {
+		return err
+	}
+
+	setKey := adminSessionSetKey(adminID)
+	if err := db.SAddRedis(setKey, sessionID); err != nil {
+		return err
+	}
+	return db.ExpireRedis(setKey, expiration)
+}
+
+// touchAdminSession 校验会话存在并更新最后活跃时间和IP，会话不存在时返回false
+func touchAdminSession(adminID uint64, sessionID, ip string) (bool, error) {
+	key := adminSessionKey(adminID, sessionID)
+	data, err := db.HGetAllRedis(key)
+	if err != nil {
+		return false, err
+	}
+	if len(data) == 0 {
+		return false, nil
+	}
+
+	lastSeen, _ := strconv.ParseInt(data["lastSeen"], 10, 64)
+	if time.Since(time.Unix(lastSeen, 0)) >= adminSessionTouchInterval || data["ip"] != ip {
+		if err := db.HSetRedis(key, "lastSeen", strconv.FormatInt(time.Now().Unix(), 10), "ip", ip); err != nil {
+			log.Errorf("更新管理员会话活跃时间失败: %v", err)
+		}
+	}
+	return true, nil
+}
+
+// ListAdminSessions 列出管理员全部有效会话（按最后活跃时间倒序），顺便清理已过期的集合成员
+func ListAdminSessions(adminID uint64) ([]models.AdminSession, error) {
+	setKey := adminSessionSetKey(adminID)
+	ids, err := db.SMembersRedis(setKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []models.AdminSession{}
+	for _, id := range ids {
+		data, err := db.HGetAllRedis(adminSessionKey(adminID, id))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) == 0 {
+			if err := db.SRemRedis(setKey, id); err != nil {
+				log.Errorf("清理过期管理员会话失败: %v", err)
+			}
+			continue
+		}
+
+		loginTime, _ := strconv.ParseInt(data["loginTime"], 10, 64)
+		lastSeen, _ := strconv.ParseInt(data["lastSeen"], 10, 64)
+		sessions = append(sessions, models.AdminSession{
+			SessionID: id,
+			Device:    data["device"],
+			IP:        data["ip"],
+			UserAgent: data["userAgent"],
+			LoginTime: time.Unix(loginTime, 0),
+			LastSeen:  time.Unix(lastSeen, 0),
+		})
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeen.After(sessions[j].LastSeen)
+	})
+	return sessions, nil
+}
+
+// RevokeAdminSession 注销单个会话，会话不存在时返回false
+func RevokeAdminSession(adminID uint64, sessionID string) (bool, error) {
+	exists, err := db.ExistsRedis(adminSessionKey(adminID, sessionID))
+	if err != nil {
+		return false, err
+	}
+	if err := db.DelRedis(adminSessionKey(adminID, sessionID)); err != nil {
+		return false, err
+	}
+	if err := db.SRemRedis(adminSessionSetKey(adminID), sessionID); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// RevokeAllAdminSessions 注销管理员全部会话（强制下线、修改密码、删除账户时使用），返回注销的会话数
+func RevokeAllAdminSessions(adminID uint64) (int, error) {
+	setKey := adminSessionSetKey(adminID)
+	ids, err := db.SMembersRedis(setKey)
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		if err := db.DelRedis(adminSessionKey(adminID, id)); err != nil {
+			return 0, err
+		}
+	}
+	if err := db.DelRedis(setKey); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// describeDevice 根据User-Agent生成简短的设备描述，例如 "Chrome / Windows"
+func describeDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return "未知设备"
+	}
+
+	os := "未知系统"
+	switch {
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad"):
+		os = "iOS"
+	case strings.Contains(ua, "android"):
+		os = "Android"
+	case strings.Contains(ua, "windows"):
+		os = "Windows"
+	case strings.Contains(ua, "mac os") || strings.Contains(ua, "macintosh"):
+		os = "macOS"
+	case strings.Contains(ua, "linux"):
+		os = "Linux"
+	}
+
+	browser := "其他"
+	switch {
+	case strings.Contains(ua, "micromessenger"):
+		browser = "微信"
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"):
+		browser = "curl"
+	}
+
+	return browser + " / " + os
+}
@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"gameWeb/db"
+	"gameWeb/log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader 服务账号调用管理后台接口时携带API密钥的请求头
+const APIKeyHeader = "X-API-Key"
+
+// API密钥格式：gwk_{前缀}_{密钥}，前缀用于查库，完整密钥只保存SHA256哈希
+const (
+	apiKeyScheme      = "gwk"
+	apiKeyPrefixBytes = 4
+	apiKeySecretBytes = 24
+)
+
+// GenerateAPIKey 生成新的API密钥，返回完整密钥、前缀和哈希
+func GenerateAPIKey() (string, string, string, error) {
+	prefixBuf := make([]byte, apiKeyPrefixBytes)
+	if _, err := rand.Read(prefixBuf); err != nil {
+		return "", "", "", err
+	}
+	secretBuf := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(secretBuf); err != nil {
+		return "", "", "", err
+	}
+
+	prefix := hex.EncodeToString(prefixBuf)
+	key := apiKeyScheme + "_" + prefix + "_" + hex.EncodeToString(secretBuf)
+	return key, prefix, HashAPIKey(key), nil
+}
+
+// HashAPIKey 计算API密钥哈希
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseAPIKeyPrefix 从完整密钥中取出前缀
+func parseAPIKeyPrefix(key string) (string, bool) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != apiKeyScheme || len(parts[1]) != apiKeyPrefixBytes*2 {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// authenticateAPIKey 校验API密钥并把调用身份写入上下文，失败时直接返回错误响应
+// 密钥以创建者的身份调用接口，实际权限为密钥权限与创建者当前权限的交集，且从不具备超级管理员身份
+func authenticateAPIKey(c *gin.Context, key string) bool {
+	abort := func(message string) bool {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": message,
+		})
+		c.Abort()
+		return false
+	}
+
+	prefix, ok := parseAPIKeyPrefix(key)
+	if !ok {
+		return abort("无效的API密钥")
+	}
+
+	var (
+		keyID        uint64
+		name         string
+		keyHash      string
+		permsJSON    string
+		status       int8
+		expireTime   sql.NullTime
+		ownerID      uint64
+		ownerName    string
+		ownerStatus  int8
+		ownerIsSuper int8
+	)
+	query := `
+		SELECT k.id, k.name, k.keyHash, k.permissions, k.status, k.expireTime,
+		       a.id, a.username, a.status, a.isSuperAdmin
+		FROM adminApiKey k
+		INNER JOIN adminAccount a ON a.id = k.createdBy
+		WHERE k.keyPrefix = ?
+	`
+	err := db.MySQLDBGameWeb.QueryRow(query, prefix).Scan(
+		&keyID, &name, &keyHash, &permsJSON, &status, &expireTime,
+		&ownerID, &ownerName, &ownerStatus, &ownerIsSuper,
+	)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Errorf("查询API密钥失败: %v", err)
+		}
+		return abort("无效的API密钥")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(HashAPIKey(key)), []byte(keyHash)) != 1 {
+		log.Warnf("API密钥校验失败: 前缀=%s, IP=%s", prefix, c.ClientIP())
+		return abort("无效的API密钥")
+	}
+	if status != 1 {
+		return abort("API密钥已吊销")
+	}
+	if expireTime.Valid && time.Now().After(expireTime.Time) {
+		return abort("API密钥已过期")
+	}
+	if ownerStatus != 1 {
+		return abort("API密钥所属账户已禁用")
+	}
+
+	var scopes []string
+	if err := json.Unmarshal([]byte(permsJSON), &scopes); err != nil {
+		log.Errorf("解析API密钥权限失败: ID=%d, %v", keyID, err)
+		return abort("无效的API密钥")
+	}
+
+	if !checkAdminIPAllowlist(c, ownerID, ownerName) {
+		return false
+	}
+
+	// 超级管理员拥有全部权限，其余账户取与当前权限的交集，账户被收回的权限密钥也随之失效
+	effective := scopes
+	if ownerIsSuper != 1 {
+		ownerPerms, err := GetAdminPermissions(ownerID)
+		if err != nil {
+			log.Errorf("查询API密钥所属账户权限失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "系统错误",
+			})
+			c.Abort()
+			return false
+		}
+		effective = intersectPermissions(scopes, ownerPerms)
+	}
+
+	clientIP := c.ClientIP()
+	go touchAPIKey(keyID, clientIP)
+
+	c.Set("adminId", ownerID)
+	c.Set("username", ownerName)
+	c.Set("isSuperAdmin", false)
+	c.Set("permissions", effective)
+	c.Set("apiKeyId", keyID)
+	c.Set("apiKeyName", name)
+
+	log.Infof("API密钥认证成功: %s (ID: %d), 所属管理员: %s, IP: %s", name, keyID, ownerName, clientIP)
+	return true
+}
+
+// intersectPermissions 求两个权限列表的交集
+func intersectPermissions(a, b []string) []string {
+	set := make(map[string]bool, len(b))
+	for _, p := range b {
+		set[p] = true
+	}
+	result := []string{}
+	for _, p := range a {
+		if set[p] {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// touchAPIKey 更新密钥最后使用时间和IP（同一分钟内只更新一次）
+func touchAPIKey(keyID uint64, ip string) {
+	query := `
+		UPDATE adminApiKey SET lastUsedTime = ?, lastUsedIp = ?
+		WHERE id = ? AND (lastUsedTime IS NULL OR lastUsedTime < ? OR lastUsedIp <> ?)
+	`
+	now := time.Now()
+	if _, err := db.MySQLDBGameWeb.Exec(query, now, ip, keyID, now.Add(-time.Minute), ip); err != nil {
+		log.Errorf("更新API密钥使用时间失败: %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gameWeb/db"
+	"gameWeb/log"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 审计信息在上下文中的键，由处理函数通过 SetAudit* 设置
+const (
+	auditTargetTypeKey = "auditTargetType"
+	auditTargetIDKey   = "auditTargetId"
+	auditBeforeKey     = "auditBefore"
+	auditAfterKey      = "auditAfter"
+)
+
+const (
+	// auditMaxBodySize 记录请求内容的最大字节数，超出部分不记录
+	auditMaxBodySize = 64 * 1024
+	// auditMaxResponseSize 为提取失败原因而缓存的响应内容最大字节数
+	auditMaxResponseSize = 4 * 1024
+)
+
+// auditSensitiveFields 请求内容中需要脱敏的字段（小写）
+var auditSensitiveFields = map[string]bool{
+	"password":     true,
+	"newpassword":  true,
+	"oldpassword":  true,
+	"token":        true,
+	"preauthtoken": true,
+	"code":         true,
+	"recoverycode": true,
+	"secret":       true,
+}
+
+// SetAuditTarget 设置本次操作的对象类型和ID
+func SetAuditTarget(c *gin.Context, targetType string, targetID interface{}) {
+	c.Set(auditTargetTypeKey, targetType)
+	c.Set(auditTargetIDKey, fmt.Sprint(targetID))
+}
+
+// SetAuditBefore 设置操作前快照
+func SetAuditBefore(c *gin.Context, data interface{}) {
+	c.Set(auditBeforeKey, data)
+}
+
+// SetAuditAfter 设置操作后快照，未设置时记录脱敏后的请求内容
+func SetAuditAfter(c *gin.Context, data interface{}) {
+	c.Set(auditAfterKey, data)
+}
+
+// auditResponseWriter 在写出响应的同时缓存前一部分内容，用于提取失败原因
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if remain := auditMaxResponseSize - w.body.Len(); remain > 0 {
+		if len(data) < remain {
+			remain = len(data)
+		}
+		w.body.Write(data[:remain])
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// AdminAudit 管理员操作审计中间件：每个变更类请求（POST/PUT/PATCH/DELETE）写一条审计记录
+// 必须放在 AdminJWTMiddleware 之后，依赖上下文中的 adminId 和 username
+func AdminAudit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		method := c.Request.Method
+		if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+
+		var requestBody []byte
+		if c.Request.Body != nil {
+			body, err := io.ReadAll(io.LimitReader(c.Request.Body, auditMaxBodySize+1))
+			if err != nil {
+				log.Errorf("读取审计请求内容失败: %v", err)
+			}
+			// 把已读取的部分和未读取的部分重新拼接，保证处理函数读到完整请求
+			c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+			if len(body) <= auditMaxBodySize {
+				requestBody = body
+			}
+		}
+
+		writer := &auditResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		adminID, ok := c.Get("adminId")
+		if !ok {
+			return
+		}
+
+		entry := auditEntry{
+			AdminID:    adminID.(uint64),
+			Username:   c.GetString("username"),
+			APIKeyID:   c.GetUint64("apiKeyId"),
+			Method:     method,
+			Route:      c.FullPath(),
+			Path:       c.Request.URL.Path,
+			TargetType: c.GetString(auditTargetTypeKey),
+			TargetID:   c.GetString(auditTargetIDKey),
+			IP:         c.ClientIP(),
+			UserAgent:  c.GetHeader("User-Agent"),
+			StatusCode: writer.Status(),
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if before, ok := c.Get(auditBeforeKey); ok {
+			entry.BeforeData = marshalAuditData(before)
+		}
+		if after, ok := c.Get(auditAfterKey); ok {
+			entry.AfterData = marshalAuditData(after)
+		} else if len(requestBody) > 0 {
+			entry.AfterData = redactAuditBody(requestBody)
+		}
+
+		if entry.StatusCode < http.StatusBadRequest {
+			entry.Result = 1
+		} else {
+			var resp struct {
+				Message string `json:"message"`
+			}
+			if err := json.Unmarshal(writer.body.Bytes(), &resp); err == nil {
+				entry.ErrorMessage = truncateAuditString(resp.Message, 255)
+			}
+		}
+
+		// 异步写入，避免审计影响接口响应
+		go entry.save()
+	}
+}
+
+// auditEntry 待写入的审计记录
+type auditEntry struct {
+	AdminID      uint64
+	Username     string
+	APIKeyID     uint64
+	Method       string
+	Route        string
+	Path         string
+	TargetType   string
+	TargetID     string
+	BeforeData   string
+	AfterData    string
+	IP           string
+	UserAgent    string
+	StatusCode   int
+	Result       int8
+	ErrorMessage string
+	DurationMs   int64
+}
+
+// save 写入审计表
+func (e *auditEntry) save() {
+	query := `
+		INSERT INTO adminAuditLog (adminId, username, apiKeyId, method, route, path, targetType, targetId,
+			beforeData, afterData, ip, userAgent, statusCode, result, errorMessage, durationMs)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	var apiKeyID interface{}
+	if e.APIKeyID > 0 {
+		apiKeyID = e.APIKeyID
+	}
+	_, err := db.MySQLDBGameWeb.Exec(query,
+		e.AdminID, e.Username, apiKeyID, e.Method, e.Route, truncateAuditString(e.Path, 512),
+		nullableAuditString(e.TargetType), nullableAuditString(e.TargetID),
+		nullableAuditString(e.BeforeData), nullableAuditString(e.AfterData),
+		e.IP, truncateAuditString(e.UserAgent, 255), e.StatusCode, e.Result,
+		nullableAuditString(e.ErrorMessage), e.DurationMs,
+	)
+	if err != nil {
+		log.Errorf("写入管理员审计日志失败: %v, 管理员=%s, 路由=%s %s", err, e.Username, e.Method, e.Route)
+	}
+}
+
+// marshalAuditData 序列化快照
+func marshalAuditData(data interface{}) string {
+	if s, ok := data.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		log.Errorf("序列化审计快照失败: %v", err)
+		return ""
+	}
+	return string(b)
+}
+
+// redactAuditBody 对JSON请求内容中的敏感字段脱敏，非JSON内容不记录
+func redactAuditBody(body []byte) string {
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return ""
+	}
+	return marshalAuditData(redactAuditValue(data))
+}
+
+// redactAuditValue 递归脱敏
+func redactAuditValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			if auditSensitiveFields[strings.ToLower(k)] {
+				val[k] = "***"
+			} else {
+				val[k] = redactAuditValue(item)
+			}
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = redactAuditValue(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// truncateAuditString 按字符截断，避免超出字段长度
+func truncateAuditString(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max])
+}
+
+// nullableAuditString 空字符串写入NULL
+func nullableAuditString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"gameWeb/config"
 	"gameWeb/db"
+	"gameWeb/keyring"
 	"gameWeb/log"
 	"gameWeb/models"
 	"net/http"
@@ -26,317 +27,270 @@ type TokenInfo struct {
 	Time   int64 `json:"time"`
 }
 
-// AuthMiddleware 生成验签中间件
+// AuthMiddleware 生成验签中间件（只接受旧版DES token）
 func AuthMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 1. 从请求头获取认证信息
-		auth := c.GetHeader("Authorization")
-		userid := c.GetHeader("X-User-ID")
+	return AuthChain(AuthSchemeDES)
+}
 
-		// 验证必要头信息是否存在
-		if auth == "" || userid == "" {
-			log.Errorf("缺少Authorization或X-User-ID")
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "缺少Authorization或X-User-ID",
-			})
-			c.Abort()
-			return
-		}
+// desTokenAuthenticator 旧版客户端认证：X-User-ID + DES-ECB加密的token，密钥保存在Redis的 user:{id} 中
+type desTokenAuthenticator struct{}
 
-		// 2. 验证token格式
-		if !strings.HasPrefix(auth, "Bearer ") {
-			log.Errorf("Authorization格式无效")
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "无效的Authorization格式",
-			})
-			c.Abort()
-			return
-		}
+// Name 认证方案名称
+func (desTokenAuthenticator) Name() string {
+	return AuthSchemeDES
+}
 
-		// 提取token
-		token := auth[7:]
-		log.Info("token ", token, " userid ", userid)
-		key := "user:" + userid
+// Authenticate 校验DES token
+func (desTokenAuthenticator) Authenticate(c *gin.Context) error {
+	// 1. 从请求头获取认证信息
+	auth := c.GetHeader("Authorization")
+	userid := c.GetHeader("X-User-ID")
 
-		// 3. 从Redis中获取用户信息
-		userInfo, err := db.HGetAllRedis(key)
-		if err != nil {
-			log.Errorf("从Redis获取用户信息失败: %v", err)
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "无效或过期的token",
-			})
-			c.Abort()
-			return
-		}
-		log.Info("userInfo ", userInfo)
+	// 缺少X-User-ID或者token是JWT时不属于本方案，交给下一个认证器
+	if auth == "" || userid == "" || looksLikeJWT(auth) {
+		return ErrNoCredentials
+	}
 
-		// 检查用户信息是否存在
-		if len(userInfo) == 0 {
-			log.Errorf("Redis中未找到用户信息: %s", userid)
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "无效或过期的token",
-			})
-			c.Abort()
-			return
-		}
+	// 2. 验证token格式
+	if !strings.HasPrefix(auth, "Bearer ") {
+		log.Errorf("Authorization格式无效")
+		return authFailed("无效的Authorization格式")
+	}
 
-		// 获取subid和token
-		svrsubid := userInfo["subid"]
-		svrtoken := userInfo["token"]
+	// 提取token
+	token := auth[7:]
+	log.Info("token ", token, " userid ", userid)
+	key := "user:" + userid
 
-		// 4. 对svrtoken进行hex解码
-		hexDecodedToken, err := hex.DecodeString(svrtoken)
-		if err != nil {
-			log.Errorf("svrtoken hex解码失败: %v", err)
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "无效或过期的token",
-			})
-			c.Abort()
-			return
-		}
-		log.Info("Hex解码后的svrtoken: ", string(hexDecodedToken))
+	// 3. 从Redis中获取用户信息
+	userInfo, err := db.HGetAllRedis(key)
+	if err != nil {
+		log.Errorf("从Redis获取用户信息失败: %v", err)
+		return authFailed("无效或过期的token")
+	}
+	log.Info("userInfo ", userInfo)
 
-		// 5. 对token进行base64解码
-		base64DecodedToken, err := base64.StdEncoding.DecodeString(token)
-		if err != nil {
-			log.Errorf("token base64解码失败: %v", err)
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "无效或过期的token",
-			})
-			c.Abort()
-			return
-		}
+	// 检查用户信息是否存在
+	if len(userInfo) == 0 {
+		log.Errorf("Redis中未找到用户信息: %s", userid)
+		return authFailed("无效或过期的token")
+	}
 
-		// 6. 使用DES算法解密
-		// 确保DES密钥长度为8字节
-		if len(hexDecodedToken) != 8 {
-			log.Errorf("DES密钥长度无效: %d, 必须为8字节", len(hexDecodedToken))
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "无效或过期的token",
-			})
-			c.Abort()
-			return
-		}
+	// 获取subid和token
+	svrsubid := userInfo["subid"]
+	svrtoken := userInfo["token"]
 
-		// 创建DES解密器
-		desBlock, err := des.NewCipher(hexDecodedToken)
-		if err != nil {
-			log.Errorf("创建DES解密器失败: %v", err)
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "无效或过期的token",
-			})
-			c.Abort()
-			return
-		}
+	// 4. 对svrtoken进行hex解码
+	hexDecodedToken, err := hex.DecodeString(svrtoken)
+	if err != nil {
+		log.Errorf("svrtoken hex解码失败: %v", err)
+		return authFailed("无效或过期的token")
+	}
+	log.Info("Hex解码后的svrtoken: ", string(hexDecodedToken))
 
-		// 确保密文长度是8的倍数
-		if len(base64DecodedToken)%8 != 0 {
-			log.Errorf("密文长度无效: %d, 必须是8的倍数", len(base64DecodedToken))
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "无效或过期的token",
-			})
-			c.Abort()
-			return
-		}
+	// 5. 对token进行base64解码
+	base64DecodedToken, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		log.Errorf("token base64解码失败: %v", err)
+		return authFailed("无效或过期的token")
+	}
 
-		// 使用ECB模式解密
-		plaintext := make([]byte, len(base64DecodedToken))
-		for i := 0; i < len(base64DecodedToken); i += des.BlockSize {
-			desBlock.Decrypt(plaintext[i:i+des.BlockSize], base64DecodedToken[i:i+des.BlockSize])
-		}
+	// 6. 使用DES算法解密
+	// 确保DES密钥长度为8字节
+	if len(hexDecodedToken) != 8 {
+		log.Errorf("DES密钥长度无效: %d, 必须为8字节", len(hexDecodedToken))
+		return authFailed("无效或过期的token")
+	}
 
-		// 7. 去除ISO7816-4填充
-		// ISO7816-4填充规则: 第一个字节是0x80，后面跟着0个或多个0x00字节
-		paddingIndex := -1
-		for i := len(plaintext) - 1; i >= 0; i-- {
-			if plaintext[i] == 0x80 {
-				paddingIndex = i
-				break
-			} else if plaintext[i] != 0x00 {
-				// 遇到非0x00且非0x80的字节，没有使用ISO7816-4填充
-				paddingIndex = len(plaintext)
-				break
-			}
-		}
+	// 创建DES解密器
+	desBlock, err := des.NewCipher(hexDecodedToken)
+	if err != nil {
+		log.Errorf("创建DES解密器失败: %v", err)
+		return authFailed("无效或过期的token")
+	}
+
+	// 确保密文长度是8的倍数
+	if len(base64DecodedToken)%8 != 0 {
+		log.Errorf("密文长度无效: %d, 必须是8的倍数", len(base64DecodedToken))
+		return authFailed("无效或过期的token")
+	}
 
-		// 如果没有找到0x80，则假设没有填充
-		if paddingIndex == -1 {
+	// 使用ECB模式解密
+	plaintext := make([]byte, len(base64DecodedToken))
+	for i := 0; i < len(base64DecodedToken); i += des.BlockSize {
+		desBlock.Decrypt(plaintext[i:i+des.BlockSize], base64DecodedToken[i:i+des.BlockSize])
+	}
+
+	// 7. 去除ISO7816-4填充
+	// ISO7816-4填充规则: 第一个字节是0x80，后面跟着0个或多个0x00字节
+	paddingIndex := -1
+	for i := len(plaintext) - 1; i >= 0; i-- {
+		if plaintext[i] == 0x80 {
+			paddingIndex = i
+			break
+		} else if plaintext[i] != 0x00 {
+			// 遇到非0x00且非0x80的字节，没有使用ISO7816-4填充
 			paddingIndex = len(plaintext)
+			break
 		}
+	}
 
-		plaintext = plaintext[:paddingIndex]
+	// 如果没有找到0x80，则假设没有填充
+	if paddingIndex == -1 {
+		paddingIndex = len(plaintext)
+	}
 
-		log.Info("DES解密数据长度: ", len(plaintext))
-		log.Info("DES解密数据: ", string(plaintext))
+	plaintext = plaintext[:paddingIndex]
 
-		// 8. JSON解析plaintext数据
-		var tokenInfo TokenInfo
-		err = json.Unmarshal(plaintext, &tokenInfo)
-		if err != nil {
-			log.Errorf("解析token信息失败: %v", err)
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "无效的token格式",
-			})
-			c.Abort()
-			return
-		}
+	log.Info("DES解密数据长度: ", len(plaintext))
+	log.Info("DES解密数据: ", string(plaintext))
 
-		// 9. 验证userid和subid
-		// 转换请求头中的userid为int64类型
-		reqUserid, err := strconv.ParseInt(userid, 10, 64)
-		if err != nil {
-			log.Errorf("userid格式无效: %v", err)
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "无效的userid格式",
-			})
-			c.Abort()
-			return
-		}
+	// 8. JSON解析plaintext数据
+	var tokenInfo TokenInfo
+	err = json.Unmarshal(plaintext, &tokenInfo)
+	if err != nil {
+		log.Errorf("解析token信息失败: %v", err)
+		return authFailed("无效的token格式")
+	}
 
-		// 转换Redis中的subid为int64类型
-		svrSubid, err := strconv.ParseInt(svrsubid, 10, 64)
-		if err != nil {
-			log.Errorf("Redis中的subid格式无效: %v", err)
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "无效的token数据",
-			})
-			c.Abort()
-			return
-		}
+	// 9. 验证userid和subid
+	// 转换请求头中的userid为int64类型
+	reqUserid, err := strconv.ParseInt(userid, 10, 64)
+	if err != nil {
+		log.Errorf("userid格式无效: %v", err)
+		return authFailed("无效的userid格式")
+	}
 
-		// 比较解析出的userid和subid
-		if tokenInfo.Userid != reqUserid || tokenInfo.Subid != svrSubid {
-			log.Errorf("token验证失败: 期望userid=%d, subid=%d; 实际userid=%d, subid=%d",
-				reqUserid, svrSubid, tokenInfo.Userid, tokenInfo.Subid)
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "无效或过期的token",
-			})
-			c.Abort()
-			return
-		}
+	// 转换Redis中的subid为int64类型
+	svrSubid, err := strconv.ParseInt(svrsubid, 10, 64)
+	if err != nil {
+		log.Errorf("Redis中的subid格式无效: %v", err)
+		return authFailed("无效的token数据")
+	}
 
-		log.Info("用户token验证成功: ", userid)
+	// 比较解析出的userid和subid
+	if tokenInfo.Userid != reqUserid || tokenInfo.Subid != svrSubid {
+		log.Errorf("token验证失败: 期望userid=%d, subid=%d; 实际userid=%d, subid=%d",
+			reqUserid, svrSubid, tokenInfo.Userid, tokenInfo.Subid)
+		return authFailed("无效或过期的token")
+	}
 
-		// 10. 将验证后的信息存储在上下文中
-		c.Set("subid", tokenInfo.Subid)
-		c.Set("userid", tokenInfo.Userid)
-		c.Set("tokenTime", tokenInfo.Time)
+	log.Info("用户token验证成功: ", userid)
 
-		// 验签通过，继续处理请求
-		c.Next()
-	}
+	// 10. 将验证后的信息存储在上下文中
+	c.Set("subid", tokenInfo.Subid)
+	c.Set("userid", tokenInfo.Userid)
+	c.Set("tokenTime", tokenInfo.Time)
+	return nil
 }
 
 // JWTClaims 定义JWT声明结构体
 type JWTClaims struct {
 	Userid    int64  `json:"userid"`
 	Channelid string `json:"channelid"`
+	Fid       string `json:"fid,omitempty"` // 刷新令牌族ID，本服务签发的令牌才有
+	// 毫秒级签发时间，本服务签发的令牌才有，用于与吊销水位比较（iat 只有秒级精度）
+	IssuedAtMs int64 `json:"iatms,omitempty"`
+	// 模拟令牌：签发令牌的管理员，普通令牌为空
+	ImpersonatorID   uint64 `json:"imp,omitempty"`
+	ImpersonatorName string `json:"impName,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// AuthMiddlewareByJWT 基于JWT的认证中间件（简化版，无需Redis验证）
+// AuthMiddlewareByJWT 基于JWT的认证中间件（只接受JWT，无需Redis验证）
 func AuthMiddlewareByJWT() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 1. 从请求头获取认证信息
-		auth := c.GetHeader("Authorization")
+	return AuthChain(AuthSchemeJWT)
+}
 
-		// 验证必要头信息是否存在
-		if auth == "" {
-			log.Errorf("缺少Authorization")
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "缺少Authorization",
-			})
-			c.Abort()
-			return
-		}
+// jwtAuthenticator 客户端JWT认证，验证密钥由客户端密钥环按kid选择
+type jwtAuthenticator struct{}
 
-		// 2. 验证token格式
-		if !strings.HasPrefix(auth, "Bearer ") {
-			log.Errorf("Authorization格式无效")
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "无效的Authorization格式",
-			})
-			c.Abort()
-			return
-		}
+// Name 认证方案名称
+func (jwtAuthenticator) Name() string {
+	return AuthSchemeJWT
+}
 
-		// 提取token
-		tokenString := auth[7:]
-		log.Info("JWT token: ", tokenString)
-		// 3. 解析和验证JWT token
-		secretKey := []byte(config.AppConfig.JWT.SecretKey)
-		token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-			// 验证签名算法
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return secretKey, nil
-		})
+// Authenticate 校验客户端JWT
+func (jwtAuthenticator) Authenticate(c *gin.Context) error {
+	// 1. 从请求头获取认证信息，不是JWT时交给下一个认证器
+	auth := c.GetHeader("Authorization")
+	if !looksLikeJWT(auth) {
+		return ErrNoCredentials
+	}
 
-		if err != nil {
-			log.Errorf("JWT解析失败: %v", err)
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "无效或过期的token",
-				"error":   err.Error(),
-			})
-			c.Abort()
-			return
-		}
+	// 2. 提取token
+	tokenString := auth[7:]
+	log.Info("JWT token: ", tokenString)
+	// 3. 解析和验证JWT token（按kid从密钥环选择验证密钥）
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, keyring.Client.Keyfunc,
+		jwt.WithValidMethods(keyring.Client.ValidMethods()))
 
-		// 验证token是否有效
-		if !token.Valid {
-			log.Errorf("token无效")
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "无效的token",
-			})
-			c.Abort()
-			return
-		}
+	if err != nil {
+		log.Errorf("JWT解析失败: %v", err)
+		return authFailed("无效或过期的token")
+	}
 
-		// 提取claims
-		claims, ok := token.Claims.(*JWTClaims)
-		if !ok {
-			log.Errorf("无法提取JWT claims")
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "无效的token格式",
-			})
-			c.Abort()
-			return
-		}
+	// 验证token是否有效
+	if !token.Valid {
+		log.Errorf("token无效")
+		return authFailed("无效的token")
+	}
 
-		// 4. 将验证后的信息存储在上下文中
-		c.Set("userid", claims.Userid)
-		c.Set("channelid", claims.Channelid)
-		c.Set("tokenTime", claims.IssuedAt.Unix())
+	// 提取claims
+	claims, ok := token.Claims.(*JWTClaims)
+	if !ok {
+		log.Errorf("无法提取JWT claims")
+		return authFailed("无效的token格式")
+	}
+	if !checkClientTokenAudience(claims) {
+		log.Warnf("JWT受众与令牌类型不符: userid=%d, aud=%v, imp=%d", claims.Userid, claims.Audience, claims.ImpersonatorID)
+		return authFailed("无效的token")
+	}
 
-		log.Info("用户JWT验证成功: userid=", claims.Userid, ", channelid=", claims.Channelid)
+	// 4. 检查令牌是否已被吊销（单个令牌黑名单或用户吊销水位）
+	revoked, err := isClientTokenRevoked(claims)
+	if err != nil {
+		log.Errorf("检查token吊销状态失败: %v", err)
+		return &AuthError{Status: http.StatusInternalServerError, Message: "系统错误"}
+	}
+	if revoked {
+		log.Warnf("token已被吊销: userid=%d, jti=%s", claims.Userid, claims.ID)
+		return authFailed("token已失效，请重新登录")
+	}
 
-		// 验签通过，继续处理请求
-		c.Next()
+	// 5. 将验证后的信息存储在上下文中
+	c.Set("userid", claims.Userid)
+	c.Set("channelid", claims.Channelid)
+	c.Set("fid", claims.Fid)
+	c.Set("jti", claims.ID)
+	if claims.ExpiresAt != nil {
+		c.Set("tokenExpireTime", claims.ExpiresAt.Time)
+	}
+	if claims.IssuedAt != nil {
+		c.Set("tokenTime", claims.IssuedAt.Unix())
 	}
+	if claims.ImpersonatorID > 0 {
+		c.Set("impersonatorId", claims.ImpersonatorID)
+		c.Set("impersonatorName", claims.ImpersonatorName)
+		log.Infof("模拟令牌访问: userid=%d, 管理员=%s (ID: %d), 路由=%s",
+			claims.Userid, claims.ImpersonatorName, claims.ImpersonatorID, c.FullPath())
+	}
+
+	log.Info("用户JWT验证成功: userid=", claims.Userid, ", channelid=", claims.Channelid)
+	return nil
 }
 
 // AdminJWTMiddleware 管理员专用JWT认证中间件
 func AdminJWTMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// 0. 服务账号使用API密钥访问
+		if apiKey := c.GetHeader(APIKeyHeader); apiKey != "" {
+			if authenticateAPIKey(c, apiKey) {
+				c.Next()
+			}
+			return
+		}
+
 		// 1. 提取Authorization头
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -372,10 +326,16 @@ func AdminJWTMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// 4. 检查管理员会话状态
-		sessionKey := fmt.Sprintf("admin_session:%d", claims.AdminID)
-		sessionData, err := db.GetRedis(sessionKey)
-		if err != nil || sessionData == "" {
+		// 4. 检查管理员会话状态（按jti区分会话，未携带jti的旧token视为过期）
+		clientIP := c.ClientIP()
+		active := false
+		if claims.ID != "" {
+			active, err = touchAdminSession(claims.AdminID, claims.ID, clientIP)
+			if err != nil {
+				log.Errorf("查询管理员会话失败: %v", err)
+			}
+		}
+		if !active {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"code":    401,
 				"message": "会话已过期，请重新登录",
@@ -384,11 +344,12 @@ func AdminJWTMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// 5. 验证IP地址（可选安全检查）
-		clientIP := c.ClientIP()
-		if claims.LoginIP != "" && claims.LoginIP != clientIP {
-			log.Warnf("管理员IP地址变更: %s -> %s, 用户: %s", claims.LoginIP, clientIP, claims.Username)
-			// 可以选择是否严格验证IP，这里只记录警告
+		// 5. 验证IP地址：白名单，以及按配置的绑定模式处理IP变更
+		if !checkAdminIPAllowlist(c, claims.AdminID, claims.Username) {
+			return
+		}
+		if !checkAdminIPBinding(c, claims.AdminID, claims.Username, claims.ID, claims.LoginIP) {
+			return
 		}
 
 		// 6. 存储管理员信息到上下文
@@ -399,6 +360,7 @@ func AdminJWTMiddleware() gin.HandlerFunc {
 		c.Set("isSuperAdmin", claims.IsSuperAdmin)
 		c.Set("departmentId", claims.DepartmentID)
 		c.Set("loginIp", claims.LoginIP)
+		c.Set("sessionId", claims.ID)
 		
 		log.Infof("管理员认证成功: %s (ID: %d)", claims.Username, claims.AdminID)
 		c.Next()
@@ -407,16 +369,9 @@ func AdminJWTMiddleware() gin.HandlerFunc {
 
 // ValidateAdminJWT 验证管理员JWT Token
 func ValidateAdminJWT(tokenString string) (*models.AdminJWTClaims, error) {
-	// 使用专门的管理员JWT密钥
-	secretKey := []byte(config.AppConfig.Admin.JWTSecretKey)
-	
-	token, err := jwt.ParseWithClaims(tokenString, &models.AdminJWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// 验证签名算法
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return secretKey, nil
-	})
+	// 使用专门的管理员密钥环，按kid选择验证密钥
+	token, err := jwt.ParseWithClaims(tokenString, &models.AdminJWTClaims{}, keyring.Admin.Keyfunc,
+		jwt.WithValidMethods(keyring.Admin.ValidMethods()))
 
 	if err != nil {
 		return nil, fmt.Errorf("JWT解析失败: %v", err)
@@ -434,9 +389,13 @@ func ValidateAdminJWT(tokenString string) (*models.AdminJWTClaims, error) {
 	return claims, nil
 }
 
-// GenerateAdminJWT 生成管理员JWT Token
-func GenerateAdminJWT(admin *models.AdminAccount) (string, error) {
+// GenerateAdminJWT 生成管理员JWT Token，返回token和作为会话ID的jti
+func GenerateAdminJWT(admin *models.AdminAccount) (string, string, error) {
 	now := time.Now()
+	jti, err := generateSessionID()
+	if err != nil {
+		return "", "", err
+	}
 	expirationTime := now.Add(time.Duration(config.AppConfig.Admin.TokenExpireHours) * time.Hour)
 
 	claims := &models.AdminJWTClaims{
@@ -453,13 +412,32 @@ func GenerateAdminJWT(admin *models.AdminAccount) (string, error) {
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "gameWeb-admin",
 			Subject:   fmt.Sprintf("admin:%d", admin.ID),
+			ID:        jti,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	secretKey := []byte(config.AppConfig.Admin.JWTSecretKey)
-	
-	return token.SignedString(secretKey)
+	tokenString, err := keyring.Admin.Sign(claims)
+	if err != nil {
+		return "", "", err
+	}
+	return tokenString, jti, nil
+}
+
+// RequireInteractiveSession 需要管理员本人登录的会话，拒绝API密钥调用
+// 用于修改个人资料、会话、两步验证和管理API密钥等个人接口，泄露的密钥不能借此接管所属账户
+func RequireInteractiveSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, isAPIKey := c.Get("apiKeyId")
+		if isAPIKey || c.GetString("sessionId") == "" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "该接口需要管理员登录会话，不能使用API密钥调用",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
 }
 
 // RequireSuperAdmin 需要超级管理员权限中间件
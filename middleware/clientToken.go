@@ -0,0 +1,359 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gameWeb/config"
+	"gameWeb/db"
+	"gameWeb/keyring"
+	"gameWeb/log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 客户端刷新令牌存储结构：
+//   client_refresh_family:{fid}      哈希，保存令牌族的用户、渠道和当前有效刷新令牌的哈希
+//   client_refresh_families:{userid} 集合，保存该用户全部令牌族ID
+// 每次登录创建一个令牌族，刷新时轮换族内的刷新令牌；旧令牌被再次使用时视为泄露，整个令牌族作废。
+//
+// 访问令牌吊销：
+//   client_token_revoked_before:{userid} 毫秒时间戳水位，签发时间不晚于该时间的访问令牌全部失效
+//   client_token_denylist:{jti}          单个访问令牌的黑名单，保留到令牌过期
+//   client_token_denylist_fid:{fid}      令牌族黑名单，族内签发的访问令牌全部失效，保留一个访问令牌有效期
+
+// ErrRefreshTokenInvalid 刷新令牌不存在、格式错误或已过期
+var ErrRefreshTokenInvalid = errors.New("refresh token invalid")
+
+// ErrRefreshTokenReused 已轮换的刷新令牌被再次使用，令牌族已作废
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+// clientTokenIssuer 客户端JWT签发方
+const clientTokenIssuer = "gameWeb"
+
+// 客户端JWT受众：玩家令牌和模拟令牌使用不同的 aud，通过 JWKS 验证令牌的服务必须要求玩家受众
+const (
+	ClientTokenAudience        = "gameWeb-client"
+	ImpersonationTokenAudience = "gameWeb-impersonation"
+)
+
+// ClientTokens 登录或刷新后返回给客户端的令牌
+type ClientTokens struct {
+	AccessToken      string `json:"accessToken"`
+	RefreshToken     string `json:"refreshToken"`
+	ExpiresIn        int64  `json:"expiresIn"`
+	RefreshExpiresIn int64  `json:"refreshExpiresIn"`
+}
+
+// rotateRefreshScript 原子地校验并轮换刷新令牌
+// 返回 -1 令牌族不存在，0 旧令牌被重用（已删除令牌族），1 轮换成功
+const rotateRefreshScript = `
+local current = redis.call('HGET', KEYS[1], 'current')
+if not current then
+	return -1
+end
+if current ~= ARGV[1] then
+	redis.call('DEL', KEYS[1])
+	return 0
+end
+redis.call('HSET', KEYS[1], 'current', ARGV[2])
+redis.call('EXPIRE', KEYS[1], ARGV[3])
+return 1
+`
+
+// clientRefreshFamilyKey 令牌族的键
+func clientRefreshFamilyKey(familyID string) string {
+	return "client_refresh_family:" + familyID
+}
+
+// clientRefreshFamilySetKey 用户令牌族集合的键
+func clientRefreshFamilySetKey(userid int64) string {
+	return fmt.Sprintf("client_refresh_families:%d", userid)
+}
+
+// clientRefreshExpire 刷新令牌有效期
+func clientRefreshExpire() time.Duration {
+	return time.Duration(config.AppConfig.JWT.RefreshExpireTime) * time.Second
+}
+
+// randomHex 生成指定字节数的随机十六进制字符串
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashRefreshToken 计算刷新令牌哈希，Redis中只保存哈希
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateClientJWT 使用客户端密钥环签发访问令牌，fid 为所属刷新令牌族
+func GenerateClientJWT(userid int64, channelid, familyID string) (string, error) {
+	jti, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := &JWTClaims{
+		Userid:     userid,
+		Channelid:  channelid,
+		Fid:        familyID,
+		IssuedAtMs: now.UnixMilli(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(config.AppConfig.JWT.ExpireTime) * time.Second)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    clientTokenIssuer,
+			Subject:   strconv.FormatInt(userid, 10),
+			Audience:  jwt.ClaimStrings{ClientTokenAudience},
+			ID:        jti,
+		},
+	}
+
+	return keyring.Client.Sign(claims)
+}
+
+// IssueClientTokens 登录成功后创建新的令牌族，签发访问令牌和刷新令牌
+func IssueClientTokens(userid int64, channelid string) (*ClientTokens, error) {
+	familyID, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := newRefreshToken(familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	expiration := clientRefreshExpire()
+	key := clientRefreshFamilyKey(familyID)
+	if err := db.HSetRedis(key,
+		"userid", userid,
+		"channelid", channelid,
+		"current", hashRefreshToken(refreshToken),
+		"createdAt", time.Now().Unix(),
+	); err != nil {
+		return nil, err
+	}
+	if err := db.ExpireRedis(key, expiration); err != nil {
+		return nil, err
+	}
+
+	setKey := clientRefreshFamilySetKey(userid)
+	if err := db.SAddRedis(setKey, familyID); err != nil {
+		return nil, err
+	}
+	if err := db.ExpireRedis(setKey, expiration); err != nil {
+		return nil, err
+	}
+
+	return buildClientTokens(userid, channelid, familyID, refreshToken)
+}
+
+// RefreshClientTokens 使用刷新令牌换取新的访问令牌和刷新令牌，旧刷新令牌立即失效
+func RefreshClientTokens(refreshToken string) (*ClientTokens, error) {
+	familyID, ok := parseRefreshToken(refreshToken)
+	if !ok {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	key := clientRefreshFamilyKey(familyID)
+	family, err := db.HGetAllRedis(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(family) == 0 {
+		return nil, ErrRefreshTokenInvalid
+	}
+	userid, err := strconv.ParseInt(family["userid"], 10, 64)
+	if err != nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+	channelid := family["channelid"]
+
+	newToken, err := newRefreshToken(familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	expiration := clientRefreshExpire()
+	result, err := db.EvalRedis(rotateRefreshScript, []string{key},
+		hashRefreshToken(refreshToken), hashRefreshToken(newToken), int64(expiration.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+
+	switch result.(int64) {
+	case 1:
+	case 0:
+		log.Warnf("检测到刷新令牌重用，令牌族已作废: userid=%d, fid=%s", userid, familyID)
+		// 令牌族内已签发的访问令牌（包括盗用方持有的）同时失效
+		if err := denyClientTokenFamily(familyID); err != nil {
+			log.Errorf("写入令牌族黑名单失败: %v", err)
+		}
+		if err := db.SRemRedis(clientRefreshFamilySetKey(userid), familyID); err != nil {
+			log.Errorf("删除令牌族索引失败: %v", err)
+		}
+		return nil, ErrRefreshTokenReused
+	default:
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	if err := db.ExpireRedis(clientRefreshFamilySetKey(userid), expiration); err != nil {
+		log.Errorf("更新令牌族索引过期时间失败: %v", err)
+	}
+	return buildClientTokens(userid, channelid, familyID, newToken)
+}
+
+// RevokeClientTokenFamily 作废令牌族，族内的刷新令牌都不能再使用，已签发的访问令牌同时失效
+func RevokeClientTokenFamily(userid int64, familyID string) error {
+	if err := denyClientTokenFamily(familyID); err != nil {
+		return err
+	}
+	if err := db.DelRedis(clientRefreshFamilyKey(familyID)); err != nil {
+		return err
+	}
+	return db.SRemRedis(clientRefreshFamilySetKey(userid), familyID)
+}
+
+// denyClientTokenFamily 把令牌族加入黑名单，保留一个访问令牌有效期，之后族内签发的访问令牌已经自然过期
+func denyClientTokenFamily(familyID string) error {
+	ttl := time.Duration(config.AppConfig.JWT.ExpireTime) * time.Second
+	return db.SetRedisWithExpire(clientTokenFamilyDenylistKey(familyID), 1, ttl)
+}
+
+// buildClientTokens 签发访问令牌并组装响应
+func buildClientTokens(userid int64, channelid, familyID, refreshToken string) (*ClientTokens, error) {
+	accessToken, err := GenerateClientJWT(userid, channelid, familyID)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientTokens{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresIn:        config.AppConfig.JWT.ExpireTime,
+		RefreshExpiresIn: config.AppConfig.JWT.RefreshExpireTime,
+	}, nil
+}
+
+// newRefreshToken 生成刷新令牌，格式为 {令牌族ID}.{随机串}
+func newRefreshToken(familyID string) (string, error) {
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+	return familyID + "." + secret, nil
+}
+
+// parseRefreshToken 从刷新令牌中取出令牌族ID
+func parseRefreshToken(token string) (string, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || len(parts[0]) != 32 || len(parts[1]) != 64 {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// clientTokenWatermarkKey 用户访问令牌吊销水位的键
+func clientTokenWatermarkKey(userid int64) string {
+	return fmt.Sprintf("client_token_revoked_before:%d", userid)
+}
+
+// clientTokenDenylistKey 访问令牌黑名单的键
+func clientTokenDenylistKey(jti string) string {
+	return "client_token_denylist:" + jti
+}
+
+// clientTokenFamilyDenylistKey 令牌族黑名单的键
+func clientTokenFamilyDenylistKey(familyID string) string {
+	return "client_token_denylist_fid:" + familyID
+}
+
+// RevokeClientToken 把单个访问令牌加入黑名单，保留到令牌过期为止
+func RevokeClientToken(jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if jti == "" || ttl <= 0 {
+		return nil
+	}
+	return db.SetRedisWithExpire(clientTokenDenylistKey(jti), 1, ttl)
+}
+
+// RevokeAllClientTokens 吊销用户的全部令牌：此前签发的访问令牌立即失效，全部刷新令牌族作废
+// 返回作废的令牌族数量
+func RevokeAllClientTokens(userid int64) (int, error) {
+	// 水位只需保留一个访问令牌有效期，之后更早签发的令牌已经自然过期
+	ttl := time.Duration(config.AppConfig.JWT.ExpireTime) * time.Second
+	if err := db.SetRedisWithExpire(clientTokenWatermarkKey(userid), time.Now().UnixMilli(), ttl); err != nil {
+		return 0, err
+	}
+
+	setKey := clientRefreshFamilySetKey(userid)
+	families, err := db.SMembersRedis(setKey)
+	if err != nil {
+		return 0, err
+	}
+	for _, familyID := range families {
+		if err := db.DelRedis(clientRefreshFamilyKey(familyID)); err != nil {
+			return 0, err
+		}
+	}
+	if err := db.DelRedis(setKey); err != nil {
+		return 0, err
+	}
+	return len(families), nil
+}
+
+// isClientTokenRevoked 检查访问令牌是否已被吊销（单个令牌黑名单、令牌族黑名单或用户水位）
+func isClientTokenRevoked(claims *JWTClaims) (bool, error) {
+	if claims.ID != "" {
+		denied, err := db.ExistsRedis(clientTokenDenylistKey(claims.ID))
+		if err != nil {
+			return false, err
+		}
+		if denied {
+			return true, nil
+		}
+	}
+	if claims.Fid != "" {
+		denied, err := db.ExistsRedis(clientTokenFamilyDenylistKey(claims.Fid))
+		if err != nil {
+			return false, err
+		}
+		if denied {
+			return true, nil
+		}
+	}
+
+	watermark, err := db.GetRedis(clientTokenWatermarkKey(claims.Userid))
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	revokedBefore, err := strconv.ParseInt(watermark, 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	// 本服务签发的令牌按毫秒比较：吊销之后（哪怕在同一秒内）重新登录签发的令牌不受影响，
+	// 与水位同一毫秒签发的令牌按已吊销处理
+	if claims.IssuedAtMs > 0 {
+		return claims.IssuedAtMs <= revokedBefore, nil
+	}
+	// 没有签发时间的令牌无法判断先后，按已吊销处理
+	if claims.IssuedAt == nil {
+		return true, nil
+	}
+	// 其他服务签发的令牌只有秒级 iat，与水位同一秒签发的无法判断先后，按已吊销处理（宁可多吊销）
+	return claims.IssuedAt.Unix() <= revokedBefore/1000, nil
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"gameWeb/db"
+	"gameWeb/log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 权限编码（与 sql/adminPermission.sql 中的初始数据保持一致）
+const (
+	PermUserRead           = "user.read"
+	PermUserWrite          = "user.write"
+	PermUserRichesWrite    = "user.riches.write"
+	PermUserImpersonate    = "user.impersonate"
+	PermUserDelete         = "user.delete"
+	PermMailRead           = "mail.read"
+	PermMailSend           = "mail.send"
+	PermMailWrite          = "mail.write"
+	PermLogsRead           = "logs.read"
+	PermAdminManage        = "admin.manage"
+	PermAuditRead          = "audit.read"
+	PermAPIKeyManage       = "apikey.manage"
+	PermClusterRead        = "cluster.read"
+	PermClusterWrite       = "cluster.write"
+	PermMaintenanceRead    = "maintenance.read"
+	PermMaintenanceWrite   = "maintenance.write"
+	PermClientVersionRead  = "clientversion.read"
+	PermClientVersionWrite = "clientversion.write"
+)
+
+// adminPermissionCacheTTL 管理员权限缓存时间
+const adminPermissionCacheTTL = 10 * time.Minute
+
+// adminPermissionCacheKey 管理员权限缓存键
+func adminPermissionCacheKey(adminID uint64) string {
+	return fmt.Sprintf("admin_permissions:%d", adminID)
+}
+
+// GetAdminPermissions 获取管理员拥有的全部权限编码（优先读取Redis缓存）
+func GetAdminPermissions(adminID uint64) ([]string, error) {
+	cacheKey := adminPermissionCacheKey(adminID)
+	if cached, err := db.GetRedis(cacheKey); err == nil && cached != "" {
+		var perms []string
+		if err := json.Unmarshal([]byte(cached), &perms); err == nil {
+			return perms, nil
+		}
+	}
+
+	query := `
+		SELECT DISTINCT rp.permissionCode
+		FROM adminAccountRole ar
+		INNER JOIN adminRolePermission rp ON ar.roleId = rp.roleId
+		WHERE ar.adminId = ?
+	`
+	rows, err := db.MySQLDBGameWeb.Query(query, adminID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	perms := []string{}
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		perms = append(perms, code)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if data, err := json.Marshal(perms); err == nil {
+		if err := db.SetRedisWithExpire(cacheKey, string(data), adminPermissionCacheTTL); err != nil {
+			log.Errorf("缓存管理员权限失败: %v", err)
+		}
+	}
+	return perms, nil
+}
+
+// ClearAdminPermissionCache 清除指定管理员的权限缓存
+func ClearAdminPermissionCache(adminID uint64) {
+	if err := db.DelRedis(adminPermissionCacheKey(adminID)); err != nil {
+		log.Errorf("清除管理员权限缓存失败: %v", err)
+	}
+}
+
+// ClearAllAdminPermissionCache 清除所有管理员的权限缓存（角色权限变更时使用）
+func ClearAllAdminPermissionCache() {
+	keys, err := db.ScanRedisKeys("admin_permissions:*")
+	if err != nil {
+		log.Errorf("扫描管理员权限缓存失败: %v", err)
+		return
+	}
+	for _, key := range keys {
+		if err := db.DelRedis(key); err != nil {
+			log.Errorf("清除管理员权限缓存失败: %v", err)
+		}
+	}
+}
+
+// HasPermission 判断当前请求的管理员是否拥有指定权限，超级管理员拥有全部权限
+func HasPermission(c *gin.Context, perm string) bool {
+	if isSuperAdmin, ok := c.Get("isSuperAdmin"); ok && isSuperAdmin.(bool) {
+		return true
+	}
+
+	perms, err := contextPermissions(c)
+	if err != nil {
+		log.Errorf("获取管理员权限失败: %v", err)
+		return false
+	}
+	for _, p := range perms {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// contextPermissions 获取当前请求的权限列表，同一请求内只查询一次
+func contextPermissions(c *gin.Context) ([]string, error) {
+	if cached, ok := c.Get("permissions"); ok {
+		return cached.([]string), nil
+	}
+
+	adminID, ok := c.Get("adminId")
+	if !ok {
+		return nil, fmt.Errorf("上下文中缺少adminId")
+	}
+	perms, err := GetAdminPermissions(adminID.(uint64))
+	if err != nil {
+		return nil, err
+	}
+	c.Set("permissions", perms)
+	return perms, nil
+}
+
+// RequirePermission 需要指定权限的中间件
+func RequirePermission(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasPermission(c, perm) {
+			username, _ := c.Get("username")
+			log.Warnf("管理员权限不足: 管理员=%v, 需要权限=%s, 路径=%s", username, perm, c.FullPath())
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "没有权限执行此操作",
+				"data":    gin.H{"permission": perm},
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
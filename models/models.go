@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -21,6 +22,8 @@ type AdminAccount struct {
 	Note            string    `json:"note" db:"note"`
 	LastLoginIP     string    `json:"lastLoginIp" db:"lastLoginIp"`
 	LastLoginTime   time.Time `json:"lastLoginTime" db:"lastLoginTime"`
+	PasswordResetToken string     `json:"-" db:"passwordResetToken"` // 重置令牌的SHA256哈希
+	TokenExpireTime    *time.Time `json:"-" db:"tokenExpireTime"`
 	CreatedBy       *uint64   `json:"createdBy" db:"createdBy"`
 	UpdatedBy       *uint64   `json:"updatedBy" db:"updatedBy"`
 	CreatedTime     time.Time `json:"createdTime" db:"createdTime"`
@@ -39,7 +42,125 @@ type AdminJWTClaims struct {
 	jwt.RegisteredClaims
 }
 
-// UserData 用户数据模型
+// AdminSession 管理员登录会话（保存在Redis，以JWT的jti为会话ID）
+type AdminSession struct {
+	SessionID string    `json:"sessionId"`
+	Device    string    `json:"device"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"userAgent"`
+	LoginTime time.Time `json:"loginTime"`
+	LastSeen  time.Time `json:"lastSeen"`
+	Current   bool      `json:"current"`
+}
+
+// AdminRole 管理员角色模型
+type AdminRole struct {
+	ID          uint64    `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	Permissions []string  `json:"permissions"`
+	CreatedBy   *uint64   `json:"createdBy,omitempty" db:"createdBy"`
+	UpdatedBy   *uint64   `json:"updatedBy,omitempty" db:"updatedBy"`
+	CreatedTime time.Time `json:"createdTime" db:"createdTime"`
+	UpdatedTime time.Time `json:"updatedTime" db:"updatedTime"`
+}
+
+// AdminPermission 管理员权限模型
+type AdminPermission struct {
+	Code        string `json:"code" db:"code"`
+	Name        string `json:"name" db:"name"`
+	Description string `json:"description" db:"description"`
+}
+
+// AdminTwoFactor 管理员两步验证模型
+type AdminTwoFactor struct {
+	AdminID       uint64    `json:"adminId" db:"adminId"`
+	Secret        string    `json:"-" db:"secret"`
+	Enabled       int8      `json:"enabled" db:"enabled"`
+	RecoveryCodes []string  `json:"-" db:"recoveryCodes"` // SHA256哈希
+	LastUsedStep  int64     `json:"-" db:"lastUsedStep"`
+	CreatedTime   time.Time `json:"createdTime" db:"createdTime"`
+	UpdatedTime   time.Time `json:"updatedTime" db:"updatedTime"`
+}
+
+// AdminLoginLog 管理员登录历史模型
+type AdminLoginLog struct {
+	ID          uint64    `json:"id" db:"id"`
+	AdminID     *uint64   `json:"adminId,omitempty" db:"adminId"`
+	Username    string    `json:"username" db:"username"`
+	IP          string    `json:"ip" db:"ip"`
+	UserAgent   string    `json:"userAgent" db:"userAgent"`
+	Status      int8      `json:"status" db:"status"` // 0-失败, 1-成功
+	Reason      string    `json:"reason" db:"reason"`
+	CreatedTime time.Time `json:"createdTime" db:"createdTime"`
+}
+
+// AdminAuditLog 管理员操作审计日志模型
+type AdminAuditLog struct {
+	ID           uint64    `json:"id" db:"id"`
+	AdminID      uint64    `json:"adminId" db:"adminId"`
+	Username     string    `json:"username" db:"username"`
+	APIKeyID     *uint64   `json:"apiKeyId,omitempty" db:"apiKeyId"`
+	Method       string    `json:"method" db:"method"`
+	Route        string    `json:"route" db:"route"`
+	Path         string    `json:"path" db:"path"`
+	TargetType   string    `json:"targetType" db:"targetType"`
+	TargetID     string    `json:"targetId" db:"targetId"`
+	BeforeData   string    `json:"beforeData" db:"beforeData"`
+	AfterData    string    `json:"afterData" db:"afterData"`
+	IP           string    `json:"ip" db:"ip"`
+	UserAgent    string    `json:"userAgent" db:"userAgent"`
+	StatusCode   int       `json:"statusCode" db:"statusCode"`
+	Result       int8      `json:"result" db:"result"` // 0-失败, 1-成功
+	ErrorMessage string    `json:"errorMessage" db:"errorMessage"`
+	DurationMs   int64     `json:"durationMs" db:"durationMs"`
+	CreatedTime  time.Time `json:"createdTime" db:"createdTime"`
+}
+
+// AdminAPIKey 管理后台API密钥（服务账号）模型，不包含密钥明文和哈希
+type AdminAPIKey struct {
+	ID           uint64     `json:"id" db:"id"`
+	Name         string     `json:"name" db:"name"`
+	KeyPrefix    string     `json:"keyPrefix" db:"keyPrefix"`
+	Permissions  []string   `json:"permissions" db:"permissions"`
+	Status       int8       `json:"status" db:"status"` // 0-已吊销, 1-有效
+	ExpireTime   *time.Time `json:"expireTime" db:"expireTime"`
+	LastUsedTime *time.Time `json:"lastUsedTime" db:"lastUsedTime"`
+	LastUsedIP   string     `json:"lastUsedIp" db:"lastUsedIp"`
+	RotatedTime  *time.Time `json:"rotatedTime" db:"rotatedTime"`
+	CreatedBy    uint64     `json:"createdBy" db:"createdBy"`
+	CreatedTime  time.Time  `json:"createdTime" db:"createdTime"`
+	UpdatedTime  time.Time  `json:"updatedTime" db:"updatedTime"`
+}
+
+// AdminAPIKeyCreateResponse 创建或轮换API密钥的响应，明文密钥只返回这一次
+type AdminAPIKeyCreateResponse struct {
+	Key    string       `json:"key"`
+	APIKey *AdminAPIKey `json:"apiKey"`
+}
+
+// AdminIPAllowlist 管理员IP白名单条目，adminId为0表示全局白名单
+type AdminIPAllowlist struct {
+	ID          uint64    `json:"id" db:"id"`
+	AdminID     uint64    `json:"adminId" db:"adminId"`
+	CIDR        string    `json:"cidr" db:"cidr"`
+	Remark      string    `json:"remark" db:"remark"`
+	CreatedBy   uint64    `json:"createdBy" db:"createdBy"`
+	CreatedTime time.Time `json:"createdTime" db:"createdTime"`
+}
+
+// AdminIPAllowlistCreateRequest 添加IP白名单请求
+type AdminIPAllowlistCreateRequest struct {
+	AdminID uint64 `json:"adminId"` // 0表示全局白名单
+	CIDR    string `json:"cidr" binding:"required,max=50"`
+	Remark  string `json:"remark" binding:"max=100"`
+}
+
+// AdminIPAllowlistQueryRequest 查询IP白名单请求，不传adminId时返回全部条目
+type AdminIPAllowlistQueryRequest struct {
+	AdminID *uint64 `form:"adminId"`
+}
+
 type UserData struct {
 	UserID     int64     `json:"userid" db:"userid"`
 	Nickname   string    `json:"nickname" db:"nickname"`
@@ -163,8 +284,36 @@ type AdminLoginRequest struct {
 
 // AdminLoginResponse 管理员登录响应
 type AdminLoginResponse struct {
-	Token     string       `json:"token"`
-	AdminInfo *AdminInfo   `json:"adminInfo"`
+	Token         string     `json:"token"`
+	AdminInfo     *AdminInfo `json:"adminInfo"`
+	RecoveryCodes []string   `json:"recoveryCodes,omitempty"` // 登录时完成两步验证绑定才返回
+}
+
+// AdminOIDCLoginResponse 单点登录发起响应
+type AdminOIDCLoginResponse struct {
+	AuthorizationURL string `json:"authorizationUrl"` // 前端跳转到该地址登录身份提供方
+	State            string `json:"state"`
+	ExpiresIn        int64  `json:"expiresIn"` // 登录请求有效期（秒）
+}
+
+// AdminOIDCCallbackRequest 单点登录回调请求，code 和 state 来自身份提供方回调页面的查询参数
+type AdminOIDCCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// AdminPreAuthResponse 需要两步验证时的登录响应
+type AdminPreAuthResponse struct {
+	NeedTwoFactor  bool   `json:"needTwoFactor"`
+	EnrollRequired bool   `json:"enrollRequired"` // 未启用两步验证但系统要求启用，需要先绑定
+	PreAuthToken   string `json:"preAuthToken"`
+	ExpiresIn      int64  `json:"expiresIn"` // 预认证令牌有效期（秒）
+}
+
+// AdminTwoFactorSetupResponse 两步验证绑定信息响应
+type AdminTwoFactorSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"` // otpauth://URI，用于生成二维码
 }
 
 // AdminInfo 管理员信息响应
@@ -185,6 +334,44 @@ type AdminInfo struct {
 	UpdatedBy     *uint64   `json:"updatedBy,omitempty"`
 	CreatedTime   time.Time `json:"createdTime"`
 	UpdatedTime   time.Time `json:"updatedTime"`
+	Permissions   []string  `json:"permissions,omitempty"`
+}
+
+// AdminLoginLock 管理员登录锁定信息
+type AdminLoginLock struct {
+	Type             string    `json:"type"`   // username 或 ip
+	Target           string    `json:"target"` // 被锁定的用户名或IP
+	RemainingSeconds int64     `json:"remainingSeconds"`
+	LockedUntil      time.Time `json:"lockedUntil"`
+}
+
+// AdminLoginLogQueryRequest 管理员登录历史查询请求
+type AdminLoginLogQueryRequest struct {
+	AdminID   uint64    `form:"adminId"`
+	Username  string    `form:"username"`
+	IP        string    `form:"ip"`
+	Status    *int8     `form:"status"`
+	StartTime time.Time `form:"startTime"`
+	EndTime   time.Time `form:"endTime"`
+	Page      int       `form:"page,default=1" binding:"min=1"`
+	PageSize  int       `form:"pageSize,default=20" binding:"min=1,max=100"`
+}
+
+// AdminAuditQueryRequest 管理员操作审计查询请求
+type AdminAuditQueryRequest struct {
+	AdminID    uint64    `form:"adminId"`
+	APIKeyID   uint64    `form:"apiKeyId"`
+	Username   string    `form:"username"`
+	Method     string    `form:"method"`
+	Route      string    `form:"route"`
+	TargetType string    `form:"targetType"`
+	TargetID   string    `form:"targetId"`
+	Result     *int8     `form:"result"`
+	IP         string    `form:"ip"`
+	StartTime  time.Time `form:"startTime"`
+	EndTime    time.Time `form:"endTime"`
+	Page       int       `form:"page,default=1" binding:"min=1"`
+	PageSize   int       `form:"pageSize,default=20" binding:"min=1,max=100"`
 }
 
 // UserListRequest 用户列表查询请求
@@ -208,6 +395,217 @@ type UserUpdateRequest struct {
 	Riches   []UserRich `json:"riches"`
 }
 
+// UserImpersonateRequest 签发用户模拟令牌请求
+type UserImpersonateRequest struct {
+	Reason  string `json:"reason" binding:"required,max=255"` // 模拟原因，如工单号
+	Minutes int    `json:"minutes" binding:"omitempty,min=1"` // 有效期（分钟），默认15，最长60
+}
+
+// UserIdentity 用户绑定的登录身份
+type UserIdentity struct {
+	LoginType string     `json:"loginType"` // 登录方式，account 为账号密码登录
+	Username  string     `json:"username"`  // 账号表中的用户标识（openid、设备ID哈希或账号名）
+	CreatedAt *time.Time `json:"createdAt"`
+}
+
+// UserIdentityMergeRequest 合并用户登录身份请求
+type UserIdentityMergeRequest struct {
+	FromUserid int64  `json:"fromUserid" binding:"required,min=1"` // 身份被移走的用户
+	Reason     string `json:"reason" binding:"required,max=255"`
+}
+
+// AccountDeletion 玩家账号注销申请
+type AccountDeletion struct {
+	ID            uint64                `json:"id"`
+	Userid        int64                 `json:"userid"`
+	Status        int8                  `json:"status"` // 0-待审批, 1-已批准, 2-执行中, 3-已完成, 4-已拒绝, 5-已撤销, 6-执行失败
+	Reason        string                `json:"reason"`
+	RequestIP     string                `json:"requestIp"`
+	Channel       string                `json:"channel"`
+	ExecuteAfter  time.Time             `json:"executeAfter"`
+	ReviewerID    *uint64               `json:"reviewerId"`
+	ReviewerName  string                `json:"reviewerName"`
+	ReviewComment string                `json:"reviewComment"`
+	ReviewedTime  *time.Time            `json:"reviewedTime"`
+	StartedTime   *time.Time            `json:"startedTime"`
+	CompletedTime *time.Time            `json:"completedTime"`
+	LastError     string                `json:"lastError"`
+	CreatedTime   time.Time             `json:"createdTime"`
+	Steps         []AccountDeletionStep `json:"steps,omitempty"`
+}
+
+// AccountDeletionStep 注销执行步骤记录
+type AccountDeletionStep struct {
+	Step         string    `json:"step"`
+	Action       string    `json:"action"` // delete、anonymize 或 revoke
+	AffectedRows int64     `json:"affectedRows"`
+	Status       int8      `json:"status"` // 1-成功, 2-失败
+	ErrorMessage string    `json:"errorMessage"`
+	ExecutedTime time.Time `json:"executedTime"`
+}
+
+// AccountDeletionQueryRequest 注销申请查询请求
+type AccountDeletionQueryRequest struct {
+	Userid   int64 `form:"userid"`
+	Status   *int8 `form:"status"`
+	Page     int   `form:"page,default=1" binding:"min=1"`
+	PageSize int   `form:"pageSize,default=20" binding:"min=1,max=100"`
+}
+
+// AccountDeletionReviewRequest 注销申请审批请求
+type AccountDeletionReviewRequest struct {
+	Comment string `json:"comment" binding:"max=255"`
+}
+
+// ClusterConfigRequest 集群配置校验、对比和发布请求
+type ClusterConfigRequest struct {
+	BaseVer *int            `json:"baseVer"`                    // 编辑时的版本号，发布时必填，Redis中的版本不一致时拒绝发布
+	Config  json.RawMessage `json:"config" binding:"required"` // 集群配置JSON，ver 字段会被忽略
+	Comment string          `json:"comment" binding:"max=255"`
+}
+
+// ClusterConfigRollbackRequest 集群配置回滚请求
+type ClusterConfigRollbackRequest struct {
+	Comment string `json:"comment" binding:"max=255"`
+}
+
+// ClusterConfigHistory 集群配置发布历史
+type ClusterConfigHistory struct {
+	ID           uint64    `json:"id"`
+	Ver          int       `json:"ver"`
+	Action       string    `json:"action"` // import、publish 或 rollback
+	RollbackFrom *int      `json:"rollbackFrom"`
+	Comment      string    `json:"comment"`
+	AdminID      uint64    `json:"adminId"`
+	AdminName    string    `json:"adminName"`
+	CreatedTime  time.Time `json:"createdTime"`
+}
+
+// ClusterConfigHistoryQueryRequest 集群配置历史查询请求
+type ClusterConfigHistoryQueryRequest struct {
+	Page     int `form:"page,default=1" binding:"min=1"`
+	PageSize int `form:"pageSize,default=20" binding:"min=1,max=100"`
+}
+
+// MaintenanceWindow 停服维护窗口
+type MaintenanceWindow struct {
+	ID             uint64            `json:"id"`
+	Title          string            `json:"title"`
+	Channels       []string          `json:"channels"` // 适用的登录方式，为空表示全部
+	Notice         string            `json:"notice"`
+	ChannelNotices map[string]string `json:"channelNotices"`
+	StartTime      time.Time         `json:"startTime"`
+	EndTime        *time.Time        `json:"endTime"` // 为空表示需要手动结束
+	Status         int8              `json:"status"`  // 0-正常, 1-已手动结束或取消
+	State          string            `json:"state"`   // scheduled、active、ended 或 stopped，按当前时间计算
+	CreatedBy      uint64            `json:"createdBy"`
+	CreatedByName  string            `json:"createdByName"`
+	StoppedBy      *uint64           `json:"stoppedBy"`
+	StoppedTime    *time.Time        `json:"stoppedTime"`
+	CreatedTime    time.Time         `json:"createdTime"`
+}
+
+// MaintenanceWindowRequest 开始、计划或修改维护窗口请求
+type MaintenanceWindowRequest struct {
+	Title          string            `json:"title" binding:"required,max=100"`
+	Channels       []string          `json:"channels"`
+	Notice         string            `json:"notice" binding:"required,max=1000"`
+	ChannelNotices map[string]string `json:"channelNotices"`
+	StartTime      *time.Time        `json:"startTime"` // 计划维护时必填，立即开始时忽略
+	EndTime        *time.Time        `json:"endTime"`   // 预计结束时间，为空表示需要手动结束
+}
+
+// MaintenanceWindowQueryRequest 维护窗口查询请求
+type MaintenanceWindowQueryRequest struct {
+	Status   *int8 `form:"status"`
+	Page     int   `form:"page,default=1" binding:"min=1"`
+	PageSize int   `form:"pageSize,default=20" binding:"min=1,max=100"`
+}
+
+// MaintenanceAllowlistEntry 停服维护放行名单条目
+type MaintenanceAllowlistEntry struct {
+	ID          uint64    `json:"id"`
+	Type        string    `json:"type"` // userid 或 ip
+	Value       string    `json:"value"`
+	Remark      string    `json:"remark"`
+	CreatedBy   uint64    `json:"createdBy"`
+	CreatedTime time.Time `json:"createdTime"`
+}
+
+// MaintenanceAllowlistCreateRequest 添加维护放行名单请求
+type MaintenanceAllowlistCreateRequest struct {
+	Type   string `json:"type" binding:"required,oneof=userid ip"`
+	Value  string `json:"value" binding:"required,max=50"`
+	Remark string `json:"remark" binding:"max=100"`
+}
+
+// ClientVersionPolicy 客户端版本策略
+type ClientVersionPolicy struct {
+	ID                 uint64    `json:"id"`
+	Channel            string    `json:"channel"` // 登录方式
+	Appid              int       `json:"appid"`   // 0 表示该登录方式下的全部应用
+	MinVersion         string    `json:"minVersion"`
+	RecommendedVersion string    `json:"recommendedVersion"`
+	UpdateURL          string    `json:"updateUrl"`
+	ReleaseNotes       string    `json:"releaseNotes"`
+	UpdatedBy          uint64    `json:"updatedBy"`
+	UpdatedByName      string    `json:"updatedByName"`
+	CreatedTime        time.Time `json:"createdTime"`
+	UpdatedTime        time.Time `json:"updatedTime"`
+}
+
+// ClientVersionPolicyRequest 创建或修改客户端版本策略请求
+type ClientVersionPolicyRequest struct {
+	Channel            string `json:"channel" binding:"required,max=64"`
+	Appid              int    `json:"appid" binding:"min=0"`
+	MinVersion         string `json:"minVersion" binding:"max=32"`
+	RecommendedVersion string `json:"recommendedVersion" binding:"max=32"`
+	UpdateURL          string `json:"updateUrl" binding:"omitempty,url,max=512"`
+	ReleaseNotes       string `json:"releaseNotes" binding:"max=2000"`
+}
+
+// GrayReleaseRule 灰度发布规则
+type GrayReleaseRule struct {
+	ID            uint64    `json:"id"`
+	Name          string    `json:"name"`
+	Priority      int       `json:"priority"` // 数值小的先匹配
+	Enabled       bool      `json:"enabled"`
+	Userids       []int64   `json:"userids"`  // 指定用户，命中时不再判断其他条件
+	Channels      []string  `json:"channels"` // 登录方式，为空表示不限
+	MinVersion    string    `json:"minVersion"`
+	MaxVersion    string    `json:"maxVersion"`
+	Percentage    *int      `json:"percentage"` // 按 userid 分流的比例，为空表示不按比例分流
+	GateNodes     []string  `json:"gateNodes"`  // 为空表示使用正式网关
+	GameNodes     []string  `json:"gameNodes"`  // 为空表示使用正式游戏节点
+	Comment       string    `json:"comment"`
+	UpdatedBy     uint64    `json:"updatedBy"`
+	UpdatedByName string    `json:"updatedByName"`
+	CreatedTime   time.Time `json:"createdTime"`
+	UpdatedTime   time.Time `json:"updatedTime"`
+}
+
+// GrayReleaseRuleRequest 创建或修改灰度发布规则请求
+type GrayReleaseRuleRequest struct {
+	Name       string   `json:"name" binding:"required,max=64"`
+	Priority   int      `json:"priority"`
+	Enabled    bool     `json:"enabled"`
+	Userids    []int64  `json:"userids" binding:"max=1000"`
+	Channels   []string `json:"channels"`
+	MinVersion string   `json:"minVersion" binding:"max=32"`
+	MaxVersion string   `json:"maxVersion" binding:"max=32"`
+	Percentage *int     `json:"percentage" binding:"omitempty,min=0,max=100"`
+	GateNodes  []string `json:"gateNodes"`
+	GameNodes  []string `json:"gameNodes"`
+	Comment    string   `json:"comment" binding:"max=255"`
+}
+
+// GrayReleasePreviewRequest 灰度分配预览请求
+type GrayReleasePreviewRequest struct {
+	Userid  int64  `json:"userid" binding:"required,min=1"`
+	Channel string `json:"channel"`
+	Version string `json:"version"`
+}
+
 // LogQueryRequest 日志查询请求
 type LogQueryRequest struct {
 	UserID    int64     `form:"userid"`
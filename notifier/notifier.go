@@ -0,0 +1,127 @@
+// Package notifier 提供可替换的消息通知通道（邮件等），用于向管理员发送密码重置等通知
+package notifier
+
+import (
+	"fmt"
+	"gameWeb/config"
+	"gameWeb/log"
+	"net/smtp"
+	"strings"
+	"sync"
+)
+
+// Message 通知消息
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier 通知发送接口
+type Notifier interface {
+	Send(msg Message) error
+}
+
+var (
+	mu      sync.RWMutex
+	current Notifier = &LogNotifier{}
+)
+
+// InitNotifier 根据配置初始化默认通知通道
+func InitNotifier() {
+	cfg := config.AppConfig.Notifier
+	switch strings.ToLower(cfg.Driver) {
+	case "smtp":
+		SetNotifier(&SMTPNotifier{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.SMTP.From,
+		})
+		log.Infof("通知通道: SMTP (%s:%s)", cfg.SMTP.Host, cfg.SMTP.Port)
+	default:
+		SetNotifier(&LogNotifier{})
+		log.Info("通知通道: 本地日志（未配置SMTP，不会真正发送；日志级别为 debug 时记录正文）")
+	}
+}
+
+// SetNotifier 替换默认通知通道（测试时可替换为MemoryNotifier）
+func SetNotifier(n Notifier) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = n
+}
+
+// Send 通过默认通知通道发送消息
+func Send(msg Message) error {
+	mu.RLock()
+	n := current
+	mu.RUnlock()
+	return n.Send(msg)
+}
+
+// SMTPNotifier 通过SMTP发送邮件
+type SMTPNotifier struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send 发送邮件
+func (s *SMTPNotifier) Send(msg Message) error {
+	if msg.To == "" {
+		return fmt.Errorf("收件人为空")
+	}
+	if strings.ContainsAny(msg.To+msg.Subject, "\r\n") {
+		return fmt.Errorf("收件人或主题包含非法字符")
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	body := "From: " + s.From + "\r\n" +
+		"To: " + msg.To + "\r\n" +
+		"Subject: " + msg.Subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + msg.Body
+
+	return smtp.SendMail(s.Host+":"+s.Port, auth, s.From, []string{msg.To}, []byte(body))
+}
+
+// LogNotifier 本地替代实现：把消息写入日志，用于开发环境
+// 正文可能包含密码重置令牌等凭据，只在 debug 日志级别下写入，其他级别只记录收件人和主题
+type LogNotifier struct{}
+
+// Send 将消息写入日志
+func (l *LogNotifier) Send(msg Message) error {
+	log.Infof("[notifier] To: %s, Subject: %s, 正文 %d 字节", msg.To, msg.Subject, len(msg.Body))
+	log.Debugf("[notifier] 正文:\n%s", msg.Body)
+	return nil
+}
+
+// MemoryNotifier 本地替代实现：把消息保存在内存中，用于测试
+type MemoryNotifier struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// Send 保存消息
+func (m *MemoryNotifier) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages 返回已发送的消息
+func (m *MemoryNotifier) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
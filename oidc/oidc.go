@@ -0,0 +1,372 @@
+// Package oidc 实现管理后台单点登录使用的 OpenID Connect 客户端：
+// 发现文档、授权码 + PKCE 流程、换取令牌，以及按 JWKS 验证 ID Token。
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gameWeb/config"
+	"gameWeb/log"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrNotConfigured 未启用或未配置单点登录
+var ErrNotConfigured = errors.New("oidc not configured")
+
+// httpTimeout 访问身份提供方的超时时间
+const httpTimeout = 10 * time.Second
+
+// jwksMinRefreshInterval 遇到未知 kid 时重新获取 JWKS 的最小间隔，避免被伪造令牌放大请求
+const jwksMinRefreshInterval = 30 * time.Second
+
+// Admin 管理后台使用的身份提供方，未启用单点登录时为 nil
+var Admin *Provider
+
+// Discovery 发现文档中用到的字段
+type Discovery struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// TokenResponse 令牌端点的响应
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// Provider 一个 OIDC 身份提供方
+type Provider struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	client *http.Client
+
+	mu          sync.Mutex
+	discovery   *Discovery
+	keys        map[string]interface{}
+	keysFetched time.Time
+}
+
+// InitAdminProvider 根据配置初始化管理后台身份提供方，发现文档在首次使用时获取
+func InitAdminProvider() {
+	cfg := config.AppConfig.OIDC
+	if !cfg.Enabled {
+		return
+	}
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		log.Fatalf("OIDC enabled but issuer, clientID or redirectURL is empty")
+	}
+	Admin = NewProvider(cfg.Issuer, cfg.ClientID, cfg.ClientSecret, cfg.RedirectURL, cfg.Scopes)
+	log.Infof("OIDC single sign-on enabled: issuer=%s, clientID=%s", cfg.Issuer, cfg.ClientID)
+}
+
+// NewProvider 创建身份提供方，scopes 为空时使用 openid profile email
+func NewProvider(issuer, clientID, clientSecret, redirectURL string, scopes []string) *Provider {
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email"}
+	}
+	hasOpenID := false
+	for _, s := range scopes {
+		if s == "openid" {
+			hasOpenID = true
+		}
+	}
+	if !hasOpenID {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+	return &Provider{
+		Issuer:       strings.TrimRight(issuer, "/"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       scopes,
+		client:       &http.Client{Timeout: httpTimeout},
+	}
+}
+
+// Discover 获取并缓存发现文档
+func (p *Provider) Discover(ctx context.Context) (*Discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var doc Discovery
+	if err := p.getJSON(ctx, p.Issuer+"/.well-known/openid-configuration", &doc); err != nil {
+		return nil, fmt.Errorf("discovery: %v", err)
+	}
+	if strings.TrimRight(doc.Issuer, "/") != p.Issuer {
+		return nil, fmt.Errorf("discovery: issuer mismatch %q", doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery: missing endpoints")
+	}
+	p.discovery = &doc
+	return p.discovery, nil
+}
+
+// AuthCodeURL 生成授权地址，challenge 为 PKCE S256 code_challenge
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, challenge string) (string, error) {
+	doc, err := p.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.ClientID)
+	q.Set("redirect_uri", p.RedirectURL)
+	q.Set("scope", strings.Join(p.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", challenge)
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return doc.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange 使用授权码和 PKCE code_verifier 换取令牌
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (*TokenResponse, error) {
+	doc, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", p.ClientID)
+
+	// 默认 client_secret_basic，身份提供方只支持 client_secret_post 时放在表单中
+	useBasic := p.ClientSecret != ""
+	if useBasic && supportsOnly(doc.TokenEndpointAuthMethodsSupported, "client_secret_post", "client_secret_basic") {
+		form.Set("client_secret", p.ClientSecret)
+		useBasic = false
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if useBasic {
+		req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, truncate(string(body), 200))
+	}
+
+	var token TokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("token response: %v", err)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+	return &token, nil
+}
+
+// VerifyIDToken 验证 ID Token 的签名、iss、aud、exp 和 nonce，返回全部声明
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return p.verificationKey(ctx, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384", "EdDSA"}),
+		jwt.WithIssuer(p.Issuer),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("id_token: %v", err)
+	}
+
+	if got, _ := claims["nonce"].(string); got == "" || got != nonce {
+		return nil, fmt.Errorf("id_token: nonce mismatch")
+	}
+	// 多个受众时 azp 必须是本客户端
+	if aud, ok := claims["aud"].([]interface{}); ok && len(aud) > 1 {
+		if azp, _ := claims["azp"].(string); azp != p.ClientID {
+			return nil, fmt.Errorf("id_token: azp mismatch")
+		}
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return nil, fmt.Errorf("id_token: missing sub")
+	}
+	return claims, nil
+}
+
+// verificationKey 按 kid 返回验证公钥，未知 kid 时重新获取 JWKS（身份提供方轮换密钥）
+func (p *Provider) verificationKey(ctx context.Context, kid string) (interface{}, error) {
+	doc, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if !p.keysFetched.IsZero() && time.Since(p.keysFetched) < jwksMinRefreshInterval {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+
+	var set JWKSet
+	if err := p.getJSON(ctx, doc.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("jwks: %v", err)
+	}
+	p.keys = set.PublicKeys()
+	p.keysFetched = time.Now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown kid %q", kid)
+}
+
+// lookupKey 在已缓存的 JWKS 中查找公钥，令牌没有 kid 且只有一个密钥时使用该密钥
+func (p *Provider) lookupKey(kid string) (interface{}, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// getJSON 发送 GET 请求并解析 JSON 响应
+func (p *Provider) getJSON(ctx context.Context, rawURL string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %d", rawURL, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
+
+// GeneratePKCE 生成 PKCE code_verifier 和对应的 S256 code_challenge
+func GeneratePKCE() (verifier, challenge string, err error) {
+	verifier, err = RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	return verifier, PKCEChallenge(verifier), nil
+}
+
+// PKCEChallenge 计算 S256 code_challenge
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ClaimString 读取字符串声明
+func ClaimString(claims jwt.MapClaims, name string) string {
+	if v, ok := claims[name].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// ClaimBool 读取布尔声明，兼容字符串形式的 "true"
+func ClaimBool(claims jwt.MapClaims, name string) bool {
+	switch v := claims[name].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// ClaimStrings 读取字符串或字符串数组声明（如 groups、roles）
+func ClaimStrings(claims jwt.MapClaims, name string) []string {
+	switch v := claims[name].(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// supportsOnly 判断 methods 包含 want 但不包含 other
+func supportsOnly(methods []string, want, other string) bool {
+	hasWant, hasOther := false, false
+	for _, m := range methods {
+		switch m {
+		case want:
+			hasWant = true
+		case other:
+			hasOther = true
+		}
+	}
+	return hasWant && !hasOther
+}
+
+// truncate 截断错误信息中的响应内容
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
@@ -9,61 +9,219 @@ import (
 
 // RegisterRoutes 注册路由
 func RegisterRoutes(router *gin.Engine) {
+	// 客户端令牌公钥（JWKS），供游戏服验证令牌
+	router.GET("/.well-known/jwks.json", controller.GetJWKS)
+
 	// API分组
 	api := router.Group("/api")
 	{
-		// 游戏相关路由
+		// 游戏登录和令牌刷新（无需JWT认证，登录后才能拿到JWT）
+		gameAuth := api.Group("/game")
+		{
+			gameAuth.POST("/thirdlogin", controller.ThirdLogin)
+			gameAuth.POST("/token/refresh", controller.RefreshClientToken)
+		}
+
+		// 游戏相关路由（模拟令牌只能访问只读路由）
 		game := api.Group("/game")
-		game.Use(middleware.AuthMiddlewareByJWT())
+		game.Use(middleware.ClientAuth("game"))
+		{
+			game.POST("/logout", controller.ClientLogout)
+			game.POST("/wechat/userinfo", controller.UpdateWechatUserInfo)
+			game.POST("/bind", controller.BindLoginIdentity)
+			game.POST("/unbind", controller.UnbindLoginIdentity)
+			game.POST("/account/delete", controller.RequestAccountDeletion)
+			game.POST("/account/delete/cancel", controller.CancelAccountDeletion)
+		}
+		gameReadOnly := api.Group("/game")
+		gameReadOnly.Use(middleware.ClientAuthReadOnly("game"))
 		{
-			game.POST("/authlist", controller.GetAuthGameList)
-			game.POST("/thirdlogin", controller.ThirdLogin)
+			gameReadOnly.POST("/authlist", controller.GetAuthGameList)
+			gameReadOnly.POST("/bindings", controller.GetClientBindings)
+			gameReadOnly.POST("/account/delete/status", controller.GetAccountDeletionStatus)
 		}
 
-		// 邮件相关路由 - 需要验签
+		// 邮件相关路由 - 需要验签，认证方案见配置 auth.clientSchemes / auth.groups
+		// 模拟令牌只读，只能查看邮件列表和详情
 		mail := api.Group("/mail")
-		mail.Use(middleware.AuthMiddlewareByJWT()) // 更新中间件引用
+		mail.Use(middleware.ClientAuth("mail"))
 		{
-			mail.POST("/list", controller.GetClientMailList)
-			mail.POST("/detail/:id", controller.GetClientMailDetail)
 			mail.POST("/read/:id", controller.MarkMailAsRead)
 			mail.POST("/getaward/:id", controller.GetMailAward)
 		}
+		mailReadOnly := api.Group("/mail")
+		mailReadOnly.Use(middleware.ClientAuthReadOnly("mail"))
+		{
+			mailReadOnly.POST("/list", controller.GetClientMailList)
+			mailReadOnly.POST("/detail/:id", controller.GetClientMailDetail)
+		}
 
 		// 管理后台路由组
 		admin := api.Group("/admin")
 		{
 			// 管理员认证相关路由（无需JWT认证）
 			admin.POST("/login", controller.AdminLogin)
+			admin.POST("/login/2fa", controller.AdminLoginTwoFactor)
+			admin.POST("/login/2fa/setup", controller.AdminLoginTwoFactorSetup)
+			admin.POST("/password/forgot", controller.ForgotAdminPassword)
+			admin.POST("/password/reset", controller.ResetAdminPassword)
+			admin.GET("/oidc/login", controller.AdminOIDCLogin)
+			admin.POST("/oidc/callback", controller.AdminOIDCCallback)
 
 			// 需要JWT认证的管理员路由
 			authorized := admin.Group("/")
-			authorized.Use(middleware.AdminJWTMiddleware())
+			authorized.Use(middleware.AdminJWTMiddleware(), middleware.AdminAudit())
 			{
-				// 管理员认证管理
-				authorized.POST("/logout", controller.AdminLogout)
-				authorized.GET("/info", controller.GetAdminInfo)
-				authorized.PUT("/update/:id", controller.UpdateAdmin)
+				// 个人接口只接受登录会话，API密钥调用返回403
+				personal := authorized.Group("/")
+				personal.Use(middleware.RequireInteractiveSession())
+				{
+					// 管理员认证管理
+					personal.POST("/logout", controller.AdminLogout)
+					personal.GET("/info", controller.GetAdminInfo)
+					personal.PUT("/update/:id", controller.UpdateAdmin)
+
+					// 登录会话（个人）
+					personal.GET("/sessions", controller.GetMySessions)
+					personal.DELETE("/sessions/:sessionId", controller.RevokeMySession)
 
-				// 管理员管理（仅超级管理员）
+					// 两步验证（个人）
+					personal.GET("/2fa", controller.GetTwoFactorStatus)
+					personal.POST("/2fa/setup", controller.SetupTwoFactor)
+					personal.POST("/2fa/enable", controller.EnableTwoFactor)
+					personal.POST("/2fa/disable", controller.DisableTwoFactor)
+					personal.POST("/2fa/recovery-codes", controller.RegenerateRecoveryCodes)
+				}
+
+				// 两步验证全局策略、密码重置、会话管理、IP白名单（仅超级管理员）
 				superAdmin := authorized.Group("/")
 				superAdmin.Use(middleware.RequireSuperAdmin())
 				{
-					superAdmin.GET("/admins", controller.GetAdminList)
-					superAdmin.POST("/create-admin", controller.CreateAdmin)
-					superAdmin.DELETE("/delete/:id", controller.DeleteAdmin)
+					superAdmin.GET("/2fa/policy", controller.GetTwoFactorPolicy)
+					superAdmin.PUT("/2fa/policy", controller.UpdateTwoFactorPolicy)
+					superAdmin.DELETE("/admins/:id/2fa", controller.ResetAdminTwoFactor)
+					superAdmin.POST("/admins/:id/password-reset", controller.InitiateAdminPasswordReset)
+					superAdmin.GET("/admins/:id/sessions", controller.GetAdminSessions)
+					superAdmin.DELETE("/admins/:id/sessions", controller.ForceLogoutAdmin)
+					superAdmin.DELETE("/admins/:id/sessions/:sessionId", controller.ForceRevokeAdminSession)
+					superAdmin.GET("/ip-allowlist", controller.GetIPAllowlist)
+					superAdmin.POST("/ip-allowlist", controller.AddIPAllowlist)
+					superAdmin.DELETE("/ip-allowlist/:id", controller.DeleteIPAllowlist)
+				}
+
+				// 管理员管理
+				adminManage := authorized.Group("/")
+				adminManage.Use(middleware.RequirePermission(middleware.PermAdminManage))
+				{
+					adminManage.GET("/admins", controller.GetAdminList)
+					adminManage.POST("/create-admin", controller.CreateAdmin)
+					adminManage.DELETE("/delete/:id", controller.DeleteAdmin)
+
+					// 登录锁定与登录历史
+					adminManage.GET("/login-locks", controller.GetAdminLoginLocks)
+					adminManage.DELETE("/login-locks", controller.ClearAdminLoginLock)
+					adminManage.GET("/login-logs", controller.GetAdminLoginLogs)
+
+					// 角色与权限管理
+					adminManage.GET("/permissions", controller.GetPermissionList)
+					adminManage.GET("/roles", controller.GetRoleList)
+					adminManage.POST("/roles", controller.CreateRole)
+					adminManage.PUT("/roles/:id", controller.UpdateRole)
+					adminManage.DELETE("/roles/:id", controller.DeleteRole)
+					adminManage.GET("/admins/:id/roles", controller.GetAdminRoles)
+					adminManage.PUT("/admins/:id/roles", controller.SetAdminRoles)
+				}
+
+				// 玩家账号注销审批
+				deletions := authorized.Group("/account-deletions")
+				deletions.Use(middleware.RequirePermission(middleware.PermUserDelete))
+				{
+					deletions.GET("", controller.GetAccountDeletions)
+					deletions.GET("/:id", controller.GetAccountDeletionDetail)
+					deletions.POST("/:id/approve", controller.ApproveAccountDeletion)
+					deletions.POST("/:id/reject", controller.RejectAccountDeletion)
+					deletions.POST("/:id/retry", controller.RetryAccountDeletion)
+				}
+
+				// 集群配置管理
+				clusterConfig := authorized.Group("/cluster-config")
+				{
+					clusterConfig.GET("", middleware.RequirePermission(middleware.PermClusterRead), controller.GetClusterConfig)
+					clusterConfig.PUT("", middleware.RequirePermission(middleware.PermClusterWrite), controller.PublishClusterConfig)
+					clusterConfig.POST("/validate", middleware.RequirePermission(middleware.PermClusterRead), controller.ValidateClusterConfig)
+					clusterConfig.POST("/diff", middleware.RequirePermission(middleware.PermClusterRead), controller.DiffClusterConfig)
+					clusterConfig.GET("/history", middleware.RequirePermission(middleware.PermClusterRead), controller.GetClusterConfigHistory)
+					clusterConfig.GET("/history/:ver", middleware.RequirePermission(middleware.PermClusterRead), controller.GetClusterConfigHistoryDetail)
+					clusterConfig.POST("/rollback/:ver", middleware.RequirePermission(middleware.PermClusterWrite), controller.RollbackClusterConfig)
+				}
+
+				// 停服维护
+				maint := authorized.Group("/maintenance")
+				{
+					maint.GET("", middleware.RequirePermission(middleware.PermMaintenanceRead), controller.GetMaintenanceStatus)
+					maint.GET("/windows", middleware.RequirePermission(middleware.PermMaintenanceRead), controller.GetMaintenanceWindows)
+					maint.POST("/start", middleware.RequirePermission(middleware.PermMaintenanceWrite), controller.StartMaintenance)
+					maint.POST("/windows", middleware.RequirePermission(middleware.PermMaintenanceWrite), controller.ScheduleMaintenance)
+					maint.PUT("/windows/:id", middleware.RequirePermission(middleware.PermMaintenanceWrite), controller.UpdateMaintenanceWindow)
+					maint.POST("/windows/:id/stop", middleware.RequirePermission(middleware.PermMaintenanceWrite), controller.StopMaintenanceWindow)
+					maint.GET("/allowlist", middleware.RequirePermission(middleware.PermMaintenanceRead), controller.GetMaintenanceAllowlist)
+					maint.POST("/allowlist", middleware.RequirePermission(middleware.PermMaintenanceWrite), controller.AddMaintenanceAllowlist)
+					maint.DELETE("/allowlist/:id", middleware.RequirePermission(middleware.PermMaintenanceWrite), controller.DeleteMaintenanceAllowlist)
+				}
+
+				// 灰度发布规则（与集群配置使用相同的权限）
+				grayRules := authorized.Group("/gray-release-rules")
+				{
+					grayRules.GET("", middleware.RequirePermission(middleware.PermClusterRead), controller.GetGrayReleaseRules)
+					grayRules.POST("", middleware.RequirePermission(middleware.PermClusterWrite), controller.CreateGrayReleaseRule)
+					grayRules.PUT("/:id", middleware.RequirePermission(middleware.PermClusterWrite), controller.UpdateGrayReleaseRule)
+					grayRules.DELETE("/:id", middleware.RequirePermission(middleware.PermClusterWrite), controller.DeleteGrayReleaseRule)
+					grayRules.POST("/preview", middleware.RequirePermission(middleware.PermClusterRead), controller.PreviewGrayRelease)
+				}
+
+				// 客户端版本策略
+				clientVersions := authorized.Group("/client-versions")
+				{
+					clientVersions.GET("", middleware.RequirePermission(middleware.PermClientVersionRead), controller.GetClientVersionPolicies)
+					clientVersions.POST("", middleware.RequirePermission(middleware.PermClientVersionWrite), controller.CreateClientVersionPolicy)
+					clientVersions.PUT("/:id", middleware.RequirePermission(middleware.PermClientVersionWrite), controller.UpdateClientVersionPolicy)
+					clientVersions.DELETE("/:id", middleware.RequirePermission(middleware.PermClientVersionWrite), controller.DeleteClientVersionPolicy)
+				}
+
+				// 操作审计
+				audit := authorized.Group("/audit")
+				audit.Use(middleware.RequirePermission(middleware.PermAuditRead))
+				{
+					audit.GET("", controller.GetAdminAuditLogs)
+					audit.GET("/export", controller.ExportAdminAuditLogs)
+				}
+
+				// API密钥（服务账号）
+				apiKeys := authorized.Group("/api-keys")
+				apiKeys.Use(middleware.RequireInteractiveSession(), middleware.RequirePermission(middleware.PermAPIKeyManage))
+				{
+					apiKeys.GET("", controller.GetAPIKeyList)
+					apiKeys.POST("", controller.CreateAPIKey)
+					apiKeys.POST("/:id/rotate", controller.RotateAPIKey)
+					apiKeys.DELETE("/:id", controller.RevokeAPIKey)
 				}
 
 				// 用户管理相关路由
 				users := authorized.Group("/users")
 				{
-					users.GET("/", controller.GetUserList)
-					users.GET("/:userid", controller.GetUserDetail)
-					users.PUT("/:userid", controller.UpdateUser)
+					users.GET("/", middleware.RequirePermission(middleware.PermUserRead), controller.GetUserList)
+					users.GET("/:userid", middleware.RequirePermission(middleware.PermUserRead), controller.GetUserDetail)
+					users.PUT("/:userid", middleware.RequirePermission(middleware.PermUserWrite), controller.UpdateUser)
+					users.POST("/:userid/revoke-tokens", middleware.RequirePermission(middleware.PermUserWrite), controller.RevokeUserTokens)
+					users.POST("/:userid/impersonate", middleware.RequirePermission(middleware.PermUserImpersonate), controller.ImpersonateUser)
+					users.GET("/:userid/identities", middleware.RequirePermission(middleware.PermUserRead), controller.GetUserIdentities)
+					users.DELETE("/:userid/identities/:loginType", middleware.RequirePermission(middleware.PermUserWrite), controller.UnlinkUserIdentity)
+					users.POST("/:userid/identities/merge", middleware.RequirePermission(middleware.PermUserWrite), controller.MergeUserIdentities)
 				}
 
 				// 日志查询相关路由
 				logs := authorized.Group("/logs")
+				logs.Use(middleware.RequirePermission(middleware.PermLogsRead))
 				{
 					logs.GET("/auth", controller.GetUserAuthLogs)
 					logs.GET("/game", controller.GetUserGameLogs)
@@ -74,11 +232,11 @@ func RegisterRoutes(router *gin.Engine) {
 				// 系统邮件相关路由
 				mails := authorized.Group("/mails")
 				{
-					mails.POST("/send", controller.SendSystemMail)
-					mails.GET("/", controller.GetAdminMailList)
-					mails.GET("/:id", controller.GetAdminMailDetail)
-					mails.PUT("/:id/status", controller.UpdateMailStatus)
-					mails.GET("/stats", controller.GetMailStats)
+					mails.POST("/send", middleware.RequirePermission(middleware.PermMailSend), controller.SendSystemMail)
+					mails.GET("/", middleware.RequirePermission(middleware.PermMailRead), controller.GetAdminMailList)
+					mails.GET("/:id", middleware.RequirePermission(middleware.PermMailRead), controller.GetAdminMailDetail)
+					mails.PUT("/:id/status", middleware.RequirePermission(middleware.PermMailWrite), controller.UpdateMailStatus)
+					mails.GET("/stats", middleware.RequirePermission(middleware.PermMailRead), controller.GetMailStats)
 				}
 			}
 		}
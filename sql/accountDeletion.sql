@@ -0,0 +1,24 @@
+CREATE TABLE `accountDeletion` (
+  `id` bigint(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '注销申请ID，主键',
+  `userid` bigint NOT NULL COMMENT '申请注销的用户ID',
+  `status` tinyint NOT NULL DEFAULT '0' COMMENT '状态：0-待审批，1-已批准，2-执行中，3-已完成，4-已拒绝，5-已撤销，6-执行失败',
+  `reason` varchar(255) DEFAULT NULL COMMENT '玩家填写的注销原因',
+  `requestIp` varchar(45) NOT NULL COMMENT '申请IP',
+  `channel` varchar(64) DEFAULT NULL COMMENT '申请时令牌的登录方式',
+  `executeAfter` datetime NOT NULL COMMENT '冷静期结束时间，之后才会执行',
+  `reviewerId` bigint(20) UNSIGNED DEFAULT NULL COMMENT '审批管理员ID',
+  `reviewerName` varchar(50) DEFAULT NULL COMMENT '审批管理员用户名',
+  `reviewComment` varchar(255) DEFAULT NULL COMMENT '审批意见',
+  `reviewedTime` datetime DEFAULT NULL COMMENT '审批时间',
+  `startedTime` datetime DEFAULT NULL COMMENT '最近一次开始执行的时间',
+  `heartbeatTime` datetime DEFAULT NULL COMMENT '执行实例最近一次心跳时间，超时后其他实例可以接手',
+  `executorToken` varchar(32) DEFAULT NULL COMMENT '当前执行实例的标识，只有持有者可以更新心跳和结果',
+  `completedTime` datetime DEFAULT NULL COMMENT '执行完成时间',
+  `lastError` varchar(512) DEFAULT NULL COMMENT '最近一次执行失败的原因',
+  `createdTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '申请时间',
+  `updatedTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+
+  PRIMARY KEY (`id`),
+  KEY `idx_userid` (`userid`),
+  KEY `idx_status_execute_after` (`status`, `executeAfter`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='玩家账号注销申请表';
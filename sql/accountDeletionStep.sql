@@ -0,0 +1,13 @@
+CREATE TABLE `accountDeletionStep` (
+  `id` bigint(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '主键',
+  `deletionId` bigint(20) UNSIGNED NOT NULL COMMENT '注销申请ID，关联accountDeletion表id',
+  `step` varchar(64) NOT NULL COMMENT '步骤名称，如 game.userRiches',
+  `action` varchar(16) NOT NULL COMMENT '处理方式：delete-删除，anonymize-匿名化，revoke-吊销',
+  `affectedRows` bigint NOT NULL DEFAULT '0' COMMENT '影响的行数',
+  `status` tinyint NOT NULL COMMENT '状态：1-成功，2-失败',
+  `errorMessage` varchar(512) DEFAULT NULL COMMENT '失败原因',
+  `executedTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '执行时间',
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_deletion_step` (`deletionId`, `step`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='玩家账号注销执行步骤表';
@@ -0,0 +1,9 @@
+CREATE TABLE `adminAccountRole` (
+  `adminId` bigint(20) UNSIGNED NOT NULL COMMENT '管理员ID，关联adminAccount表id',
+  `roleId` bigint(20) UNSIGNED NOT NULL COMMENT '角色ID，关联adminRole表id',
+  `createdBy` bigint(20) UNSIGNED DEFAULT NULL COMMENT '分配者ID',
+  `createdTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '分配时间',
+
+  PRIMARY KEY (`adminId`, `roleId`),
+  KEY `idx_role_id` (`roleId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='管理员角色关联表';
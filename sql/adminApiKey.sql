@@ -0,0 +1,19 @@
+CREATE TABLE `adminApiKey` (
+  `id` bigint(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '密钥ID，主键',
+  `name` varchar(50) NOT NULL COMMENT '密钥名称（服务账号用途）',
+  `keyPrefix` varchar(16) NOT NULL COMMENT '密钥前缀，用于查找密钥，可明文展示',
+  `keyHash` char(64) NOT NULL COMMENT '完整密钥的SHA256哈希',
+  `permissions` text NOT NULL COMMENT '密钥可用的权限编码（JSON数组）',
+  `status` tinyint(1) NOT NULL DEFAULT '1' COMMENT '状态：0-已吊销，1-有效',
+  `expireTime` datetime DEFAULT NULL COMMENT '过期时间，为空表示永不过期',
+  `lastUsedTime` datetime DEFAULT NULL COMMENT '最后使用时间',
+  `lastUsedIp` varchar(45) DEFAULT NULL COMMENT '最后使用IP',
+  `rotatedTime` datetime DEFAULT NULL COMMENT '最后轮换时间',
+  `createdBy` bigint(20) UNSIGNED NOT NULL COMMENT '创建者ID，密钥以该管理员身份调用接口',
+  `createdTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updatedTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '最后更新时间',
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_key_prefix` (`keyPrefix`),
+  KEY `idx_created_by` (`createdBy`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='管理后台API密钥表（服务账号）';
@@ -0,0 +1,27 @@
+CREATE TABLE `adminAuditLog` (
+  `id` bigint(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '日志ID，主键',
+  `adminId` bigint(20) UNSIGNED NOT NULL COMMENT '操作管理员ID',
+  `username` varchar(50) NOT NULL COMMENT '操作管理员用户名',
+  `apiKeyId` bigint(20) UNSIGNED DEFAULT NULL COMMENT '通过API密钥调用时的密钥ID',
+  `method` varchar(10) NOT NULL COMMENT 'HTTP方法',
+  `route` varchar(255) NOT NULL COMMENT '路由模板，如 /api/admin/users/:userid',
+  `path` varchar(512) NOT NULL COMMENT '实际请求路径',
+  `targetType` varchar(50) DEFAULT NULL COMMENT '操作对象类型，如 user、mail、admin、role',
+  `targetId` varchar(64) DEFAULT NULL COMMENT '操作对象ID',
+  `beforeData` mediumtext COMMENT '操作前快照（JSON）',
+  `afterData` mediumtext COMMENT '操作后快照或请求内容（JSON，敏感字段已脱敏）',
+  `ip` varchar(45) NOT NULL COMMENT '操作IP',
+  `userAgent` varchar(255) DEFAULT NULL COMMENT '客户端User-Agent',
+  `statusCode` int(11) NOT NULL COMMENT 'HTTP响应状态码',
+  `result` tinyint(1) NOT NULL COMMENT '操作结果：0-失败，1-成功',
+  `errorMessage` varchar(255) DEFAULT NULL COMMENT '失败原因（响应中的message）',
+  `durationMs` int(11) NOT NULL DEFAULT '0' COMMENT '请求耗时（毫秒）',
+  `createdTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '操作时间',
+
+  PRIMARY KEY (`id`),
+  KEY `idx_admin_id` (`adminId`),
+  KEY `idx_api_key_id` (`apiKeyId`),
+  KEY `idx_target` (`targetType`, `targetId`),
+  KEY `idx_route` (`route`),
+  KEY `idx_created_time` (`createdTime`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='管理员操作审计表';
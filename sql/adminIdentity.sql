@@ -0,0 +1,13 @@
+CREATE TABLE `adminIdentity` (
+  `id` bigint(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '主键',
+  `adminId` bigint(20) UNSIGNED NOT NULL COMMENT '关联的管理员ID',
+  `issuer` varchar(255) NOT NULL COMMENT '身份提供方（ID Token 的 iss）',
+  `subject` varchar(255) NOT NULL COMMENT '身份提供方中的用户标识（ID Token 的 sub）',
+  `email` varchar(100) DEFAULT NULL COMMENT '最近一次登录时的邮箱',
+  `lastLoginTime` datetime DEFAULT NULL COMMENT '最近一次单点登录时间',
+  `createdTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '关联时间',
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_issuer_subject` (`issuer`, `subject`),
+  KEY `idx_admin_id` (`adminId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='管理员外部身份关联表（单点登录）';
@@ -0,0 +1,11 @@
+CREATE TABLE `adminIpAllowlist` (
+  `id` bigint(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '条目ID，主键',
+  `adminId` bigint(20) UNSIGNED NOT NULL DEFAULT '0' COMMENT '管理员ID，0表示全局白名单',
+  `cidr` varchar(50) NOT NULL COMMENT '允许的IP段（CIDR），单个IP保存为/32或/128',
+  `remark` varchar(100) DEFAULT NULL COMMENT '备注',
+  `createdBy` bigint(20) UNSIGNED NOT NULL COMMENT '创建者ID',
+  `createdTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_admin_cidr` (`adminId`, `cidr`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='管理员IP白名单表';
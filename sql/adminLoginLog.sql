@@ -0,0 +1,16 @@
+CREATE TABLE `adminLoginLog` (
+  `id` bigint(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '日志ID，主键',
+  `adminId` bigint(20) UNSIGNED DEFAULT NULL COMMENT '管理员ID，账户不存在时为空',
+  `username` varchar(50) NOT NULL COMMENT '登录时提交的用户名',
+  `ip` varchar(45) NOT NULL COMMENT '登录IP',
+  `userAgent` varchar(255) DEFAULT NULL COMMENT '客户端User-Agent',
+  `status` tinyint(1) NOT NULL COMMENT '登录结果：0-失败，1-成功',
+  `reason` varchar(100) DEFAULT NULL COMMENT '失败原因',
+  `createdTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '登录时间',
+
+  PRIMARY KEY (`id`),
+  KEY `idx_admin_id` (`adminId`),
+  KEY `idx_username` (`username`),
+  KEY `idx_ip` (`ip`),
+  KEY `idx_created_time` (`createdTime`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='管理员登录历史表';
@@ -0,0 +1,28 @@
+CREATE TABLE `adminPermission` (
+  `code` varchar(64) NOT NULL COMMENT '权限编码，如 user.riches.write',
+  `name` varchar(50) NOT NULL COMMENT '权限名称',
+  `description` varchar(255) DEFAULT NULL COMMENT '权限描述',
+
+  PRIMARY KEY (`code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='后台管理权限表';
+
+-- 初始权限数据（与 middleware/permissionMiddleware.go 中的常量保持一致）
+INSERT INTO `adminPermission` (`code`, `name`, `description`) VALUES
+('user.read', '查看用户', '查看用户列表和用户详情'),
+('user.write', '修改用户', '修改用户昵称和状态'),
+('user.riches.write', '修改用户财富', '修改用户财富数值'),
+('user.impersonate', '模拟用户', '签发只读的用户模拟令牌，用于客服排查问题'),
+('user.delete', '审批账号注销', '查看、批准、拒绝和重试玩家账号注销申请'),
+('mail.read', '查看邮件', '查看管理后台邮件列表、详情和统计'),
+('mail.send', '发送邮件', '发送全服邮件和个人邮件（含奖励）'),
+('mail.write', '管理邮件', '修改玩家邮件状态'),
+('logs.read', '查看日志', '查看登录日志、对局日志和统计'),
+('admin.manage', '管理员管理', '管理管理员账户、角色、权限和登录锁定'),
+('audit.read', '查看审计日志', '查询和导出管理员操作审计日志'),
+('apikey.manage', '管理API密钥', '创建、轮换和吊销服务账号API密钥'),
+('cluster.read', '查看集群配置', '查看集群配置、校验、对比和发布历史'),
+('cluster.write', '发布集群配置', '发布集群配置和回滚到历史版本'),
+('maintenance.read', '查看停服维护', '查看维护窗口和维护放行名单'),
+('maintenance.write', '管理停服维护', '开始、结束和计划维护窗口，管理维护放行名单'),
+('clientversion.read', '查看版本策略', '查看客户端版本策略'),
+('clientversion.write', '管理版本策略', '创建、修改和删除客户端版本策略');
@@ -0,0 +1,12 @@
+CREATE TABLE `adminRole` (
+  `id` bigint(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '角色ID，主键',
+  `name` varchar(50) NOT NULL COMMENT '角色名称，唯一',
+  `description` varchar(255) DEFAULT NULL COMMENT '角色描述',
+  `createdBy` bigint(20) UNSIGNED DEFAULT NULL COMMENT '创建者ID',
+  `updatedBy` bigint(20) UNSIGNED DEFAULT NULL COMMENT '最后修改者ID',
+  `createdTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updatedTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '最后更新时间',
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='后台管理角色表';
@@ -0,0 +1,7 @@
+CREATE TABLE `adminRolePermission` (
+  `roleId` bigint(20) UNSIGNED NOT NULL COMMENT '角色ID，关联adminRole表id',
+  `permissionCode` varchar(64) NOT NULL COMMENT '权限编码，关联adminPermission表code',
+
+  PRIMARY KEY (`roleId`, `permissionCode`),
+  KEY `idx_permission_code` (`permissionCode`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='角色权限关联表';
@@ -0,0 +1,8 @@
+CREATE TABLE `adminSetting` (
+  `settingKey` varchar(64) NOT NULL COMMENT '配置键',
+  `settingValue` varchar(1024) NOT NULL DEFAULT '' COMMENT '配置值',
+  `updatedBy` bigint(20) UNSIGNED DEFAULT NULL COMMENT '最后修改者ID',
+  `updatedTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '最后更新时间',
+
+  PRIMARY KEY (`settingKey`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='管理后台全局设置表';
@@ -0,0 +1,11 @@
+CREATE TABLE `adminTwoFactor` (
+  `adminId` bigint(20) UNSIGNED NOT NULL COMMENT '管理员ID，关联adminAccount表id',
+  `secret` varchar(128) NOT NULL COMMENT 'TOTP密钥，使用服务端密钥AES-GCM加密（enc:前缀），早期数据为Base32明文',
+  `enabled` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否已启用：0-待验证，1-已启用',
+  `recoveryCodes` text COMMENT '恢复码的SHA256哈希列表（JSON数组）',
+  `lastUsedStep` bigint(20) NOT NULL DEFAULT '0' COMMENT '最后一次使用的时间步，用于防止验证码重放',
+  `createdTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updatedTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '最后更新时间',
+
+  PRIMARY KEY (`adminId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='管理员两步验证表';
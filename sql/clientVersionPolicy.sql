@@ -0,0 +1,16 @@
+CREATE TABLE `clientVersionPolicy` (
+  `id` bigint(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '策略ID，主键',
+  `channel` varchar(64) NOT NULL COMMENT '登录方式（loginType）',
+  `appid` int NOT NULL DEFAULT '0' COMMENT '应用ID，0表示该登录方式下的全部应用',
+  `minVersion` varchar(32) DEFAULT NULL COMMENT '最低支持版本，低于该版本必须更新，为空表示不强制',
+  `recommendedVersion` varchar(32) DEFAULT NULL COMMENT '推荐版本，低于该版本提示更新，为空表示不提示',
+  `updateUrl` varchar(512) DEFAULT NULL COMMENT '下载或更新地址',
+  `releaseNotes` text DEFAULT NULL COMMENT '更新说明',
+  `updatedBy` bigint(20) UNSIGNED NOT NULL COMMENT '最后修改的管理员ID',
+  `updatedByName` varchar(50) NOT NULL COMMENT '最后修改的管理员用户名',
+  `createdTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updatedTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_channel_appid` (`channel`, `appid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='客户端版本策略表';
@@ -0,0 +1,14 @@
+CREATE TABLE `clusterConfigHistory` (
+  `id` bigint(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '主键',
+  `ver` int NOT NULL COMMENT '集群配置版本号（clusterConfig.ver）',
+  `content` mediumtext NOT NULL COMMENT '该版本的完整配置JSON',
+  `action` varchar(16) NOT NULL COMMENT '来源：import-发布前记录的手工配置，publish-发布，rollback-回滚',
+  `rollbackFrom` int DEFAULT NULL COMMENT '回滚时恢复的历史版本号',
+  `comment` varchar(255) DEFAULT NULL COMMENT '变更说明',
+  `adminId` bigint(20) UNSIGNED NOT NULL DEFAULT '0' COMMENT '操作管理员ID',
+  `adminName` varchar(50) NOT NULL DEFAULT '' COMMENT '操作管理员用户名',
+  `createdTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '发布时间',
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_ver` (`ver`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='集群配置发布历史表';
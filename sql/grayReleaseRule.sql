@@ -0,0 +1,21 @@
+CREATE TABLE `grayReleaseRule` (
+  `id` bigint(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '规则ID，主键，同时作为按比例分流的哈希种子',
+  `name` varchar(64) NOT NULL COMMENT '规则名称',
+  `priority` int NOT NULL DEFAULT '0' COMMENT '优先级，数值小的先匹配',
+  `enabled` tinyint NOT NULL DEFAULT '1' COMMENT '是否启用：0-停用，1-启用',
+  `userids` text DEFAULT NULL COMMENT '指定用户ID（JSON数组），命中时不再判断其他条件',
+  `channels` varchar(255) DEFAULT NULL COMMENT '登录方式（JSON数组），为空表示不限',
+  `minVersion` varchar(32) DEFAULT NULL COMMENT '最低客户端版本（含），为空表示不限',
+  `maxVersion` varchar(32) DEFAULT NULL COMMENT '最高客户端版本（含），为空表示不限',
+  `percentage` tinyint DEFAULT NULL COMMENT '按 userid 分流的比例（0-100），为空表示不按比例分流',
+  `gateNodes` varchar(1024) DEFAULT NULL COMMENT '分配的网关节点名称（JSON数组），为空表示使用正式网关',
+  `gameNodes` varchar(1024) DEFAULT NULL COMMENT '分配的游戏节点名称（JSON数组），为空表示使用正式游戏节点',
+  `comment` varchar(255) DEFAULT NULL COMMENT '备注',
+  `updatedBy` bigint(20) UNSIGNED NOT NULL COMMENT '最后修改的管理员ID',
+  `updatedByName` varchar(50) NOT NULL COMMENT '最后修改的管理员用户名',
+  `createdTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updatedTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+
+  PRIMARY KEY (`id`),
+  KEY `idx_priority` (`priority`, `id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='灰度发布规则表';
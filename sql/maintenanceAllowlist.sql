@@ -0,0 +1,11 @@
+CREATE TABLE `maintenanceAllowlist` (
+  `id` bigint(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '条目ID，主键',
+  `type` varchar(10) NOT NULL COMMENT '类型：userid-用户ID，ip-IP段',
+  `value` varchar(50) NOT NULL COMMENT '用户ID，或IP段（CIDR，单个IP保存为/32或/128）',
+  `remark` varchar(100) DEFAULT NULL COMMENT '备注',
+  `createdBy` bigint(20) UNSIGNED NOT NULL COMMENT '创建者ID',
+  `createdTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_type_value` (`type`, `value`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='停服维护放行名单表';
@@ -0,0 +1,20 @@
+CREATE TABLE `maintenanceWindow` (
+  `id` bigint(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '维护窗口ID，主键',
+  `title` varchar(100) NOT NULL COMMENT '标题，仅后台显示',
+  `channels` varchar(255) DEFAULT NULL COMMENT '适用的登录方式（JSON数组），为空表示全部',
+  `notice` varchar(1000) NOT NULL COMMENT '默认维护公告',
+  `channelNotices` text DEFAULT NULL COMMENT '按登录方式的维护公告（JSON对象），未配置的登录方式使用默认公告',
+  `startTime` datetime NOT NULL COMMENT '开始时间',
+  `endTime` datetime DEFAULT NULL COMMENT '预计结束时间，为空表示需要手动结束',
+  `status` tinyint NOT NULL DEFAULT '0' COMMENT '状态：0-正常（按时间生效），1-已手动结束或取消',
+  `createdBy` bigint(20) UNSIGNED NOT NULL COMMENT '创建者ID',
+  `createdByName` varchar(50) NOT NULL COMMENT '创建者用户名',
+  `stoppedBy` bigint(20) UNSIGNED DEFAULT NULL COMMENT '结束或取消的管理员ID',
+  `stoppedTime` datetime DEFAULT NULL COMMENT '手动结束或取消的时间',
+  `createdTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updatedTime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+
+  PRIMARY KEY (`id`),
+  KEY `idx_status_end_time` (`status`, `endTime`),
+  KEY `idx_start_time` (`startTime`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='停服维护窗口表';
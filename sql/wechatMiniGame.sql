@@ -6,5 +6,6 @@ CREATE TABLE `wechatMiniGame` (
   `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
   PRIMARY KEY (`username`),
-  KEY `idx_userid_password` (`username`,`password`)
+  KEY `idx_userid_password` (`username`,`password`),
+  KEY `idx_userid` (`userid`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
\ No newline at end of file
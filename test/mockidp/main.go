@@ -0,0 +1,241 @@
+// mockidp 本地测试用的 OIDC 身份提供方，用于验证管理后台单点登录，不要用于生产环境。
+//
+//	go run ./test/mockidp -addr :9999 -groups gameweb-ops
+//
+// /authorize 不需要交互登录，直接带授权码重定向回 redirect_uri；
+// 授权地址上可以追加 sub、email、username、name、groups 参数覆盖默认用户。
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyID = "mock-1"
+
+var (
+	addr         = flag.String("addr", ":9999", "监听地址")
+	issuer       = flag.String("issuer", "http://localhost:9999", "issuer，需要与 gameWeb 配置的 oidc.issuer 一致")
+	clientID     = flag.String("client-id", "gameweb-admin", "客户端ID")
+	clientSecret = flag.String("client-secret", "mock-secret", "客户端密钥")
+	defaultSub   = flag.String("sub", "mock-user-1", "默认用户 sub")
+	defaultEmail = flag.String("email", "alice@example.com", "默认用户邮箱")
+	defaultUser  = flag.String("username", "alice", "默认用户名（preferred_username）")
+	defaultName  = flag.String("name", "Alice", "默认姓名")
+	defaultGroup = flag.String("groups", "", "默认用户组，逗号分隔")
+)
+
+// authCode 已签发的授权码
+type authCode struct {
+	redirectURI string
+	nonce       string
+	challenge   string
+	claims      jwt.MapClaims
+	expiresAt   time.Time
+}
+
+var (
+	privateKey *rsa.PrivateKey
+	mu         sync.Mutex
+	codes      = map[string]*authCode{}
+)
+
+func main() {
+	flag.Parse()
+
+	var err error
+	privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatalf("generate key: %v", err)
+	}
+
+	http.HandleFunc("/.well-known/openid-configuration", handleDiscovery)
+	http.HandleFunc("/authorize", handleAuthorize)
+	http.HandleFunc("/token", handleToken)
+	http.HandleFunc("/jwks", handleJWKS)
+
+	log.Printf("mock IdP listening on %s, issuer=%s, client_id=%s", *addr, *issuer, *clientID)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+func handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                *issuer,
+		"authorization_endpoint":                *issuer + "/authorize",
+		"token_endpoint":                        *issuer + "/token",
+		"jwks_uri":                              *issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+	})
+}
+
+func handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != *clientID || q.Get("response_type") != "code" {
+		http.Error(w, "invalid client_id or response_type", http.StatusBadRequest)
+		return
+	}
+	if q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "PKCE S256 required", http.StatusBadRequest)
+		return
+	}
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"sub":                valueOr(q.Get("sub"), *defaultSub),
+		"email":              valueOr(q.Get("email"), *defaultEmail),
+		"email_verified":     true,
+		"preferred_username": valueOr(q.Get("username"), *defaultUser),
+		"name":               valueOr(q.Get("name"), *defaultName),
+	}
+	if groups := valueOr(q.Get("groups"), *defaultGroup); groups != "" {
+		claims["groups"] = strings.Split(groups, ",")
+	}
+
+	code := randomHex(16)
+	mu.Lock()
+	codes[code] = &authCode{
+		redirectURI: q.Get("redirect_uri"),
+		nonce:       q.Get("nonce"),
+		challenge:   q.Get("code_challenge"),
+		claims:      claims,
+		expiresAt:   time.Now().Add(time.Minute),
+	}
+	mu.Unlock()
+
+	params := redirectURI.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirectURI.RawQuery = params.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+
+	id, secret, ok := r.BasicAuth()
+	if ok {
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if id != *clientID || secret != *clientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	mu.Lock()
+	code := codes[r.PostForm.Get("code")]
+	delete(codes, r.PostForm.Get("code"))
+	mu.Unlock()
+	if code == nil || time.Now().After(code.expiresAt) || code.redirectURI != r.PostForm.Get("redirect_uri") {
+		tokenError(w, "invalid_grant")
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != code.challenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   *issuer,
+		"aud":   *clientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+		"nonce": code.nonce,
+	}
+	for k, v := range code.claims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(privateKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomHex(16),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func handleJWKS(w http.ResponseWriter, r *http.Request) {
+	pub := privateKey.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Println("write response:", err)
+	}
+}
+
+func valueOr(v, def string) string {
+	if v != "" {
+		return v
+	}
+	return def
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		log.Fatalf("random: %v", err)
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,105 @@
+#!/bin/bash
+
+# API密钥（服务账号）接口测试脚本
+# 使用方法: ./test_api_key.sh
+
+# 配置
+BASE_URL="http://localhost:8080"
+API_ENDPOINT="/api/admin"
+
+# 颜色输出
+RED='\033[0;31m'
+GREEN='\033[0;32m'
+YELLOW='\033[1;33m'
+NC='\033[0m' # No Color
+
+echo -e "${YELLOW}=== API密钥接口测试 ===${NC}"
+
+# 检查jq是否安装
+if ! command -v jq &> /dev/null; then
+    echo -e "${RED}错误: 需要安装 jq 工具来解析JSON响应${NC}"
+    exit 1
+fi
+
+# 测试1: 管理员登录获取token
+echo -e "\n${YELLOW}测试1: 管理员登录获取token${NC}"
+LOGIN_RESPONSE=$(curl -s -X POST "$BASE_URL$API_ENDPOINT/login" \
+  -H "Content-Type: application/json" \
+  -d '{
+    "username": "admin",
+    "password": "password123"
+  }')
+
+TOKEN=$(echo $LOGIN_RESPONSE | jq -r '.data.token // empty')
+if [ -z "$TOKEN" ]; then
+    echo -e "${RED}登录失败，无法获取token: $LOGIN_RESPONSE${NC}"
+    exit 1
+fi
+echo -e "${GREEN}登录成功${NC}"
+
+# 测试2: 创建只允许发送邮件的API密钥
+echo -e "\n${YELLOW}测试2: 创建API密钥${NC}"
+CREATE_RESPONSE=$(curl -s -X POST "$BASE_URL$API_ENDPOINT/api-keys" \
+  -H "Authorization: Bearer $TOKEN" \
+  -H "Content-Type: application/json" \
+  -d '{
+    "name": "test-mail-job",
+    "permissions": ["mail.send", "mail.read"],
+    "expireDays": 1
+  }')
+echo "创建响应: $CREATE_RESPONSE"
+
+API_KEY=$(echo $CREATE_RESPONSE | jq -r '.data.key // empty')
+KEY_ID=$(echo $CREATE_RESPONSE | jq -r '.data.apiKey.id // empty')
+if [ -z "$API_KEY" ]; then
+    echo -e "${RED}创建API密钥失败${NC}"
+    exit 1
+fi
+echo -e "${GREEN}创建成功，密钥ID: $KEY_ID${NC}"
+
+# 测试3: 使用API密钥访问有权限的接口
+echo -e "\n${YELLOW}测试3: 使用API密钥查询邮件统计（应成功）${NC}"
+STATUS=$(curl -s -o /dev/null -w "%{http_code}" "$BASE_URL$API_ENDPOINT/mails/stats" \
+  -H "X-API-Key: $API_KEY")
+if [ "$STATUS" = "200" ]; then
+    echo -e "${GREEN}✓ 返回 200${NC}"
+else
+    echo -e "${RED}✗ 期望 200，实际 $STATUS${NC}"
+fi
+
+# 测试4: 使用API密钥访问无权限的接口
+echo -e "\n${YELLOW}测试4: 使用API密钥查询用户列表（应返回403）${NC}"
+STATUS=$(curl -s -o /dev/null -w "%{http_code}" "$BASE_URL$API_ENDPOINT/users/" \
+  -H "X-API-Key: $API_KEY")
+if [ "$STATUS" = "403" ]; then
+    echo -e "${GREEN}✓ 返回 403${NC}"
+else
+    echo -e "${RED}✗ 期望 403，实际 $STATUS${NC}"
+fi
+
+# 测试5: 轮换密钥后旧密钥失效
+echo -e "\n${YELLOW}测试5: 轮换API密钥${NC}"
+ROTATE_RESPONSE=$(curl -s -X POST "$BASE_URL$API_ENDPOINT/api-keys/$KEY_ID/rotate" \
+  -H "Authorization: Bearer $TOKEN")
+NEW_KEY=$(echo $ROTATE_RESPONSE | jq -r '.data.key // empty')
+STATUS=$(curl -s -o /dev/null -w "%{http_code}" "$BASE_URL$API_ENDPOINT/mails/stats" \
+  -H "X-API-Key: $API_KEY")
+if [ "$STATUS" = "401" ] && [ -n "$NEW_KEY" ]; then
+    echo -e "${GREEN}✓ 旧密钥返回 401${NC}"
+else
+    echo -e "${RED}✗ 旧密钥期望 401，实际 $STATUS${NC}"
+fi
+
+# 测试6: 吊销密钥
+echo -e "\n${YELLOW}测试6: 吊销API密钥${NC}"
+curl -s -X DELETE "$BASE_URL$API_ENDPOINT/api-keys/$KEY_ID" \
+  -H "Authorization: Bearer $TOKEN" | jq .
+STATUS=$(curl -s -o /dev/null -w "%{http_code}" "$BASE_URL$API_ENDPOINT/mails/stats" \
+  -H "X-API-Key: $NEW_KEY")
+if [ "$STATUS" = "401" ]; then
+    echo -e "${GREEN}✓ 吊销后返回 401${NC}"
+else
+    echo -e "${RED}✗ 吊销后期望 401，实际 $STATUS${NC}"
+fi
+
+echo -e "\n${YELLOW}=== 测试完成 ===${NC}"
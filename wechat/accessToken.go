@@ -0,0 +1,142 @@
+package wechat
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"gameWeb/db"
+	"gameWeb/log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// 接口调用凭证缓存
+const (
+	accessTokenKeyPrefix     = "wechat_access_token:"      // 缓存的 access_token，按 appid 区分
+	accessTokenLockKeyPrefix = "wechat_access_token_lock:" // 刷新锁，多实例同时只有一个去微信获取
+	accessTokenRefreshMargin = 5 * time.Minute             // 提前刷新的时间，避免临近过期时仍被使用
+	accessTokenMinTTL        = time.Minute                 // 缓存最短时间
+	accessTokenLockTTL       = 10 * time.Second
+	accessTokenWaitInterval  = 200 * time.Millisecond
+	accessTokenWaitTimes     = 25
+)
+
+// delIfEqualScript 只在键的值仍等于 ARGV[1] 时删除：用于清除失效的 token（避免删掉其他实例刚刷新的 token），
+// 以及释放自己持有的刷新锁（锁已过期并被其他实例获取时不删除）
+const delIfEqualScript = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`
+
+// AccessToken 获取接口调用凭证，优先读Redis缓存，缓存不存在时通过 stable_token 接口获取
+func (c *Client) AccessToken(ctx context.Context) (string, error) {
+	if token, err := db.GetRedis(accessTokenKeyPrefix + c.AppID); err == nil && token != "" {
+		return token, nil
+	}
+	return c.refreshAccessToken(ctx)
+}
+
+// InvalidateAccessToken 微信返回 token 失效时清除缓存，下次调用重新获取
+func (c *Client) InvalidateAccessToken(token string) {
+	if _, err := db.EvalRedis(delIfEqualScript, []string{accessTokenKeyPrefix + c.AppID}, token); err != nil {
+		log.Warnf("wechat invalidate access_token failed: appid=%s, err=%v", c.AppID, err)
+	}
+}
+
+// refreshAccessToken 获取新的 access_token 并写入缓存
+// 拿不到刷新锁说明其他实例正在刷新，等待其写入缓存；等待超时后自己获取
+func (c *Client) refreshAccessToken(ctx context.Context) (string, error) {
+	key := accessTokenKeyPrefix + c.AppID
+	lockKey := accessTokenLockKeyPrefix + c.AppID
+
+	holder, err := lockHolderToken()
+	if err != nil {
+		return "", err
+	}
+	locked, err := db.SetNXRedis(lockKey, holder, accessTokenLockTTL)
+	if err != nil {
+		return "", err
+	}
+	if locked {
+		defer func() {
+			if _, err := db.EvalRedis(delIfEqualScript, []string{lockKey}, holder); err != nil {
+				log.Warnf("wechat release access_token lock failed: appid=%s, err=%v", c.AppID, err)
+			}
+		}()
+	} else {
+		for i := 0; i < accessTokenWaitTimes; i++ {
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(accessTokenWaitInterval):
+			}
+			if token, err := db.GetRedis(key); err == nil && token != "" {
+				return token, nil
+			}
+		}
+	}
+
+	// stable_token 在有效期内重复调用返回同一个 token，不会让其他实例手上的 token 失效
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	body := map[string]interface{}{
+		"grant_type":    "client_credential",
+		"appid":         c.AppID,
+		"secret":        c.Secret,
+		"force_refresh": false,
+	}
+	if err := c.call(ctx, http.MethodPost, "/cgi-bin/stable_token", nil, body, &resp); err != nil {
+		return "", err
+	}
+	if resp.AccessToken == "" {
+		return "", errors.New("wechat stable_token: empty access_token")
+	}
+
+	ttl := time.Duration(resp.ExpiresIn)*time.Second - accessTokenRefreshMargin
+	if ttl < accessTokenMinTTL {
+		ttl = accessTokenMinTTL
+	}
+	if err := db.SetRedisWithExpire(key, resp.AccessToken, ttl); err != nil {
+		log.Warnf("wechat cache access_token failed: appid=%s, err=%v", c.AppID, err)
+	}
+	log.Infof("wechat access_token refreshed: appid=%s, expires_in=%d", c.AppID, resp.ExpiresIn)
+	return resp.AccessToken, nil
+}
+
+// lockHolderToken 生成刷新锁的持有者标识
+func lockHolderToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// isTokenError 判断是否为 access_token 失效类错误
+func isTokenError(err error) bool {
+	return IsErrCode(err, ErrCodeInvalidCredential) ||
+		IsErrCode(err, ErrCodeInvalidAccessToken) ||
+		IsErrCode(err, ErrCodeAccessTokenExpired)
+}
+
+// callWithToken 调用需要 access_token 的接口；token 失效时清除缓存并重试一次
+func (c *Client) callWithToken(ctx context.Context, path string, body interface{}, out interface{}) error {
+	for attempt := 0; ; attempt++ {
+		token, err := c.AccessToken(ctx)
+		if err != nil {
+			return err
+		}
+		query := url.Values{"access_token": {token}}
+		err = c.call(ctx, http.MethodPost, path, query, body, out)
+		if err == nil || attempt > 0 || !isTokenError(err) {
+			return err
+		}
+		log.Warnf("wechat %s access_token invalid, refreshing: appid=%s", path, c.AppID)
+		c.InvalidateAccessToken(token)
+	}
+}
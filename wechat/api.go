@@ -0,0 +1,98 @@
+package wechat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// Session 小游戏登录凭证校验结果
+type Session struct {
+	OpenID     string `json:"openid"`
+	UnionID    string `json:"unionid"`
+	SessionKey string `json:"session_key"`
+}
+
+// Code2Session 用 wx.login 返回的 code 换取 openid 和 session_key
+func (c *Client) Code2Session(ctx context.Context, code string) (*Session, error) {
+	query := url.Values{}
+	query.Set("appid", c.AppID)
+	query.Set("secret", c.Secret)
+	query.Set("js_code", code)
+	query.Set("grant_type", "authorization_code")
+
+	// js_code 只能使用一次，不重试：请求可能已被微信处理，重试只会得到 40163，交给客户端重新 wx.login
+	var session Session
+	if err := c.send(ctx, http.MethodGet, "/sns/jscode2session", query, nil, &session, 0); err != nil {
+		return nil, err
+	}
+	if session.OpenID == "" {
+		return nil, errors.New("wechat jscode2session: empty openid")
+	}
+	return &session, nil
+}
+
+// 内容安全检测场景
+const (
+	SceneProfile = 1 // 资料
+	SceneComment = 2 // 评论
+	SceneForum   = 3 // 论坛
+	SceneSocial  = 4 // 社交日志
+)
+
+// 内容安全检测建议
+const (
+	SuggestPass   = "pass"
+	SuggestReview = "review"
+	SuggestRisky  = "risky"
+)
+
+// MsgSecCheckResult 文本内容安全检测结果
+type MsgSecCheckResult struct {
+	TraceID string `json:"trace_id"`
+	Result  struct {
+		Suggest string `json:"suggest"` // pass、review 或 risky
+		Label   int    `json:"label"`   // 命中的标签，100 为正常
+	} `json:"result"`
+}
+
+// Pass 检测结果是否为通过
+func (r *MsgSecCheckResult) Pass() bool {
+	return r.Result.Suggest == SuggestPass
+}
+
+// MsgSecCheck 文本内容安全检测（2.0 版本），openid 需要是近两小时访问过小游戏的用户
+func (c *Client) MsgSecCheck(ctx context.Context, openid, content string, scene int) (*MsgSecCheckResult, error) {
+	body := map[string]interface{}{
+		"version": 2,
+		"openid":  openid,
+		"scene":   scene,
+		"content": content,
+	}
+	var result MsgSecCheckResult
+	if err := c.callWithToken(ctx, "/wxa/msg_sec_check", body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SubscribeMessage 订阅消息
+type SubscribeMessage struct {
+	ToUser           string                       `json:"touser"`
+	TemplateID       string                       `json:"template_id"`
+	Page             string                       `json:"page,omitempty"`
+	MiniProgramState string                       `json:"miniprogram_state,omitempty"` // developer、trial 或 formal
+	Lang             string                       `json:"lang,omitempty"`
+	Data             map[string]SubscribeMsgValue `json:"data"`
+}
+
+// SubscribeMsgValue 订阅消息模板字段的值
+type SubscribeMsgValue struct {
+	Value string `json:"value"`
+}
+
+// SendSubscribeMessage 发送订阅消息，用户未订阅或拒绝时返回 ErrCodeUserRefusedSubscribe
+func (c *Client) SendSubscribeMessage(ctx context.Context, msg *SubscribeMessage) error {
+	return c.callWithToken(ctx, "/cgi-bin/message/subscribe/send", msg, nil)
+}
@@ -0,0 +1,198 @@
+// Package wechat 微信服务端接口客户端：小游戏登录、接口调用凭证（access_token）缓存、
+// 内容安全检测和订阅消息。接口地址、超时和重试次数可配置，测试时可以指向本地桩服务。
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gameWeb/config"
+	"gameWeb/log"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 微信接口错误码
+const (
+	ErrCodeSystemBusy           = -1    // 系统繁忙，可以重试
+	ErrCodeInvalidCredential    = 40001 // access_token 无效
+	ErrCodeInvalidAccessToken   = 40014 // access_token 不合法
+	ErrCodeAccessTokenExpired   = 42001 // access_token 过期
+	ErrCodeInvalidCode          = 40029 // js_code 无效
+	ErrCodeCodeBeenUsed         = 40163 // js_code 已被使用
+	ErrCodeUserRefusedSubscribe = 43101 // 用户拒绝接收订阅消息
+)
+
+// defaultBaseURL 微信接口默认地址
+const defaultBaseURL = "https://api.weixin.qq.com"
+
+// retryBackoff 重试等待时间，按次数递增
+const retryBackoff = 200 * time.Millisecond
+
+// APIError 微信接口返回的业务错误
+type APIError struct {
+	Code    int    `json:"errcode"`
+	Message string `json:"errmsg"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("wechat API error, code: %d, message: %s", e.Code, e.Message)
+}
+
+// IsErrCode 判断错误是否为指定的微信错误码
+func IsErrCode(err error, code int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+var (
+	sharedClient *http.Client
+	sharedOnce   sync.Once
+)
+
+// HTTPClient 调用平台接口共用的HTTP客户端，复用连接；超时使用微信配置，QQ、抖音登录也使用这个客户端
+func HTTPClient() *http.Client {
+	sharedOnce.Do(func() {
+		timeout := time.Duration(config.AppConfig.Wechat.Timeout) * time.Second
+		if timeout <= 0 {
+			timeout = 5 * time.Second
+		}
+		sharedClient = &http.Client{Timeout: timeout}
+	})
+	return sharedClient
+}
+
+// Client 一个微信应用（小游戏）的接口客户端
+type Client struct {
+	AppID   string
+	Secret  string
+	BaseURL string
+	Retries int
+
+	httpClient *http.Client
+}
+
+// NewClient 按配置创建客户端
+func NewClient(appID, secret string) *Client {
+	cfg := config.AppConfig.Wechat
+	baseURL := strings.TrimRight(cfg.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	retries := cfg.Retries
+	if retries < 0 {
+		retries = 0
+	}
+	return &Client{
+		AppID:      appID,
+		Secret:     secret,
+		BaseURL:    baseURL,
+		Retries:    retries,
+		httpClient: HTTPClient(),
+	}
+}
+
+// call 调用微信接口并解析响应；网络错误、5xx 和系统繁忙时按配置重试
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, body interface{}, out interface{}) error {
+	return c.send(ctx, method, path, query, body, out, c.Retries)
+}
+
+// send 调用微信接口并解析响应，失败时最多重试 retries 次
+// 日志和错误中只包含接口路径，不包含带 secret 或 access_token 的完整地址
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body interface{}, out interface{}, retries int) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	fullURL := c.BaseURL + path
+	if len(query) > 0 {
+		fullURL += "?" + query.Encode()
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			log.Warnf("wechat %s retry %d/%d: %v", path, attempt, retries, lastErr)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(attempt) * retryBackoff):
+			}
+		}
+
+		data, retryable, err := c.do(ctx, method, fullURL, payload)
+		if err != nil {
+			lastErr = fmt.Errorf("wechat %s: %v", path, err)
+			if retryable {
+				continue
+			}
+			return lastErr
+		}
+
+		var apiErr APIError
+		if err := json.Unmarshal(data, &apiErr); err != nil {
+			return fmt.Errorf("wechat %s: unmarshal response: %v", path, err)
+		}
+		if apiErr.Code == ErrCodeSystemBusy {
+			lastErr = &apiErr
+			continue
+		}
+		if apiErr.Code != 0 {
+			return &apiErr
+		}
+		if out != nil {
+			if err := json.Unmarshal(data, out); err != nil {
+				return fmt.Errorf("wechat %s: unmarshal response: %v", path, err)
+			}
+		}
+		return nil
+	}
+	return lastErr
+}
+
+// do 发送一次请求，返回响应内容以及失败时是否可以重试
+func (c *Client) do(ctx context.Context, method, fullURL string, payload []byte) ([]byte, bool, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, reader)
+	if err != nil {
+		return nil, false, errors.New("invalid request")
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// url.Error 的消息包含完整地址，只保留底层错误
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, true, fmt.Errorf("read response body: %v", err)
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, true, fmt.Errorf("HTTP status %d", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("HTTP status %d", resp.StatusCode)
+	}
+	return data, false, nil
+}
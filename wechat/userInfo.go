@@ -0,0 +1,111 @@
+package wechat
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"gameWeb/db"
+	"time"
+)
+
+// 会话密钥缓存
+const (
+	sessionKeyPrefix = "wechat_session_key:" // wechat_session_key:{appid}:{openid}
+	sessionKeyTTL    = 72 * time.Hour        // 微信不公布 session_key 有效期，每次登录都会刷新缓存
+)
+
+var (
+	// ErrSessionKeyNotFound 没有缓存的会话密钥，需要重新登录
+	ErrSessionKeyNotFound = errors.New("wechat session_key not found")
+	// ErrInvalidEncryptedData 加密数据无法解密或格式错误
+	ErrInvalidEncryptedData = errors.New("invalid wechat encrypted data")
+	// ErrWatermarkMismatch 加密数据的水印 appid 不是当前应用
+	ErrWatermarkMismatch = errors.New("wechat encrypted data watermark mismatch")
+)
+
+// SaveSessionKey 登录成功后缓存会话密钥，用于之后解密客户端提交的加密数据
+func SaveSessionKey(appID, openid, sessionKey string) error {
+	return db.SetRedisWithExpire(sessionKeyPrefix+appID+":"+openid, sessionKey, sessionKeyTTL)
+}
+
+// GetSessionKey 读取缓存的会话密钥
+func GetSessionKey(appID, openid string) (string, error) {
+	sessionKey, err := db.GetRedis(sessionKeyPrefix + appID + ":" + openid)
+	if err != nil || sessionKey == "" {
+		return "", ErrSessionKeyNotFound
+	}
+	return sessionKey, nil
+}
+
+// DeleteSessionKey 删除缓存的会话密钥（账号注销时使用）
+func DeleteSessionKey(appID, openid string) error {
+	return db.DelRedis(sessionKeyPrefix + appID + ":" + openid)
+}
+
+// Watermark 加密数据的水印
+type Watermark struct {
+	AppID     string `json:"appid"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// UserInfo wx.getUserInfo 返回的加密用户信息
+type UserInfo struct {
+	OpenID    string    `json:"openId"`
+	UnionID   string    `json:"unionId"`
+	NickName  string    `json:"nickName"`
+	Gender    int       `json:"gender"` // 0 未知，1 男，2 女
+	Country   string    `json:"country"`
+	Province  string    `json:"province"`
+	City      string    `json:"city"`
+	AvatarURL string    `json:"avatarUrl"`
+	Language  string    `json:"language"`
+	Watermark Watermark `json:"watermark"`
+}
+
+// DecryptUserInfo 解密用户信息并校验水印 appid
+func DecryptUserInfo(appID, sessionKey, encryptedData, iv string) (*UserInfo, error) {
+	plain, err := decrypt(sessionKey, encryptedData, iv)
+	if err != nil {
+		return nil, err
+	}
+	var info UserInfo
+	if err := json.Unmarshal(plain, &info); err != nil {
+		return nil, ErrInvalidEncryptedData
+	}
+	if info.Watermark.AppID != appID {
+		return nil, ErrWatermarkMismatch
+	}
+	return &info, nil
+}
+
+// decrypt AES-128-CBC 解密，密钥为 session_key，数据、密钥和 iv 都是 base64 编码，PKCS#7 填充
+func decrypt(sessionKey, encryptedData, iv string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(sessionKey)
+	if err != nil || len(key) != 16 {
+		return nil, ErrInvalidEncryptedData
+	}
+	ivBytes, err := base64.StdEncoding.DecodeString(iv)
+	if err != nil || len(ivBytes) != aes.BlockSize {
+		return nil, ErrInvalidEncryptedData
+	}
+	data, err := base64.StdEncoding.DecodeString(encryptedData)
+	if err != nil || len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, ErrInvalidEncryptedData
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrInvalidEncryptedData
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, ivBytes).CryptBlocks(plain, data)
+
+	pad := int(plain[len(plain)-1])
+	if pad == 0 || pad > aes.BlockSize || !bytes.Equal(plain[len(plain)-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+		return nil, ErrInvalidEncryptedData
+	}
+	return plain[:len(plain)-pad], nil
+}